@@ -0,0 +1,130 @@
+// Package admin serves the administration endpoints of an application, to be
+// exposed on a separate port, not reachable by the application clients:
+//   - /loglevel reads (GET) and changes (PUT) the log level, optionally for a
+//     limited time,
+//   - /config shows the effective configuration, with the secrets masked,
+//   - /clients lists the interceptors of the named HTTP clients,
+//   - /goroutines dumps the stacks of all the goroutines,
+//   - /debug/pprof/ serves the runtime profiles of net/http/pprof.
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"sync"
+
+	"github.com/monorepo/common/configloader"
+	"github.com/monorepo/common/httputils"
+	"github.com/monorepo/common/logging"
+)
+
+// Admin is the handler of the administration endpoints.
+type Admin struct {
+	logger logging.LoggerLevel
+	level  *levelChanger
+
+	mu      sync.RWMutex
+	config  interface{}
+	clients map[string]*httputils.Client
+}
+
+// New instantiates an Admin changing the level of the given logger.
+func New(logger logging.LoggerLevel) *Admin {
+	return &Admin{
+		logger:  logger,
+		level:   newLevelChanger(logger),
+		clients: make(map[string]*httputils.Client),
+	}
+}
+
+// WithConfig sets the configuration shown by /config, as loaded by a
+// configloader.Loader.
+func (a *Admin) WithConfig(config interface{}) *Admin {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.config = config
+	return a
+}
+
+// WithClient registers an HTTP client listed by /clients under the given
+// name.
+func (a *Admin) WithClient(name string, client *httputils.Client) *Admin {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.clients[name] = client
+	return a
+}
+
+// Handler returns the handler of the administration endpoints.
+func (a *Admin) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/loglevel", a.level.serveHTTP)
+	mux.HandleFunc("/config", a.serveConfig)
+	mux.HandleFunc("/clients", a.serveClients)
+	mux.Handle("/goroutines", pprof.Handler("goroutine"))
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return mux
+}
+
+// serveConfig serves the settings of the configuration, see
+// configloader.Settings.
+func (a *Admin) serveConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	a.mu.RLock()
+	settings := configloader.Settings(a.config)
+	a.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// serveClients serves the interceptor types of the named clients, from the
+// outermost to the innermost.
+func (a *Admin) serveClients(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	a.mu.RLock()
+	clients := make(map[string][]string, len(a.clients))
+	for name, client := range a.clients {
+		types := []string{}
+		for _, i := range client.Interceptors() {
+			types = append(types, fmt.Sprintf("%T", i))
+		}
+		clients[name] = types
+	}
+	a.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, clients)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func methodNotAllowed(w http.ResponseWriter, methods ...string) {
+	sort.Strings(methods)
+	for _, m := range methods {
+		w.Header().Add("Allow", m)
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
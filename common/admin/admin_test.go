@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/httputils"
+	"github.com/monorepo/common/logging"
+	"github.com/monorepo/common/logging/slog"
+	"github.com/monorepo/common/secret"
+)
+
+func newTestLogger(t *testing.T) logging.LoggerLevel {
+	logger, err := slog.New(io.Discard, &logging.Config{Level: "info"}, "test")
+	require.NoError(t, err)
+	return logger
+}
+
+func serve(t *testing.T, h http.Handler, method, path, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	var got map[string]interface{}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	}
+	return w, got
+}
+
+func TestAdmin_loglevel(t *testing.T) {
+	logger := newTestLogger(t)
+	h := New(logger).Handler()
+
+	w, got := serve(t, h, http.MethodGet, "/loglevel", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]interface{}{"level": "info"}, got)
+
+	w, got = serve(t, h, http.MethodPut, "/loglevel", `{"level":"debug"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]interface{}{"level": "debug"}, got)
+	assert.Equal(t, logging.LevelDebug, logger.GetLevel())
+
+	w, _ = serve(t, h, http.MethodPut, "/loglevel", `{"level":"verbose"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = serve(t, h, http.MethodPut, "/loglevel", `{"level":"error","ttl":"-1s"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = serve(t, h, http.MethodDelete, "/loglevel", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, logging.LevelDebug, logger.GetLevel())
+}
+
+func TestAdmin_loglevel_ttl(t *testing.T) {
+	logger := newTestLogger(t)
+	h := New(logger).Handler()
+
+	w, got := serve(t, h, http.MethodPut, "/loglevel", `{"level":"debug","ttl":"1h"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "debug", got["level"])
+	assert.Contains(t, got, "revert_at")
+
+	// A new temporary change reverts to the level preceding the first one.
+	w, _ = serve(t, h, http.MethodPut, "/loglevel", `{"level":"error","ttl":"20ms"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, logging.LevelError, logger.GetLevel())
+
+	assert.Eventually(t, func() bool {
+		return logger.GetLevel() == logging.LevelInfo
+	}, time.Second, 5*time.Millisecond)
+
+	_, got = serve(t, h, http.MethodGet, "/loglevel", "")
+	assert.Equal(t, map[string]interface{}{"level": "info"}, got)
+}
+
+func TestAdmin_loglevel_permanent_change_cancels_revert(t *testing.T) {
+	logger := newTestLogger(t)
+	h := New(logger).Handler()
+
+	serve(t, h, http.MethodPut, "/loglevel", `{"level":"debug","ttl":"20ms"}`)
+	serve(t, h, http.MethodPut, "/loglevel", `{"level":"warning"}`)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, logging.LevelWarning, logger.GetLevel())
+}
+
+func TestAdmin_config(t *testing.T) {
+	type config struct {
+		Name     string        `mapstructure:"name"`
+		Password secret.String `mapstructure:"password"`
+	}
+
+	h := New(newTestLogger(t)).
+		WithConfig(&config{Name: "app", Password: "p4ss"}).
+		Handler()
+
+	w, got := serve(t, h, http.MethodGet, "/config", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]interface{}{"name": "app", "password": "*****"}, got)
+	assert.NotContains(t, w.Body.String(), "p4ss")
+}
+
+func TestAdmin_clients(t *testing.T) {
+	h := New(newTestLogger(t)).
+		WithClient("billing", httputils.NewClient(time.Second, 0).WithLimiter(1)).
+		Handler()
+
+	w, got := serve(t, h, http.MethodGet, "/clients", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]interface{}{
+		"billing": []interface{}{
+			"*interceptors.UniqueID",
+			"*interceptors.HeaderPropagation",
+			"*interceptors.BodyLimit",
+			"interceptors.Limiter",
+		},
+	}, got)
+}
+
+func TestAdmin_goroutines_and_pprof(t *testing.T) {
+	h := New(newTestLogger(t)).Handler()
+
+	w, _ := serve(t, h, http.MethodGet, "/goroutines?debug=2", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine ")
+
+	w, _ = serve(t, h, http.MethodGet, "/debug/pprof/", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "heap")
+}
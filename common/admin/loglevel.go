@@ -0,0 +1,140 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/monorepo/common/logging"
+)
+
+// levelRequest is the body of a log level change. TTL is an optional
+// duration, e.g. "15m", after which the level is reverted.
+type levelRequest struct {
+	Level string `json:"level"`
+	TTL   string `json:"ttl,omitempty"`
+}
+
+// levelResponse is the current log level, with the time it is reverted at if
+// it was changed for a limited time.
+type levelResponse struct {
+	Level    logging.Level `json:"level"`
+	RevertAt *time.Time    `json:"revert_at,omitempty"`
+}
+
+// levelChanger changes the level of a logger, reverting it after a TTL.
+type levelChanger struct {
+	logger logging.LoggerLevel
+
+	mu sync.Mutex
+	// revert is the pending revert of a temporary change, nil if none.
+	revert *time.Timer
+	// revertTo is the level before the first pending temporary change.
+	revertTo logging.Level
+	revertAt time.Time
+}
+
+func newLevelChanger(logger logging.LoggerLevel) *levelChanger {
+	return &levelChanger{
+		logger: logger,
+	}
+}
+
+func (c *levelChanger) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var req levelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		lvl, err := logging.ParseLevel(req.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var ttl time.Duration
+		if req.TTL != "" {
+			ttl, err = time.ParseDuration(req.TTL)
+			if err != nil || ttl <= 0 {
+				http.Error(w, fmt.Sprintf("invalid ttl: %q", req.TTL), http.StatusBadRequest)
+				return
+			}
+		}
+
+		c.set(lvl, ttl)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodPost)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, c.current())
+}
+
+// set changes the log level, reverting it after ttl if not zero. A change
+// cancels the pending revert of a previous temporary change, a new temporary
+// change still reverting to the level preceding the first one.
+func (c *levelChanger) set(lvl logging.Level, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := c.logger.GetLevel()
+	if c.revert != nil {
+		c.revert.Stop()
+		c.revert = nil
+	} else {
+		c.revertTo = previous
+	}
+
+	c.logger.SetLevel(lvl)
+
+	fields := logging.Fields{
+		"level":          lvl.String(),
+		"previous_level": previous.String(),
+	}
+	if ttl > 0 {
+		fields["ttl"] = ttl.String()
+		c.revertAt = time.Now().Add(ttl)
+
+		var timer *time.Timer
+		timer = time.AfterFunc(ttl, func() { c.expire(timer) })
+		c.revert = timer
+	}
+
+	c.logger.WithFields(fields).Warning("log level changed")
+}
+
+// expire reverts the temporary change of the given timer, unless it was
+// superseded.
+func (c *levelChanger) expire(timer *time.Timer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.revert != timer {
+		return
+	}
+	c.revert = nil
+
+	c.logger.SetLevel(c.revertTo)
+	c.logger.WithField("level", c.revertTo.String()).Warning("log level reverted")
+}
+
+func (c *levelChanger) current() levelResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp := levelResponse{
+		Level: c.logger.GetLevel(),
+	}
+	if c.revert != nil {
+		revertAt := c.revertAt
+		resp.RevertAt = &revertAt
+	}
+
+	return resp
+}
@@ -0,0 +1,328 @@
+// Package application runs the services of an application, with the
+// configuration, logger and monitoring common to all the applications:
+//
+//	var cfg Config
+//	app, err := application.New("my-app", &cfg, "conf/base.yaml")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	app.Register(service.NewHTTPServer(srv))
+//	if err := app.Run(); err != nil {
+//		os.Exit(1)
+//	}
+package application
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/multierr"
+
+	"github.com/monorepo/common/admin"
+	"github.com/monorepo/common/application/service"
+	"github.com/monorepo/common/configloader"
+	"github.com/monorepo/common/graceful"
+	"github.com/monorepo/common/logging"
+	"github.com/monorepo/common/logging/slog"
+	"github.com/monorepo/common/monitoring/metrics"
+	"github.com/monorepo/common/monitoring/metrics/ddmetrics"
+	"github.com/monorepo/common/monitoring/metrics/otelmetrics"
+	"github.com/monorepo/common/monitoring/profiling"
+	"github.com/monorepo/common/monitoring/tracing"
+	"github.com/monorepo/common/monitoring/tracing/ddtracing"
+	"github.com/monorepo/common/monitoring/tracing/oteltracing"
+)
+
+// Application runs the registered services until it receives a SIGTERM or
+// SIGINT signal, or until one of them fails.
+type Application struct {
+	name   string
+	cfg    *Config
+	logger logging.LoggerLevel
+	admin  *admin.Admin
+
+	// monitoring are the services of the tracer and profiler, started before
+	// the registered services and stopped after them.
+	monitoring []service.Service
+	services   []service.Service
+	// closers flush and close the metrics and OTEL tracer once all the
+	// services are stopped.
+	closers []func()
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New loads the application configuration from the given files and the
+// environment variables prefixed by the application name, and builds the
+// global logger, metrics, tracer and profiler.
+func New(name string, cfg Configuration, paths ...string) (*Application, error) {
+	if err := configloader.New(name, paths...).Load(cfg); err != nil {
+		return nil, fmt.Errorf("can't load the configuration: %w", err)
+	}
+
+	a := &Application{
+		name: name,
+		cfg:  cfg.AppConfig(),
+	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	logger, err := slog.New(os.Stdout, &a.cfg.Logging, name)
+	if err != nil {
+		return nil, fmt.Errorf("can't create the logger: %w", err)
+	}
+	logging.OverrideDefaultStandardLogger(logger)
+	a.logger = logger
+	a.admin = admin.New(logger).WithConfig(cfg)
+
+	if err := a.setupMonitoring(); err != nil {
+		a.close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// setupMonitoring builds the global metrics handler and the tracer and
+// profiler services of the monitoring backend.
+func (a *Application) setupMonitoring() error {
+	cfg := a.cfg.Monitoring
+
+	switch cfg.Backend {
+	case BackendDatadog:
+		namespace := cfg.Namespace
+		if namespace == "" {
+			namespace = a.name
+		}
+		client, err := ddmetrics.NewClient(&ddmetrics.Config{
+			Namespace: namespace,
+			Tags:      cfg.Tags,
+		}, a.logger)
+		if err != nil {
+			return fmt.Errorf("can't create the statsd client: %w", err)
+		}
+		metrics.SetGlobalStatsdHandler(client)
+		a.closers = append(a.closers, client.Close)
+
+		if cfg.Trace {
+			a.monitoring = append(a.monitoring, ddtracing.NewService(&ddtracing.Config{
+				ServiceName: a.name,
+				Version:     a.cfg.Version,
+				Env:         a.cfg.Env,
+				RateSampled: cfg.TraceSampleRate > 0,
+				Rate:        cfg.TraceSampleRate,
+			}, a.logger))
+		}
+
+	case BackendOTEL:
+		mp, shutdown, err := otelmetrics.NewMeterProvider(&otelmetrics.Config{
+			ServiceName: a.name,
+			Version:     a.cfg.Version,
+			Env:         a.cfg.Env,
+		}, a.logger)
+		if err != nil {
+			return fmt.Errorf("can't create the OTEL meter provider: %w", err)
+		}
+		metrics.SetGlobalMeterProvider(mp)
+		a.closers = append(a.closers, shutdown)
+
+		if cfg.Trace {
+			tp, shutdown, err := oteltracing.NewTracerProvider(&oteltracing.Config{
+				ServiceName: a.name,
+				Version:     a.cfg.Version,
+				Env:         a.cfg.Env,
+			}, a.logger)
+			if err != nil {
+				return fmt.Errorf("can't create the OTEL tracer provider: %w", err)
+			}
+			tracing.SetGlobalTracerProvider(tp)
+			a.closers = append(a.closers, shutdown)
+		}
+
+	case BackendNone:
+
+	default:
+		return fmt.Errorf("unsupported monitoring backend %q", cfg.Backend)
+	}
+
+	if cfg.Profiling {
+		a.monitoring = append(a.monitoring, profiling.NewService(&profiling.Config{
+			ServiceName: a.name,
+			Version:     a.cfg.Version,
+			Env:         a.cfg.Env,
+			Tags:        cfg.Tags,
+		}, a.logger))
+	}
+
+	return nil
+}
+
+// Logger returns the application logger.
+func (a *Application) Logger() logging.LoggerLevel {
+	return a.logger
+}
+
+// Admin returns the administration endpoints, served on the admin address
+// when enabled, e.g. to register the HTTP clients.
+func (a *Application) Admin() *admin.Admin {
+	return a.admin
+}
+
+// Context returns a context canceled when the application starts shutting
+// down, before the drain delay, e.g. to fail the readiness checks.
+func (a *Application) Context() context.Context {
+	return a.ctx
+}
+
+// Register registers services to run. The services are started concurrently,
+// and stopped in the reverse order of their registration.
+func (a *Application) Register(services ...service.Service) *Application {
+	a.services = append(a.services, services...)
+	return a
+}
+
+// Shutdown shuts the application down as if it received a SIGTERM signal.
+func (a *Application) Shutdown() {
+	a.cancel()
+}
+
+// Run starts the monitoring services, then the registered services
+// concurrently, and blocks until the application receives a SIGTERM or SIGINT
+// signal (or Shutdown is called) or until a service fails.
+//
+// On a signal, the services are stopped once the drain delay has elapsed. A
+// second signal terminates the process right away. The services are stopped
+// in the reverse order of their registration, each within the shutdown
+// timeout.
+//
+// Run returns the errors of the services, aggregated with multierr.
+func (a *Application) Run() error {
+	defer a.close()
+
+	sigCtx, stopSignals := signal.NotifyContext(a.ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer stopSignals()
+
+	var errs error
+	for i, svc := range a.monitoring {
+		if err := svc.Run(); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("run %T: %w", svc, err))
+			return multierr.Append(errs, a.stop(a.monitoring[:i]))
+		}
+	}
+
+	services := a.services
+	if a.cfg.Admin.Enabled {
+		// Registered first to be stopped last, for the application to be
+		// observable while it shuts down.
+		services = append([]service.Service{service.NewHTTPServer(&http.Server{
+			Addr:              a.cfg.Admin.Addr,
+			Handler:           a.admin.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		})}, services...)
+	}
+
+	// Each service sends at most one error, the channel never blocks them.
+	errc := make(chan error, len(services))
+	var wg sync.WaitGroup
+	for _, svc := range services {
+		wg.Add(1)
+		go func(svc service.Service) {
+			defer wg.Done()
+			if err := svc.Run(); err != nil {
+				errc <- fmt.Errorf("run %T: %w", svc, err)
+			}
+		}(svc)
+	}
+	a.logger.Info("application started")
+
+	select {
+	case <-sigCtx.Done():
+		// Restore the default behavior, for a second signal to terminate the
+		// process.
+		stopSignals()
+		a.cancel()
+
+		a.logger.Infof("application shutting down in %s", a.cfg.Shutdown.DrainDelay)
+		drain, cancel := graceful.Graceful(a.ctx, a.cfg.Shutdown.DrainDelay)
+		<-drain.Done()
+		cancel()
+
+	case err := <-errc:
+		a.cancel()
+		a.logger.WithError(err).Error("application shutting down: service failed")
+		errs = multierr.Append(errs, err)
+	}
+
+	errs = multierr.Append(errs, a.stop(services))
+	if !waitTimeout(&wg, a.cfg.Shutdown.Timeout) {
+		errs = multierr.Append(errs, fmt.Errorf("services still running %s after being stopped", a.cfg.Shutdown.Timeout))
+	}
+	errs = multierr.Append(errs, drainErrors(errc))
+
+	errs = multierr.Append(errs, a.stop(a.monitoring))
+	a.logger.Info("application stopped")
+
+	return errs
+}
+
+// drainErrors returns the errors sent so far, without waiting for the others:
+// the services still running may fail after Run returned, errc is then never
+// closed.
+func drainErrors(errc <-chan error) error {
+	var errs error
+	for {
+		select {
+		case err := <-errc:
+			errs = multierr.Append(errs, err)
+		default:
+			return errs
+		}
+	}
+}
+
+// stop stops the services in the reverse order, each within the shutdown
+// timeout.
+func (a *Application) stop(services []service.Service) error {
+	var errs error
+	for i := len(services) - 1; i >= 0; i-- {
+		svc := services[i]
+
+		ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Shutdown.Timeout)
+		if err := svc.Stop(ctx); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("stop %T: %w", svc, err))
+		}
+		cancel()
+	}
+	return errs
+}
+
+// close flushes and closes the metrics and OTEL tracer.
+func (a *Application) close() {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		a.closers[i]()
+	}
+	a.closers = nil
+}
+
+// waitTimeout waits for the wait group, and reports whether it is done before
+// the timeout.
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,210 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
+)
+
+// events records the lifecycle events of the fake services.
+type events struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.events...)
+}
+
+// fakeService blocks until it is stopped, or fails with runErr.
+type fakeService struct {
+	name    string
+	events  *events
+	runErr  error
+	stopErr error
+
+	started chan struct{}
+	stopped chan struct{}
+}
+
+func newFakeService(name string, e *events) *fakeService {
+	return &fakeService{
+		name:    name,
+		events:  e,
+		started: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+func (s *fakeService) Run() error {
+	close(s.started)
+	if s.runErr != nil {
+		return s.runErr
+	}
+	<-s.stopped
+	return nil
+}
+
+func (s *fakeService) Stop(ctx context.Context) error {
+	s.events.add("stop " + s.name)
+	close(s.stopped)
+	return s.stopErr
+}
+
+func newTestApplication(t *testing.T) *Application {
+	t.Helper()
+	t.Setenv("APP_MONITORING_BACKEND", BackendNone)
+	t.Setenv("APP_SHUTDOWN_DRAIN_DELAY", "10ms")
+
+	var cfg struct {
+		Config `mapstructure:",squash"`
+
+		Port int `mapstructure:"port"`
+	}
+	app, err := New("app", &cfg)
+	require.NoError(t, err)
+	return app
+}
+
+func TestApplication_Run(t *testing.T) {
+	app := newTestApplication(t)
+
+	var e events
+	a := newFakeService("a", &e)
+	b := newFakeService("b", &e)
+	app.Register(a, b)
+
+	go func() {
+		<-a.started
+		<-b.started
+		app.Shutdown()
+	}()
+
+	require.NoError(t, app.Run())
+	assert.Equal(t, []string{"stop b", "stop a"}, e.get())
+	assert.Error(t, app.Context().Err())
+}
+
+func TestApplication_Run_signal(t *testing.T) {
+	app := newTestApplication(t)
+
+	var e events
+	a := newFakeService("a", &e)
+	app.Register(a)
+
+	go func() {
+		<-a.started
+		_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	}()
+
+	require.NoError(t, app.Run())
+	assert.Equal(t, []string{"stop a"}, e.get())
+}
+
+func TestApplication_Run_errors(t *testing.T) {
+	app := newTestApplication(t)
+
+	errRun := errors.New("run failure")
+	errStop := errors.New("stop failure")
+
+	var e events
+	a := newFakeService("a", &e)
+	a.stopErr = errStop
+	b := newFakeService("b", &e)
+	b.runErr = errRun
+	app.Register(a, b)
+
+	err := app.Run()
+	assert.ErrorIs(t, err, errRun)
+	assert.ErrorIs(t, err, errStop)
+	assert.Len(t, multierr.Errors(err), 2)
+	assert.Equal(t, []string{"stop b", "stop a"}, e.get())
+}
+
+// stuckService ignores Stop, and fails once released.
+type stuckService struct {
+	started  chan struct{}
+	released chan struct{}
+	returned chan struct{}
+}
+
+func (s *stuckService) Run() error {
+	close(s.started)
+	<-s.released
+	defer close(s.returned)
+	return errors.New("late failure")
+}
+
+func (s *stuckService) Stop(ctx context.Context) error {
+	return nil
+}
+
+func TestApplication_Run_stuck_service(t *testing.T) {
+	t.Setenv("APP_SHUTDOWN_TIMEOUT", "10ms")
+	app := newTestApplication(t)
+
+	svc := &stuckService{
+		started:  make(chan struct{}),
+		released: make(chan struct{}),
+		returned: make(chan struct{}),
+	}
+	app.Register(svc)
+
+	go func() {
+		<-svc.started
+		app.Shutdown()
+	}()
+
+	err := app.Run()
+	assert.ErrorContains(t, err, "services still running")
+
+	// The late failure of the service must not panic.
+	close(svc.released)
+	<-svc.returned
+	time.Sleep(10 * time.Millisecond)
+}
+
+func TestNew_invalid_backend(t *testing.T) {
+	t.Setenv("APP_MONITORING_BACKEND", "unknown")
+
+	var cfg Config
+	_, err := New("app", &cfg)
+	assert.EqualError(t, err, `unsupported monitoring backend "unknown"`)
+}
+
+func TestApplication_Run_admin(t *testing.T) {
+	t.Setenv("APP_ADMIN_ENABLED", "true")
+	t.Setenv("APP_ADMIN_ADDR", "127.0.0.1:0")
+	app := newTestApplication(t)
+	require.NotNil(t, app.Admin())
+
+	var e events
+	a := newFakeService("a", &e)
+	app.Register(a)
+
+	go func() {
+		<-a.started
+		app.Shutdown()
+	}()
+
+	require.NoError(t, app.Run())
+	assert.Equal(t, []string{"stop a"}, e.get())
+	// The admin server is not registered as a service of the application.
+	assert.Len(t, app.services, 1)
+}
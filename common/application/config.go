@@ -0,0 +1,104 @@
+package application
+
+import (
+	"time"
+
+	"github.com/monorepo/common/configloader"
+	"github.com/monorepo/common/logging"
+)
+
+// The monitoring backends.
+const (
+	BackendDatadog = "datadog"
+	BackendOTEL    = "otel"
+	BackendNone    = "none"
+)
+
+// Configuration is implemented by the application configurations, embedding
+// the Config structure:
+//
+//	type Config struct {
+//		application.Config `mapstructure:",squash"`
+//
+//		Port int `mapstructure:"port"`
+//	}
+type Configuration interface {
+	AppConfig() *Config
+}
+
+// Config contains the configuration common to all the applications.
+type Config struct {
+	// Env and Version identify the deployment in the metrics, traces and
+	// profiles.
+	Env     string `mapstructure:"env"`
+	Version string `mapstructure:"version"`
+
+	Logging    logging.Config   `mapstructure:"logging"`
+	Monitoring MonitoringConfig `mapstructure:"monitoring"`
+	Shutdown   ShutdownConfig   `mapstructure:"shutdown"`
+	Admin      AdminConfig      `mapstructure:"admin"`
+}
+
+// AppConfig implements the Configuration interface.
+func (c *Config) AppConfig() *Config {
+	return c
+}
+
+// MonitoringConfig contains the configuration of the metrics, tracer and
+// profiler of the application.
+type MonitoringConfig struct {
+	// Backend is the monitoring backend, "datadog", "otel" or "none".
+	Backend string `mapstructure:"backend"`
+	// Namespace is the prefix of the Datadog metrics, the application name by
+	// default.
+	Namespace string   `mapstructure:"namespace"`
+	Tags      []string `mapstructure:"tags"`
+	Trace     bool     `mapstructure:"trace"`
+	// TraceSampleRate is the rate of traces sampled by the Datadog tracer,
+	// between 0 and 1. Zero leaves the sampling decision to the agent.
+	TraceSampleRate float64 `mapstructure:"trace_sample_rate"`
+	// Profiling enables the Datadog profiler.
+	Profiling bool `mapstructure:"profiling"`
+}
+
+// ShutdownConfig contains the configuration of the application shutdown.
+type ShutdownConfig struct {
+	// DrainDelay is the time left to the load balancers to stop sending
+	// requests once the shutdown signal is received, before the services are
+	// stopped.
+	DrainDelay time.Duration `mapstructure:"drain_delay"`
+	// Timeout is the maximum time given to each service to stop.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// AdminConfig contains the configuration of the administration server, see
+// the admin package.
+type AdminConfig struct {
+	// Enabled serves the administration endpoints. They must not be reachable
+	// by the application clients.
+	Enabled bool   `mapstructure:"enabled"`
+	Addr    string `mapstructure:"addr"`
+}
+
+// Defaults sets default configuration values.
+func (*Config) Defaults(l *configloader.Loader) {
+	l.SetDefault("monitoring.backend", BackendDatadog)
+	l.SetDefault("monitoring.trace", true)
+	l.SetDefault("shutdown.drain_delay", 5*time.Second)
+	l.SetDefault("shutdown.timeout", 10*time.Second)
+	l.SetDefault("admin.addr", ":8081")
+}
+
+// Envs bind environment keys to env variables
+func (*Config) Envs(l *configloader.Loader) {
+	l.BindEnv("env")
+	l.BindEnv("version")
+	l.BindEnv("logging.level")
+	l.BindEnv("monitoring.backend")
+	l.BindEnv("monitoring.trace")
+	l.BindEnv("monitoring.profiling")
+	l.BindEnv("shutdown.drain_delay")
+	l.BindEnv("shutdown.timeout")
+	l.BindEnv("admin.enabled")
+	l.BindEnv("admin.addr")
+}
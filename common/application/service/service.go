@@ -0,0 +1,54 @@
+// Package service defines the background services managed by an
+// application.Application, e.g. HTTP servers, queue consumers or the
+// monitoring tracers and profilers.
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Service is a background service.
+//
+// Run starts the service. It blocks until the service is stopped, or returns
+// once the service is started if it runs in its own goroutines (e.g.
+// ddtracing.Service). An error means the service can't run.
+//
+// Stop stops the service, and makes Run return. It should return once the
+// service is stopped, or when ctx is done.
+type Service interface {
+	Run() error
+	Stop(ctx context.Context) error
+}
+
+// HTTPServer is the Service running an http.Server.
+type HTTPServer struct {
+	Server *http.Server
+}
+
+// NewHTTPServer creates a Service running the given server.
+func NewHTTPServer(srv *http.Server) *HTTPServer {
+	return &HTTPServer{
+		Server: srv,
+	}
+}
+
+// Run implements the `service.Service` interface.
+//
+// Run listens and serves the HTTP requests until the server is stopped.
+func (s *HTTPServer) Run() error {
+	err := s.Server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Stop implements the `service.Service` interface.
+//
+// Stop gracefully shuts down the server, waiting for the active requests to
+// be handled until ctx is done.
+func (s *HTTPServer) Stop(ctx context.Context) error {
+	return s.Server.Shutdown(ctx)
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPServer(t *testing.T) {
+	svc := NewHTTPServer(&http.Server{
+		Addr:              "127.0.0.1:0",
+		ReadHeaderTimeout: time.Second,
+	})
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- svc.Run()
+	}()
+
+	require.NoError(t, svc.Stop(context.Background()))
+	assert.NoError(t, <-errc)
+}
@@ -0,0 +1,24 @@
+package awsx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/monorepo/common/health"
+)
+
+// CredentialsCheck returns a health check retrieving the credentials of the
+// AWS config, e.g. to detect an expired or missing role. The credentials are
+// only fetched again once expired with a credentials cache (see
+// AWSConfigBuilder.WithCredentialsCache).
+func CredentialsCheck(cfg aws.Config) health.CheckFunc {
+	return func(ctx context.Context) error {
+		if cfg.Credentials == nil {
+			return errors.New("no AWS credentials provider")
+		}
+		_, err := cfg.Credentials.Retrieve(ctx)
+		return err
+	}
+}
@@ -0,0 +1,20 @@
+package awsx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialsCheck(t *testing.T) {
+	cfg, err := NewAWSConfigBuilder(&Config{}).
+		WithStaticCredentials("test", "test", "").
+		Build(context.Background())
+	require.NoError(t, err)
+
+	assert.NoError(t, CredentialsCheck(cfg).Check(context.Background()))
+	assert.Error(t, CredentialsCheck(aws.Config{}).Check(context.Background()))
+}
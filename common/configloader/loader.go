@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/monorepo/common/secret"
 	"github.com/spf13/viper"
 	"go.uber.org/multierr"
@@ -63,8 +64,24 @@ var (
 	envBinderType     = reflect.TypeOf((*EnvBinder)(nil)).Elem()
 	secretBinderType  = reflect.TypeOf((*SecretBinder)(nil)).Elem()
 	secretStringKind  = reflect.TypeOf(secret.String("")).Kind()
+	secretBytesType   = reflect.TypeOf(secret.Bytes(nil))
 )
 
+// decodeHook is the viper default decode hook, also decoding the strings into
+// secret.Bytes, e.g. PEM encoded certificates.
+var decodeHook = viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+	stringToSecretBytesHookFunc,
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+))
+
+func stringToSecretBytesHookFunc(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	if f.Kind() != reflect.String || t != secretBytesType {
+		return data, nil
+	}
+	return secret.Bytes(data.(string)), nil
+}
+
 // Loader configures loads a configuration
 type Loader struct {
 	configFilePaths   []string
@@ -180,7 +197,7 @@ func (l *Loader) Load(configuration interface{}) error {
 	fileError := l.mergeConfigFiles()
 	l.postConfiguration(v, v.Type())
 
-	if err := l.v.Unmarshal(configuration); err != nil {
+	if err := l.v.Unmarshal(configuration, decodeHook); err != nil {
 		return multierr.Combine(fileError, err)
 	}
 
@@ -201,7 +218,7 @@ func (l *Loader) LoadExact(configuration interface{}) error {
 	fileError := l.mergeConfigFiles()
 	l.postConfiguration(v, v.Type())
 
-	if err := l.v.UnmarshalExact(configuration); err != nil {
+	if err := l.v.UnmarshalExact(configuration, decodeHook); err != nil {
 		return multierr.Combine(fileError, err)
 	}
 
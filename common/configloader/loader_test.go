@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/monorepo/common/secret"
 	"github.com/stretchr/testify/assert"
@@ -92,6 +93,25 @@ subconf:
 	assert.Equal(t, 2, conf.Subconf.Key)
 }
 
+func TestLoader_SecretBytes(t *testing.T) {
+	cfgData := strings.NewReader(`---
+cert: |
+  -----BEGIN CERTIFICATE-----
+timeout: 2s`)
+
+	var conf struct {
+		Cert    secret.Bytes  `mapstructure:"cert"`
+		Timeout time.Duration `mapstructure:"timeout"`
+	}
+	err := New("").
+		AddConfigFileReader("test_reader", "yaml", cfgData).
+		Load(&conf)
+	require.NoError(t, err)
+
+	assert.Equal(t, secret.Bytes("-----BEGIN CERTIFICATE-----\n"), conf.Cert)
+	assert.Equal(t, 2*time.Second, conf.Timeout)
+}
+
 func TestLoader_AddFileReadersInDifferentFormats(t *testing.T) {
 	cfgDataYAML := strings.NewReader(`---
 key: 1`)
@@ -303,3 +323,50 @@ from-conf-not-override: not-override`)
 	assert.Equal(t, "not-override", conf.SecretNotOverride)
 	assert.Equal(t, "override", conf.FromPost)
 }
+
+func TestSettings(t *testing.T) {
+	type tls struct {
+		CertFile string       `mapstructure:"cert_file"`
+		Key      secret.Bytes `mapstructure:"key"`
+	}
+	type conf struct {
+		Base `mapstructure:",squash"`
+
+		Password secret.String         `mapstructure:"password"`
+		Timeout  time.Duration         `mapstructure:"timeout"`
+		TLS      *tls                  `mapstructure:"tls"`
+		Params   map[string]ConfParams `mapstructure:"params"`
+		Hosts    []string              `mapstructure:"hosts"`
+		Ignored  string                `mapstructure:"-"`
+		NoTag    int
+		Extra    map[string]secret.String `mapstructure:"extra"`
+	}
+
+	settings := Settings(&conf{
+		Base:     Base{Debug: true, Value: 2},
+		Password: "p4ssw0rd",
+		Timeout:  3 * time.Second,
+		TLS:      &tls{CertFile: "cert.pem", Key: secret.Bytes("key")},
+		Hosts:    []string{"a", "b"},
+		Ignored:  "ignored",
+		NoTag:    4,
+		Extra:    map[string]secret.String{"token": "t0ken"},
+	})
+
+	assert.Equal(t, map[string]interface{}{
+		"debug":    true,
+		"value":    2,
+		"password": "*****",
+		"timeout":  "3s",
+		"tls": map[string]interface{}{
+			"cert_file": "cert.pem",
+			"key":       "*****",
+		},
+		"params": map[string]interface{}{},
+		"hosts":  []interface{}{"a", "b"},
+		"notag":  4,
+		"extra": map[string]interface{}{
+			"token": "*****",
+		},
+	}, settings)
+}
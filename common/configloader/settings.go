@@ -0,0 +1,115 @@
+package configloader
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	durationType      = reflect.TypeOf(time.Duration(0))
+)
+
+// Settings returns the settings of a loaded configuration, keyed by their
+// mapstructure names as in the configuration files, e.g. to display the
+// effective configuration of an application.
+//
+// The values implementing encoding.TextMarshaler are marshaled, so that the
+// secret.String and secret.Bytes values are masked.
+func Settings(configuration interface{}) map[string]interface{} {
+	settings, _ := settingsOf(reflect.ValueOf(configuration)).(map[string]interface{})
+	return settings
+}
+
+func settingsOf(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	t := v.Type()
+	switch {
+	case t.Implements(textMarshalerType):
+		if (t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface) && v.IsNil() {
+			return nil
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil
+		}
+		return string(text)
+	case t == durationType:
+		return v.Interface().(time.Duration).String()
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return settingsOf(v.Elem())
+
+	case reflect.Struct:
+		settings := make(map[string]interface{})
+		structSettings(v, settings)
+		return settings
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+		settings := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			settings[iter.Key().String()] = settingsOf(iter.Value())
+		}
+		return settings
+
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		settings := make([]interface{}, v.Len())
+		for i := range settings {
+			settings[i] = settingsOf(v.Index(i))
+		}
+		return settings
+
+	default:
+		return v.Interface()
+	}
+}
+
+// structSettings adds the settings of the exported fields of a struct, named
+// as by the Loader.
+func structSettings(v reflect.Value, settings map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		st := t.Field(i)
+		if !st.IsExported() {
+			continue
+		}
+
+		tag, isSquash := cleanTag(st.Tag.Get("mapstructure"))
+		if tag == "-" {
+			continue
+		}
+
+		sv := v.Field(i)
+		if isSquash {
+			for sv.Kind() == reflect.Ptr && !sv.IsNil() {
+				sv = sv.Elem()
+			}
+			if sv.Kind() == reflect.Struct {
+				structSettings(sv, settings)
+			}
+			continue
+		}
+
+		if tag == "" {
+			tag = strings.ToLower(st.Name)
+		}
+		settings[tag] = settingsOf(sv)
+	}
+}
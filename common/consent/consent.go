@@ -0,0 +1,156 @@
+// Package consent models the user consents collected by the Didomi consent
+// management platform, and propagated between services.
+package consent
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/monorepo/common/contextkeys"
+)
+
+const (
+	// CookieName is the name of the Didomi cookie set on the user browser.
+	CookieName = "didomi_token"
+	// Header is the header carrying the Didomi token between services.
+	Header = "X-Didomi-Token"
+)
+
+// ErrNoToken is returned by FromRequest when the request has no Didomi token.
+var ErrNoToken = errors.New("no didomi token")
+
+// Consent is the user consents of a Didomi token.
+//
+// Only the explicit consents are taken into account, not the legitimate
+// interests.
+type Consent struct {
+	// UserID is the Didomi user ID.
+	UserID string
+	// Purposes are the purposes the user consented to.
+	Purposes []string
+	// Vendors are the vendors the user consented to.
+	Vendors []string
+
+	token string
+}
+
+// didomiToken is the JSON payload of a Didomi token.
+type didomiToken struct {
+	UserID   string      `json:"user_id"`
+	Purposes didomiItems `json:"purposes"`
+	Vendors  didomiItems `json:"vendors"`
+}
+
+type didomiItems struct {
+	Enabled []string `json:"enabled"`
+}
+
+// Parse parses a Didomi token: the base64 encoded JSON value of the Didomi
+// cookie, possibly URL escaped.
+func Parse(token string) (*Consent, error) {
+	token = strings.TrimSpace(token)
+	if unescaped, err := url.PathUnescape(token); err == nil {
+		token = unescaped
+	}
+	if token == "" {
+		return nil, ErrNoToken
+	}
+
+	payload, err := decodeBase64(token)
+	if err != nil {
+		return nil, fmt.Errorf("decode didomi token: %w", err)
+	}
+
+	var t didomiToken
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return nil, fmt.Errorf("unmarshal didomi token: %w", err)
+	}
+
+	return &Consent{
+		UserID:   t.UserID,
+		Purposes: t.Purposes.Enabled,
+		Vendors:  t.Vendors.Enabled,
+		token:    token,
+	}, nil
+}
+
+// decodeBase64 decodes a base64 value, with the standard or URL alphabet, with
+// or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// FromRequest parses the Didomi token of the Header header, or of the
+// CookieName cookie.
+func FromRequest(req *http.Request) (*Consent, error) {
+	if token := req.Header.Get(Header); token != "" {
+		return Parse(token)
+	}
+	cookie, err := req.Cookie(CookieName)
+	if err != nil {
+		return nil, ErrNoToken
+	}
+	return Parse(cookie.Value)
+}
+
+// NewContext returns a copy of the context with the consent, under the
+// contextkeys.Consent key.
+func NewContext(ctx context.Context, c *Consent) context.Context {
+	return context.WithValue(ctx, contextkeys.Consent, c)
+}
+
+// FromContext returns the consent of the context, if any.
+func FromContext(ctx context.Context) (*Consent, bool) {
+	c, ok := ctx.Value(contextkeys.Consent).(*Consent)
+	return c, ok && c != nil
+}
+
+// Token returns the Didomi token the consent was parsed from.
+func (c *Consent) Token() string {
+	return c.token
+}
+
+// HasPurpose reports whether the user consented to the purpose.
+func (c *Consent) HasPurpose(purpose string) bool {
+	return c != nil && contains(c.Purposes, purpose)
+}
+
+// HasVendor reports whether the user consented to the vendor.
+func (c *Consent) HasVendor(vendor string) bool {
+	return c != nil && contains(c.Vendors, vendor)
+}
+
+// Missing returns the purposes and vendors the user did not consent to, among
+// the given ones.
+func (c *Consent) Missing(purposes, vendors []string) (missingPurposes, missingVendors []string) {
+	for _, p := range purposes {
+		if !c.HasPurpose(p) {
+			missingPurposes = append(missingPurposes, p)
+		}
+	}
+	for _, v := range vendors {
+		if !c.HasVendor(v) {
+			missingVendors = append(missingVendors, v)
+		}
+	}
+	return missingPurposes, missingVendors
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package consent
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPayload = `{
+	"user_id": "user",
+	"purposes": {"enabled": ["analytics", "geolocation_data"], "disabled": ["advertising"]},
+	"vendors": {"enabled": ["google"], "disabled": ["c:criteo"]},
+	"purposes_li": {"enabled": ["advertising"]},
+	"version": 2
+}`
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"standard":    base64.StdEncoding.EncodeToString([]byte(testPayload)),
+		"url":         base64.RawURLEncoding.EncodeToString([]byte(testPayload)),
+		"url escaped": url.QueryEscape(base64.StdEncoding.EncodeToString([]byte(testPayload))),
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, err := Parse(token)
+			require.NoError(t, err)
+
+			assert.Equal(t, "user", c.UserID)
+			assert.True(t, c.HasPurpose("analytics"))
+			assert.False(t, c.HasPurpose("advertising"))
+			assert.True(t, c.HasVendor("google"))
+			assert.False(t, c.HasVendor("c:criteo"))
+			assert.NotEmpty(t, c.Token())
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	_, err := Parse("")
+	assert.ErrorIs(t, err, ErrNoToken)
+
+	_, err = Parse("not base64!")
+	assert.Error(t, err)
+
+	_, err = Parse(base64.StdEncoding.EncodeToString([]byte("not json")))
+	assert.Error(t, err)
+}
+
+func TestFromRequest(t *testing.T) {
+	token := base64.StdEncoding.EncodeToString([]byte(testPayload))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://test", nil)
+	_, err := FromRequest(req)
+	assert.ErrorIs(t, err, ErrNoToken)
+
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: url.QueryEscape(token)})
+	c, err := FromRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, "user", c.UserID)
+
+	req.Header.Set(Header, base64.StdEncoding.EncodeToString([]byte(`{"user_id": "other"}`)))
+	c, err = FromRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, "other", c.UserID)
+}
+
+func TestConsent_Missing(t *testing.T) {
+	c, err := Parse(base64.StdEncoding.EncodeToString([]byte(testPayload)))
+	require.NoError(t, err)
+
+	purposes, vendors := c.Missing([]string{"analytics", "advertising"}, []string{"google", "c:criteo"})
+	assert.Equal(t, []string{"advertising"}, purposes)
+	assert.Equal(t, []string{"c:criteo"}, vendors)
+
+	var none *Consent
+	purposes, vendors = none.Missing([]string{"analytics"}, nil)
+	assert.Equal(t, []string{"analytics"}, purposes)
+	assert.Empty(t, vendors)
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	c := &Consent{UserID: "user"}
+	got, ok := FromContext(NewContext(context.Background(), c))
+	assert.True(t, ok)
+	assert.Same(t, c, got)
+}
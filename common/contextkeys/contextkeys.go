@@ -39,4 +39,6 @@ const (
 	BrandConfig
 	// Language identifier
 	Language
+	// Consent is the user consents, value is a *consent.Consent
+	Consent
 )
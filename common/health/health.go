@@ -0,0 +1,315 @@
+// Package health runs the health checks of the application components, and
+// serves them as the liveness and readiness probes of the pods.
+//
+// The components register named checks, e.g. a database pool:
+//
+//	h := health.New().WithShutdownContext(app.Context())
+//	h.Register("database", health.CheckFunc(db.PingContext), health.WithTimeout(time.Second))
+//	h.Register("users-api", client.HealthCheck(usersURL+"/healthz"), health.NonCritical())
+//	router.Handle(health.ReadinessPath, h.ReadinessHandler())
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/monorepo/common/monitoring/metrics"
+)
+
+const (
+	// DefaultCheckTimeout is the default timeout of the checks.
+	DefaultCheckTimeout = 5 * time.Second
+
+	// DefaultCacheTTL is the default time the check results are cached, so
+	// that frequent probes don't overload the checked dependencies.
+	DefaultCacheTTL = 5 * time.Second
+)
+
+var (
+	// ErrCheckTimeout is the error of the checks which didn't return within
+	// their timeout.
+	ErrCheckTimeout = errors.New("health check timed out")
+
+	// ErrShuttingDown is the readiness error of a shutting down application.
+	ErrShuttingDown = errors.New("shutting down")
+)
+
+// Status is the status of a check, or the aggregated status of all the checks.
+type Status string
+
+// All available values for Status.
+const (
+	// StatusPass is the status of a healthy component.
+	StatusPass Status = "pass"
+	// StatusWarn is the status of a failing non critical component, or the
+	// aggregated status when only non critical components fail.
+	StatusWarn Status = "warn"
+	// StatusFail is the status of a failing critical component, or the
+	// aggregated status when at least one critical component fails.
+	StatusFail Status = "fail"
+)
+
+// Check checks the health of a component.
+type Check interface {
+	Check(ctx context.Context) error
+}
+
+// CheckFunc is a function implementing the Check interface, e.g.
+// sql.DB.PingContext.
+type CheckFunc func(ctx context.Context) error
+
+// Check implements the Check interface.
+func (f CheckFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// CheckOption customizes a registered check.
+type CheckOption func(*check)
+
+// WithTimeout sets the timeout of the check, DefaultCheckTimeout by default.
+func WithTimeout(timeout time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// NonCritical makes the check non critical: its failures only degrade the
+// aggregated status to StatusWarn, and don't fail the readiness.
+func NonCritical() CheckOption {
+	return func(c *check) {
+		c.critical = false
+	}
+}
+
+// Report is the result of the checks.
+type Report struct {
+	Status Status                 `json:"status"`
+	Error  string                 `json:"error,omitempty"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the result of a check.
+type CheckResult struct {
+	Status    Status    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+	Duration  string    `json:"duration"`
+}
+
+// Health runs the registered checks.
+type Health struct {
+	cacheTTL     time.Duration
+	shuttingDown atomic.Bool
+
+	sh                 metrics.StatsdHandler
+	serviceCheckPrefix string
+
+	mu     sync.RWMutex
+	checks []*check
+
+	now func() time.Time
+}
+
+// check is a registered check, with its cached result.
+type check struct {
+	name     string
+	check    Check
+	timeout  time.Duration
+	critical bool
+
+	// mu is held while the check runs, so that the concurrent probes wait for
+	// its result instead of checking the component again.
+	mu     sync.Mutex
+	result CheckResult
+}
+
+// New instantiates a Health without any check.
+func New() *Health {
+	return &Health{
+		cacheTTL: DefaultCacheTTL,
+		now:      time.Now,
+	}
+}
+
+// WithCacheTTL sets the time the check results are cached, DefaultCacheTTL by
+// default. Zero disables the cache.
+func (h *Health) WithCacheTTL(ttl time.Duration) *Health {
+	h.cacheTTL = ttl
+	return h
+}
+
+// WithServiceChecks mirrors the check results as Datadog service checks,
+// named after the given prefix and the check names, e.g. "my_app.database".
+func (h *Health) WithServiceChecks(sh metrics.StatsdHandler, prefix string) *Health {
+	h.sh = sh
+	h.serviceCheckPrefix = prefix
+	return h
+}
+
+// WithShutdownContext fails the readiness once ctx is done, e.g. the
+// application.Application context.
+func (h *Health) WithShutdownContext(ctx context.Context) *Health {
+	context.AfterFunc(ctx, h.Shutdown)
+	return h
+}
+
+// Register registers a critical check, with the DefaultCheckTimeout timeout.
+// Registering a check with the name of a registered one replaces it.
+func (h *Health) Register(name string, c Check, opts ...CheckOption) *Health {
+	ch := &check{
+		name:     name,
+		check:    c,
+		timeout:  DefaultCheckTimeout,
+		critical: true,
+	}
+	for _, opt := range opts {
+		opt(ch)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, registered := range h.checks {
+		if registered.name == name {
+			h.checks[i] = ch
+			return h
+		}
+	}
+	h.checks = append(h.checks, ch)
+	return h
+}
+
+// Shutdown fails the readiness, for the load balancers to stop sending
+// requests during the graceful shutdown.
+func (h *Health) Shutdown() {
+	h.shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether Shutdown has been called.
+func (h *Health) ShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
+
+// Check runs the registered checks concurrently, or returns their cached
+// results, and aggregates their status. The checks are not canceled with ctx,
+// only its values are used, see WithTimeout.
+func (h *Health) Check(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := append([]*check(nil), h.checks...)
+	h.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = h.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusPass,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+	for i, c := range checks {
+		result := results[i]
+		report.Checks[c.name] = result
+
+		switch {
+		case result.Status == StatusFail:
+			report.Status = StatusFail
+		case result.Status == StatusWarn && report.Status == StatusPass:
+			report.Status = StatusWarn
+		}
+	}
+	return report
+}
+
+// run runs a check, unless its cached result is still fresh.
+func (h *Health) run(ctx context.Context, c *check) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.result.CheckedAt.IsZero() && h.now().Sub(c.result.CheckedAt) < h.cacheTTL {
+		return c.result
+	}
+
+	// The result is cached for the next probes: it must not depend on the
+	// probe which runs the check, e.g. be canceled when the prober gives up
+	// before the check timeout. The check is then only bounded by its timeout.
+	start := h.now()
+	err := runWithTimeout(context.WithoutCancel(ctx), c.check, c.timeout)
+
+	c.result = CheckResult{
+		Status:    StatusPass,
+		Critical:  c.critical,
+		CheckedAt: start,
+		Duration:  h.now().Sub(start).String(),
+	}
+	if err != nil {
+		c.result.Error = err.Error()
+		c.result.Status = StatusFail
+		if !c.critical {
+			c.result.Status = StatusWarn
+		}
+	}
+
+	h.serviceCheck(c.name, c.result)
+
+	return c.result
+}
+
+// runWithTimeout runs the check, and doesn't wait for it longer than the
+// timeout if it ignores the context.
+func runWithTimeout(ctx context.Context, c Check, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				errc <- fmt.Errorf("health check panicked: %v", v)
+			}
+		}()
+		errc <- c.Check(ctx)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrCheckTimeout
+		}
+		return ctx.Err()
+	}
+}
+
+// serviceCheck mirrors a check result as a Datadog service check.
+func (h *Health) serviceCheck(name string, result CheckResult) {
+	if h.sh == nil {
+		return
+	}
+
+	status := metrics.ServiceCheckStatusOk
+	switch result.Status {
+	case StatusWarn:
+		status = metrics.ServiceCheckStatusWarn
+	case StatusFail:
+		status = metrics.ServiceCheckStatusCritical
+	}
+
+	h.sh.ServiceCheck(&metrics.StatsdServiceCheck{
+		Name:      h.serviceCheckPrefix + "." + name,
+		Status:    status,
+		Timestamp: result.CheckedAt,
+		Message:   result.Error,
+	})
+}
@@ -0,0 +1,206 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/monitoring/metrics"
+)
+
+// countCheck counts its calls, and returns err.
+type countCheck struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (c *countCheck) Check(ctx context.Context) error {
+	c.calls.Add(1)
+	return c.err
+}
+
+// serviceCheckStatsdHandler records the service checks.
+type serviceCheckStatsdHandler struct {
+	metrics.StatsdHandler
+
+	mu     sync.Mutex
+	checks map[string]metrics.StatsdServiceCheckStatus
+}
+
+func (h *serviceCheckStatsdHandler) ServiceCheck(sc *metrics.StatsdServiceCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[sc.Name] = sc.Status
+}
+
+func TestHealth_Check(t *testing.T) {
+	errDown := errors.New("down")
+
+	tests := map[string]struct {
+		register   func(h *Health)
+		wantStatus Status
+	}{
+		"no checks": {
+			register:   func(h *Health) {},
+			wantStatus: StatusPass,
+		},
+		"passing": {
+			register: func(h *Health) {
+				h.Register("a", &countCheck{})
+				h.Register("b", &countCheck{}, NonCritical())
+			},
+			wantStatus: StatusPass,
+		},
+		"failing non critical": {
+			register: func(h *Health) {
+				h.Register("a", &countCheck{})
+				h.Register("b", &countCheck{err: errDown}, NonCritical())
+			},
+			wantStatus: StatusWarn,
+		},
+		"failing critical": {
+			register: func(h *Health) {
+				h.Register("a", &countCheck{err: errDown})
+				h.Register("b", &countCheck{err: errDown}, NonCritical())
+			},
+			wantStatus: StatusFail,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := New()
+			tt.register(h)
+
+			report := h.Check(context.Background())
+			assert.Equal(t, tt.wantStatus, report.Status)
+		})
+	}
+}
+
+func TestHealth_Check_result(t *testing.T) {
+	h := New()
+	h.Register("database", &countCheck{err: errors.New("connection refused")})
+
+	report := h.Check(context.Background())
+	require.Contains(t, report.Checks, "database")
+	result := report.Checks["database"]
+	assert.Equal(t, StatusFail, result.Status)
+	assert.True(t, result.Critical)
+	assert.Equal(t, "connection refused", result.Error)
+	assert.False(t, result.CheckedAt.IsZero())
+	assert.NotEmpty(t, result.Duration)
+}
+
+func TestHealth_Check_cache(t *testing.T) {
+	now := time.Now()
+	h := New().WithCacheTTL(time.Minute)
+	h.now = func() time.Time { return now }
+
+	c := &countCheck{}
+	h.Register("a", c)
+
+	h.Check(context.Background())
+	h.Check(context.Background())
+	assert.Equal(t, int32(1), c.calls.Load())
+
+	now = now.Add(time.Minute)
+	h.Check(context.Background())
+	assert.Equal(t, int32(2), c.calls.Load())
+}
+
+func TestHealth_Check_timeout(t *testing.T) {
+	h := New()
+	h.Register("blocking", CheckFunc(func(ctx context.Context) error {
+		// The check ignores the context.
+		time.Sleep(time.Second)
+		return nil
+	}), WithTimeout(10*time.Millisecond))
+
+	start := time.Now()
+	report := h.Check(context.Background())
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, ErrCheckTimeout.Error(), report.Checks["blocking"].Error)
+}
+
+func TestHealth_Check_canceled_probe(t *testing.T) {
+	h := New().WithCacheTTL(time.Minute)
+	c := &countCheck{}
+	h.Register("slow", CheckFunc(func(ctx context.Context) error {
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return c.Check(ctx)
+	}))
+
+	// The prober gives up in the middle of the check.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report := h.Check(ctx)
+	assert.Equal(t, StatusPass, report.Status)
+
+	// The cached result is not a failure for the next probes.
+	report = h.Check(context.Background())
+	assert.Equal(t, StatusPass, report.Status)
+	assert.Empty(t, report.Checks["slow"].Error)
+	assert.Equal(t, int32(1), c.calls.Load())
+}
+
+func TestHealth_Check_panic(t *testing.T) {
+	h := New()
+	h.Register("panicking", CheckFunc(func(ctx context.Context) error {
+		panic("boom")
+	}))
+
+	report := h.Check(context.Background())
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, "health check panicked: boom", report.Checks["panicking"].Error)
+}
+
+func TestHealth_Register_replace(t *testing.T) {
+	h := New()
+	h.Register("a", &countCheck{err: errors.New("down")})
+	h.Register("a", &countCheck{})
+
+	report := h.Check(context.Background())
+	assert.Equal(t, StatusPass, report.Status)
+	assert.Len(t, report.Checks, 1)
+}
+
+func TestHealth_WithServiceChecks(t *testing.T) {
+	sh := &serviceCheckStatsdHandler{
+		StatsdHandler: metrics.NoopStatsdHandler,
+		checks:        make(map[string]metrics.StatsdServiceCheckStatus),
+	}
+	h := New().WithServiceChecks(sh, "my_app")
+	h.Register("a", &countCheck{})
+	h.Register("b", &countCheck{err: errors.New("down")}, NonCritical())
+	h.Register("c", &countCheck{err: errors.New("down")})
+
+	h.Check(context.Background())
+
+	assert.Equal(t, map[string]metrics.StatsdServiceCheckStatus{
+		"my_app.a": metrics.ServiceCheckStatusOk,
+		"my_app.b": metrics.ServiceCheckStatusWarn,
+		"my_app.c": metrics.ServiceCheckStatusCritical,
+	}, sh.checks)
+}
+
+func TestHealth_WithShutdownContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := New().WithShutdownContext(ctx)
+	assert.False(t, h.ShuttingDown())
+
+	cancel()
+	assert.Eventually(t, h.ShuttingDown, time.Second, time.Millisecond)
+}
@@ -0,0 +1,54 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// The paths of the probes served by Handler.
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// Handler serves the liveness probe on LivenessPath and the readiness probe on
+// ReadinessPath.
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(LivenessPath, h.LivenessHandler())
+	mux.Handle(ReadinessPath, h.ReadinessHandler())
+	return mux
+}
+
+// LivenessHandler serves the liveness probe. It doesn't run the checks: a
+// failing dependency should not restart the pods depending on it.
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, Report{Status: StatusPass})
+	})
+}
+
+// ReadinessHandler serves the readiness probe, with the report of the checks.
+// It responds with a 503 status code if a critical check fails or if the
+// application is shutting down, and with a 200 otherwise.
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.ShuttingDown() {
+			writeReport(w, Report{Status: StatusFail, Error: ErrShuttingDown.Error()})
+			return
+		}
+		writeReport(w, h.Check(r.Context()))
+	})
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if report.Status == StatusFail {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
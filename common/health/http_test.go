@@ -0,0 +1,72 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth_Handler(t *testing.T) {
+	failing := &countCheck{}
+	h := New().WithCacheTTL(0)
+	h.Register("database", &countCheck{})
+	h.Register("cache", failing)
+
+	tests := []struct {
+		name       string
+		setup      func()
+		path       string
+		wantCode   int
+		wantStatus Status
+	}{
+		{
+			name:       "ready",
+			path:       ReadinessPath,
+			wantCode:   http.StatusOK,
+			wantStatus: StatusPass,
+		},
+		{
+			name:       "not ready",
+			setup:      func() { failing.err = errors.New("down") },
+			path:       ReadinessPath,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusFail,
+		},
+		{
+			name:       "alive with failing checks",
+			path:       LivenessPath,
+			wantCode:   http.StatusOK,
+			wantStatus: StatusPass,
+		},
+		{
+			name:       "shutting down",
+			setup:      func() { failing.err = nil; h.Shutdown() },
+			path:       ReadinessPath,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+
+			resp := httptest.NewRecorder()
+			h.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantCode, resp.Code)
+			assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+			var report Report
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+			assert.Equal(t, tt.wantStatus, report.Status)
+		})
+	}
+}
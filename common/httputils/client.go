@@ -3,20 +3,26 @@ package httputils
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/f2prateek/train"
 	"github.com/monorepo/common/monitoring/metrics"
+	"golang.org/x/net/http2"
 
 	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/httputils/signing"
 	"github.com/monorepo/common/httputils/svcauth"
+	"github.com/monorepo/common/logging"
 	"github.com/monorepo/common/secret"
+	"github.com/monorepo/common/tlsx"
 )
 
 // Client is an http.Client wrapper.
@@ -25,8 +31,27 @@ type Client struct {
 	isTraced    bool
 	isMonitored bool
 	sqp         secret.QueryParams
+	backend     ObservabilityBackend
+	limiter     interceptors.Limiter
+	hedging     *interceptors.Hedging
+	bodyLimit   *interceptors.BodyLimit
+	faults      *interceptors.FaultInjection
 }
 
+// ObservabilityBackend is the backend used by a Client to monitor and trace
+// its requests.
+type ObservabilityBackend uint8
+
+// All available values for ObservabilityBackend.
+const (
+	// BackendDatadog monitors requests with the global statsd handler and
+	// traces them with dd-trace-go. It is the default.
+	BackendDatadog ObservabilityBackend = iota
+	// BackendOTEL monitors and traces requests with the global OTEL meter and
+	// tracer providers.
+	BackendOTEL
+)
+
 // HTTPTransportWithInterceptors override http.Transport type to support the registration of
 // train.Interceptors middleware.
 // See Client.appendInterceptors() and Client.prependInterceptors() methods.
@@ -85,13 +110,21 @@ func (t *HTTPTransportWithInterceptors) PrependInterceptors(is ...train.Intercep
 	t.interceptors = append(is, t.interceptors...)
 }
 
+// Interceptors returns the registered interceptors, from the outermost to the
+// innermost.
+func (t *HTTPTransportWithInterceptors) Interceptors() []train.Interceptor {
+	return append([]train.Interceptor(nil), t.interceptors...)
+}
+
 // NewClient returns a *Client with the specified timeout and keepalive.
 // If keepalive is 0, it is disabled.
 func NewClient(timeout, keepalive time.Duration) *Client {
 	tr := NewHTTPTransportWithInterceptors(timeout, keepalive)
+	bodyLimit := interceptors.NewBodyLimit(0)
 	tr.AppendInterceptors(
-	//interceptors.NewUniqueID(),
-	//interceptors.NewBrandForwarding(),
+		interceptors.NewUniqueID(),
+		interceptors.NewBrandForwarding(),
+		bodyLimit,
 	)
 	return &Client{
 		Client: &http.Client{
@@ -100,9 +133,20 @@ func NewClient(timeout, keepalive time.Duration) *Client {
 		},
 		isMonitored: false,
 		isTraced:    false,
+		bodyLimit:   bodyLimit,
 	}
 }
 
+// Interceptors returns the interceptors registered on the Client, from the
+// outermost to the innermost.
+func (client *Client) Interceptors() []train.Interceptor {
+	tr, ok := client.Transport.(*HTTPTransportWithInterceptors)
+	if !ok {
+		return nil
+	}
+	return tr.Interceptors()
+}
+
 // appendInterceptors allows to register new interceptors to the Client
 func (client *Client) appendInterceptors(is ...train.Interceptor) {
 	tr := client.Transport.(*HTTPTransportWithInterceptors)
@@ -117,7 +161,79 @@ func (client *Client) prependInterceptors(is ...train.Interceptor) {
 
 // WithLimiter limits the number of concurrent requests.
 func (client *Client) WithLimiter(count int) *Client {
-	client.appendInterceptors(interceptors.NewLimiter(count))
+	client.limiter = interceptors.NewLimiter(count)
+	if client.hedging != nil {
+		client.hedging.WithLimiter(client.limiter)
+	}
+	client.appendInterceptors(client.limiter)
+	return client
+}
+
+// WithMaxResponseSize limits the size of the response bodies. Responses
+// announcing a larger Content-Length are rejected before their body is read,
+// otherwise reading the body fails once the limit is exceeded. Both fail with
+// an error matching interceptors.ErrResponseTooLarge.
+//
+// The limit can be overridden for a call with
+// interceptors.ContextWithMaxResponseSize.
+func (client *Client) WithMaxResponseSize(size int64) *Client {
+	client.bodyLimit.WithMaxResponseSize(size)
+	return client
+}
+
+// WithMaxRequestSize limits the size of the request bodies. Larger requests
+// are not sent, and fail with an error matching
+// interceptors.ErrRequestTooLarge. The bodies of unknown size are read in
+// memory, up to the limit, to be checked.
+func (client *Client) WithMaxRequestSize(size int64) *Client {
+	client.bodyLimit.WithMaxRequestSize(size)
+	return client
+}
+
+// WithRetry retries the requests failing with a transient error.
+// See interceptors.NewRetry for the retry policy.
+//
+// The retry interceptor is registered before all the already registered
+// interceptors, so that they are run for each attempt. It should then be
+// called after Observe, WithMonitor and WithTracer for each attempt to be
+// monitored and traced on its own.
+func (client *Client) WithRetry(retry *interceptors.Retry) *Client {
+	client.prependInterceptors(retry)
+	return client
+}
+
+// WithHedging sends hedged requests for the slow idempotent requests.
+// See interceptors.NewHedging for the hedging policy. The hedged requests
+// respect the budget of the client limiter, see WithLimiter.
+//
+// Each hedged request is monitored on its own, and the winning one is marked
+// on the request span. WithHedging must then be called before Observe and
+// WithTracer.
+func (client *Client) WithHedging(hedging *interceptors.Hedging) *Client {
+	if client.isTraced {
+		panic("httputils Client.WithHedging should be set before tracer to mark the winning request on its span")
+	}
+	if client.limiter != nil {
+		hedging.WithLimiter(client.limiter)
+	}
+	client.hedging = hedging
+	client.prependInterceptors(hedging)
+	return client
+}
+
+// WithCache caches the GET responses in the given store, following their
+// Cache-Control, Expires, ETag and Last-Modified headers. Cache hits, misses
+// and revalidations are monitored with the global statsd handler.
+// See interceptors.NewCache for the caching rules.
+//
+// The cache interceptor is registered before all the already registered
+// interceptors, so that the responses served from the cache are not monitored
+// and traced as requests. It should then be called after Observe, WithMonitor
+// and WithTracer.
+func (client *Client) WithCache(store interceptors.CacheStore) *Client {
+	client.prependInterceptors(interceptors.
+		NewCache(store).
+		WithMonitor(metrics.GetGlobalStatsdHandler()))
 	return client
 }
 
@@ -127,29 +243,97 @@ func (client *Client) WithAuthorizationHeader() *Client {
 	return client
 }
 
-// WithCircuitBreaker set a circuit Breaker with monitoring.
-// backPressureThreshold is the number of possible consecutive failure
-// duration of the open circuit
-//func (client *Client) WithCircuitBreaker(name string, backPressureThreshold uint64, duration time.Duration) *Client {
-//	client.appendInterceptors(interceptors.NewBreaker(name, backPressureThreshold, duration))
-//	return client
-//}
+// WithDeadlinePropagation sends the remaining time before the deadline of the
+// request context in a header, for the called services not to work longer
+// than the caller waits for them. See interceptors.NewDeadline, and
+// middleware.NewDeadline server side.
+func (client *Client) WithDeadlinePropagation() *Client {
+	client.appendInterceptors(interceptors.NewDeadline())
+	return client
+}
+
+// WithSigner signs the requests with the given signer, e.g. signing.NewHMAC
+// or signing.NewSigV4. See signing.NewInterceptor.
+//
+// It must be called after the methods changing the signed parts of the
+// requests, e.g. WithCompression.
+func (client *Client) WithSigner(signer signing.Signer) *Client {
+	client.appendInterceptors(signing.NewInterceptor(signer))
+	return client
+}
+
+// WithCircuitBreaker set a circuit Breaker with monitoring, for each target host.
+// backPressureThreshold is the number of possible consecutive failure,
+// duration is the duration of the open circuit.
+// When the circuit is open, requests fail fast with an error matching
+// interceptors.ErrCircuitOpen.
+func (client *Client) WithCircuitBreaker(name string, backPressureThreshold uint64, duration time.Duration) *Client {
+	client.appendInterceptors(interceptors.
+		NewBreaker(name, backPressureThreshold, duration).
+		WithMonitor(metrics.GetGlobalStatsdHandler()))
+	return client
+}
+
+// WithRateLimit limits the rate of the requests to requestsPerSecond for each
+// target host, or for each route of the given route matchers, with bursts of
+// up to burst requests. Throttled and rejected requests are monitored with
+// the global statsd handler.
+// Requests wait for their turn, unless they would exceed their context
+// deadline: they then fail fast with an error matching
+// interceptors.ErrRateLimited. See interceptors.NewRateLimiter.
+func (client *Client) WithRateLimit(requestsPerSecond float64, burst int, rm ...interceptors.RouteMatcher) *Client {
+	client.appendInterceptors(interceptors.
+		NewRateLimiter(requestsPerSecond, burst).
+		WithRouteMatchers(rm...).
+		WithMonitor(metrics.GetGlobalStatsdHandler()))
+	return client
+}
+
+// WithCompression decompresses transparently the responses compressed with
+// one of the given codecs, gzip, deflate, zstd or br by default. The request
+// bodies of at least requestThreshold bytes are compressed with the first
+// codec, unless requestThreshold is 0. Compressed and uncompressed bytes
+// counts are monitored with the global statsd handler.
+// See interceptors.NewCompression.
+func (client *Client) WithCompression(requestThreshold int64, codecs ...interceptors.Codec) *Client {
+	compression := interceptors.
+		NewCompression(codecs...).
+		WithMonitor(metrics.GetGlobalStatsdHandler())
+	if requestThreshold > 0 {
+		compression.WithRequestCompression(requestThreshold)
+	}
+	client.appendInterceptors(compression)
+	return client
+}
 
 // WithConsentChecker propagates user consents through HTTP using the Didomi cookie
-// if provided in the request context
-//func (client *Client) WithConsentChecker(logger logging.Logger) *Client {
-//	client.appendInterceptors(interceptors.NewConsentChecker(logger))
-//	return client
-//}
+// if provided in the request context, to the hosts listed by the given rules
+// only. The requests matching the given rules are blocked or stripped when the
+// user did not consent to them.
+func (client *Client) WithConsentChecker(logger logging.Logger, rules ...interceptors.ConsentRule) *Client {
+	client.appendInterceptors(interceptors.NewConsentChecker(logger).WithRules(rules...))
+	return client
+}
+
+// WithObservabilityBackend selects the backend used by Observe and WithTracer.
+// It must be called before them.
+func (client *Client) WithObservabilityBackend(backend ObservabilityBackend) *Client {
+	if client.isTraced || client.isMonitored {
+		panic("httputils Client.WithObservabilityBackend should be set before observability")
+	}
+	client.backend = backend
+	return client
+}
 
 // Observe activates the monitoring and tracing of this client
 func (client *Client) Observe(rp ...interceptors.RouteMatcher) *Client {
 	panicIfAlreadySet(client, "both")
-	sh := metrics.GetGlobalStatsdHandler()
-	client.appendInterceptors(interceptors.NewMonitoring(sh, rp...))
-	client.prependInterceptors(interceptors.
-		NewTracing().
-		WithSecretQueryParams(client.sqp...))
+	if client.backend == BackendOTEL {
+		client.appendInterceptors(interceptors.NewOTELMonitoring(metrics.GetGlobalMeterProvider(), rp...))
+	} else {
+		client.appendInterceptors(interceptors.NewMonitoring(metrics.GetGlobalStatsdHandler(), rp...))
+	}
+	client.prependInterceptors(client.newTracing())
 	return client
 }
 
@@ -160,15 +344,80 @@ func (client *Client) WithMonitor(sh metrics.StatsdHandler, rp ...interceptors.R
 	return client
 }
 
+// WithOTELMonitor activates the OTEL monitoring of the request associated with this client.
+func (client *Client) WithOTELMonitor(mp metrics.MeterProvider, rp ...interceptors.RouteMatcher) *Client {
+	panicIfAlreadySet(client, "monitor")
+	client.appendInterceptors(interceptors.NewOTELMonitoring(mp, rp...))
+	return client
+}
+
+// ObserveConnections activates the monitoring of the connection pool of this
+// client, with the observability backend. See interceptors.NewConnMonitoring.
+func (client *Client) ObserveConnections() *Client {
+	if client.backend == BackendOTEL {
+		return client.WithOTELConnMonitor(metrics.GetGlobalMeterProvider())
+	}
+	return client.WithConnMonitor(metrics.GetGlobalStatsdHandler())
+}
+
+// WithConnMonitor activates the monitoring of the connection pool of this
+// client: new and reused connections, idle time and in-flight requests per
+// target host.
+func (client *Client) WithConnMonitor(sh metrics.StatsdHandler) *Client {
+	client.appendInterceptors(interceptors.NewConnMonitoring(sh))
+	return client
+}
+
+// WithOTELConnMonitor activates the OTEL monitoring of the connection pool of
+// this client.
+func (client *Client) WithOTELConnMonitor(mp metrics.MeterProvider) *Client {
+	client.appendInterceptors(interceptors.NewOTELConnMonitoring(mp))
+	return client
+}
+
 // WithTracer activates the tracer for the requests done with this client.
 func (client *Client) WithTracer() *Client {
 	panicIfAlreadySet(client, "tracer")
-	client.prependInterceptors(interceptors.
+	client.prependInterceptors(client.newTracing())
+	return client
+}
+
+// newTracing returns the tracing interceptor of the observability backend.
+func (client *Client) newTracing() train.Interceptor {
+	if client.backend == BackendOTEL {
+		return interceptors.
+			NewOTELTracing().
+			WithSecretQueryParams(client.sqp...)
+	}
+	return interceptors.
 		NewTracing().
-		WithSecretQueryParams(client.sqp...))
+		WithSecretQueryParams(client.sqp...)
+}
+
+// WithFaultInjection injects faults in the requests matching the given rules,
+// or the rules of their context (see interceptors.ContextWithFaultRules), for
+// chaos testing. See interceptors.NewFaultInjection.
+//
+// The injected faults are tagged by the monitoring and tracing interceptors,
+// it must then be called after Observe, WithMonitor and WithTracer.
+func (client *Client) WithFaultInjection(rules ...interceptors.FaultRule) *Client {
+	if client.faults != nil {
+		panic("httputils Client.WithFaultInjection should be set once, use SetFaultRules to change the rules")
+	}
+	client.faults = interceptors.NewFaultInjection(rules...)
+	client.appendInterceptors(client.faults)
 	return client
 }
 
+// SetFaultRules replaces at runtime the fault injection rules given to
+// WithFaultInjection. No rules disables the fault injection.
+func (client *Client) SetFaultRules(rules ...interceptors.FaultRule) {
+	if client.faults == nil {
+		panic("httputils Client.SetFaultRules should be called after WithFaultInjection")
+	}
+	client.faults.SetRules(rules...)
+}
+
 // WithUserAgent define the user agent of the HTTP client.
 func (client *Client) WithUserAgent(name, version string) *Client {
 	client.appendInterceptors(interceptors.NewUserAgent(name, version))
@@ -190,6 +439,74 @@ func (client *Client) WithMaxIdleConnsPerHost(value int) *Client {
 	return client
 }
 
+// WithMaxIdleConns defined the value of MaxIdleConns of the HTTP transport.
+func (client *Client) WithMaxIdleConns(value int) *Client {
+	tr := client.Transport.(*HTTPTransportWithInterceptors)
+	tr.MaxIdleConns = value
+	return client
+}
+
+// WithHTTP2 enables HTTP/2 for the "https" requests, negotiated with the
+// servers, and with prior knowledge for the "http" requests if conf.H2C is
+// set. The h2c requests are not sent through the proxy.
+//
+// It must be called once, after WithTLSConfig and WithTLSReloader.
+func (client *Client) WithHTTP2(conf HTTP2Config) *Client {
+	tr := client.Transport.(*HTTPTransportWithInterceptors)
+	h2, err := http2.ConfigureTransports(&tr.Transport)
+	if err != nil {
+		panic("httputils Client.WithHTTP2 should be set once: " + err.Error())
+	}
+	conf.configure(h2)
+
+	if conf.H2C {
+		h2c := &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return tr.DialContext(ctx, network, addr)
+			},
+		}
+		conf.configure(h2c)
+		tr.RegisterProtocol("http", h2c)
+	}
+
+	if conf.MaxConcurrentStreamsPerHost > 0 {
+		client.appendInterceptors(interceptors.NewHostLimiter(conf.MaxConcurrentStreamsPerHost))
+	}
+	return client
+}
+
+// WithTLSConfig specifies the TLS configuration of the HTTP transport, e.g.
+// to trust a private certificate authority or to send a client certificate.
+func (client *Client) WithTLSConfig(config *tls.Config) *Client {
+	tr := client.Transport.(*HTTPTransportWithInterceptors)
+	tr.TLSClientConfig = config
+	return client
+}
+
+// WithTLSReloader specifies the TLS configuration of the HTTP transport from
+// the given reloader: the new connections use the latest loaded certificate
+// authorities and client certificate, and the idle connections are closed
+// after each reload. See tlsx.NewReloader.
+func (client *Client) WithTLSReloader(reloader *tlsx.Reloader) *Client {
+	tr := client.Transport.(*HTTPTransportWithInterceptors)
+	reloader.OnReload(tr.CloseIdleConnections)
+	return client.WithTLSConfig(reloader.TLSConfig())
+}
+
+// WithProxy sends all the requests through the given proxy, instead of the
+// proxy of the environment (HTTP_PROXY, HTTPS_PROXY and NO_PROXY). A nil URL
+// disables the proxy.
+func (client *Client) WithProxy(proxyURL *url.URL) *Client {
+	tr := client.Transport.(*HTTPTransportWithInterceptors)
+	if proxyURL == nil {
+		tr.Proxy = nil
+	} else {
+		tr.Proxy = http.ProxyURL(proxyURL)
+	}
+	return client
+}
+
 // WithServiceAuth defines the authorization interceptor.
 func (client *Client) WithServiceAuth(conf svcauth.Conf) *Client {
 	client.appendInterceptors(svcauth.NewServiceAuth(conf))
@@ -218,27 +535,34 @@ func (client *Client) WithSecretQueryParams(names ...string) *Client {
 // If the body must be ignored, then pass io.Discard.
 // The function returns the HTTP status code.
 func (client *Client) Do(ctx context.Context, dst io.Writer, request *http.Request) (statusCode int, err error) {
+	statusCode, _, err = client.doWithHeader(ctx, dst, request)
+	return statusCode, err
+}
+
+// doWithHeader is Do, also returning the response header.
+func (client *Client) doWithHeader(ctx context.Context, dst io.Writer, request *http.Request) (statusCode int, header http.Header, err error) {
 	if dst == nil {
 		panic(`destination cannot be nil, as this might panic when the response body is not empty; if you want to ignore the body, then pass io.Discard instead`)
 	}
 
 	response, err := client.Client.Do(request.WithContext(ctx))
 	if err != nil {
-		return 0, fmt.Errorf("request failed: %w", err)
+		return 0, nil, fmt.Errorf("request failed: %w", err)
 	}
 
 	defer func() { _ = response.Body.Close() }()
 
 	_, err = io.Copy(dst, response.Body)
 	if err != nil {
-		return 0, fmt.Errorf("copy failed: %w", err)
+		return 0, nil, fmt.Errorf("copy failed: %w", err)
 	}
 
-	return response.StatusCode, nil
+	return response.StatusCode, response.Header, nil
 }
 
 // DoAndUnmarshalJSON executes a request and unmarshall the response body in the provided pointer
 // The function returns the HTTP status code.
+// An application/problem+json error response is returned as a *problem.Problem error.
 func (client *Client) DoAndUnmarshalJSON(ctx context.Context, v interface{}, request *http.Request) (statusCode int, err error) {
 	response, err := client.Client.Do(request.WithContext(ctx))
 	if err != nil {
@@ -249,8 +573,13 @@ func (client *Client) DoAndUnmarshalJSON(ctx context.Context, v interface{}, req
 
 	if response.StatusCode >= http.StatusBadRequest {
 		var buf bytes.Buffer
-		r := io.TeeReader(response.Body, &buf)
-		err = json.NewDecoder(r).Decode(v)
+		if _, err = io.Copy(&buf, response.Body); err != nil {
+			return response.StatusCode, fmt.Errorf("read status %d: %w", response.StatusCode, err)
+		}
+		if p, ok := decodeProblem(response.Header, buf.Bytes()); ok {
+			return response.StatusCode, p
+		}
+		err = json.NewDecoder(bytes.NewReader(buf.Bytes())).Decode(v)
 		if err != nil {
 			return response.StatusCode, fmt.Errorf("decode status %d: body %q", response.StatusCode, buf.String())
 		}
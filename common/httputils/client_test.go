@@ -2,7 +2,9 @@ package httputils
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -10,14 +12,25 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/f2prateek/train"
+	"github.com/monorepo/common/contextkeys"
+	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/httputils/middleware"
+	"github.com/monorepo/common/httputils/polarisheaders"
+	"github.com/monorepo/common/httputils/signing"
+	"github.com/monorepo/common/logging"
 	"github.com/monorepo/common/monitoring/metrics"
+	"github.com/monorepo/common/problem"
+	"github.com/monorepo/common/tlsx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // genInterceptor generates a custom HTTP interceptor.
@@ -82,6 +95,21 @@ func Test_Client_Interceptors(t *testing.T) {
 	require.Equal(t, "1,2,3,4,5,6", responseBody.String())
 }
 
+func Test_Client_Interceptors_list(t *testing.T) {
+	client := NewClient(time.Second, 0).WithLimiter(1)
+
+	got := client.Interceptors()
+	require.Len(t, got, 4)
+	assert.IsType(t, &interceptors.UniqueID{}, got[0])
+	assert.IsType(t, interceptors.Limiter(nil), got[3])
+
+	// The returned slice is a copy.
+	got[0] = nil
+	assert.NotNil(t, client.Interceptors()[0])
+
+	assert.Nil(t, (&Client{Client: &http.Client{}}).Interceptors())
+}
+
 func Test_Client_Do_can_ignore_the_body_if_the_destination_parameter_is_nil(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
@@ -152,6 +180,36 @@ func Test_Client_DoAndUnmarshalJSON_nicer_error_on_504(t *testing.T) {
 	require.Empty(t, to)
 }
 
+func Test_Client_DoAndUnmarshalJSON_problem(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			problem.Write(w, problem.New(http.StatusNotFound, "no item 42"))
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient(1*time.Second, 0)
+	httpRequest, err := http.NewRequest("GET", ts.URL, nil)
+	require.NoError(t, err)
+
+	to := make(map[string]string)
+	statusCode, err := client.DoAndUnmarshalJSON(
+		context.Background(),
+		&to,
+		httpRequest,
+	)
+	require.Equal(t, http.StatusNotFound, statusCode)
+
+	var p *problem.Problem
+	require.True(t, errors.As(err, &p))
+	require.Equal(t, http.StatusNotFound, p.Status)
+	require.Equal(t, "no item 42", p.Detail)
+	require.Empty(t, to)
+
+	// The problem of the dependency is not sent as is by the handlers.
+	require.Equal(t, problem.New(http.StatusBadGateway, ""), problem.FromError(err))
+}
+
 func Test_Client_DoAndUnmarshalJSON_504_still_default_to_parsing(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
@@ -177,45 +235,45 @@ func Test_Client_DoAndUnmarshalJSON_504_still_default_to_parsing(t *testing.T) {
 	require.Equal(t, map[string]string{"Test": "test"}, to)
 }
 
-//func TestNewClient_adds_unique_id_interceptor(t *testing.T) {
-//	client := NewClient(time.Second, 0)
-//
-//	var uniqueID string
-//	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-//		uniqueID = req.Header.Get(polarisheaders.UniqueID)
-//	}))
-//	defer ts.Close()
-//
-//	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
-//	require.NoError(t, err)
-//
-//	ctx := context.WithValue(context.Background(), contextkeys.UniqueID, "my_unique_id")
-//	req = req.WithContext(ctx)
-//
-//	_, err = client.Client.Do(req)
-//	assert.NoError(t, err)
-//	assert.Equal(t, "my_unique_id", uniqueID)
-//}
-
-//func TestNewClient_adds_brand_forwarding_interceptor(t *testing.T) {
-//	client := NewClient(time.Second, 0)
-//
-//	var forwardedHost string
-//	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-//		forwardedHost = req.Header.Get(polarisheaders.ForwardedHost)
-//	}))
-//	defer ts.Close()
-//
-//	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
-//	require.NoError(t, err)
-//
-//	ctx := context.WithValue(context.Background(), contextkeys.BrandOriginHost, "my_brand")
-//	req = req.WithContext(ctx)
-//
-//	_, err = client.Client.Do(req)
-//	assert.NoError(t, err)
-//	assert.Equal(t, "my_brand", forwardedHost)
-//}
+func TestNewClient_adds_unique_id_interceptor(t *testing.T) {
+	client := NewClient(time.Second, 0)
+
+	var uniqueID string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		uniqueID = req.Header.Get(polarisheaders.UniqueID)
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), contextkeys.UniqueID, "my_unique_id")
+	req = req.WithContext(ctx)
+
+	_, err = client.Client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "my_unique_id", uniqueID)
+}
+
+func TestNewClient_adds_brand_forwarding_interceptor(t *testing.T) {
+	client := NewClient(time.Second, 0)
+
+	var forwardedHost string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		forwardedHost = req.Header.Get(polarisheaders.ForwardedHost)
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), contextkeys.BrandOriginHost, "my_brand")
+	req = req.WithContext(ctx)
+
+	_, err = client.Client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "my_brand", forwardedHost)
+}
 
 func TestNewClient_timeouts(t *testing.T) {
 	client := NewClient(100*time.Millisecond, 0)
@@ -307,3 +365,348 @@ func Test_Client_WithSecretQueryParams(t *testing.T) {
 		})
 	})
 }
+
+func Test_Client_WithRetry(t *testing.T) {
+	var counter int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt64(&counter, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("Hello World"))
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0).
+		WithMonitor(metrics.NoopStatsdHandler).
+		WithRetry(interceptors.NewRetry([]time.Duration{time.Millisecond}))
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+
+	var body bytes.Buffer
+	statusCode, err := client.Do(context.Background(), &body, req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "Hello World", body.String())
+	assert.Equal(t, int64(2), atomic.LoadInt64(&counter))
+}
+
+func Test_Client_WithCache(t *testing.T) {
+	var counter int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&counter, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("Hello World"))
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0).
+		WithMonitor(metrics.NoopStatsdHandler).
+		WithCache(interceptors.NewLRUCacheStore(10))
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+
+		var body bytes.Buffer
+		statusCode, err := client.Do(context.Background(), &body, req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, "Hello World", body.String())
+	}
+	assert.Equal(t, int64(1), atomic.LoadInt64(&counter))
+}
+
+func Test_Client_WithCache_authorization(t *testing.T) {
+	var counter int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&counter, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte(req.Header.Get("Authorization")))
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0).
+		WithAuthorizationHeader().
+		WithRetry(interceptors.NewRetry([]time.Duration{time.Millisecond})).
+		WithCache(interceptors.NewLRUCacheStore(10))
+
+	// The responses to the requests of a user are not served to another one.
+	for _, token := range []string{"alice", "bob"} {
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+
+		var body bytes.Buffer
+		ctx := context.WithValue(context.Background(), contextkeys.AuthToken, token)
+		statusCode, err := client.Do(ctx, &body, req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, "Bearer "+token, body.String())
+	}
+	assert.Equal(t, int64(2), atomic.LoadInt64(&counter))
+}
+
+func Test_Client_WithHedging(t *testing.T) {
+	t.Run("registers hedging between tracing and the limiter", func(t *testing.T) {
+		hedging := interceptors.NewHedging(time.Millisecond)
+		client := NewClient(time.Second, 0).
+			WithLimiter(10).
+			WithHedging(hedging).
+			WithTracer()
+
+		tr := client.Transport.(*HTTPTransportWithInterceptors)
+		require.Len(t, tr.interceptors, 6)
+		assert.IsType(t, &interceptors.Tracing{}, tr.interceptors[0])
+		assert.Same(t, hedging, tr.interceptors[1])
+		assert.IsType(t, &interceptors.UniqueID{}, tr.interceptors[2])
+		assert.IsType(t, &interceptors.HeaderPropagation{}, tr.interceptors[3])
+		assert.IsType(t, &interceptors.BodyLimit{}, tr.interceptors[4])
+		assert.IsType(t, interceptors.Limiter(nil), tr.interceptors[5])
+	})
+
+	t.Run("panics when tracer is already set", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewClient(time.Second, time.Second).
+				WithTracer().
+				WithHedging(interceptors.NewHedging(time.Millisecond))
+		})
+	})
+}
+
+func Test_Client_WithRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0).
+		WithRateLimit(1, 1)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(context.Background(), io.Discard, req)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, err = http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(ctx, io.Discard, req)
+	assert.True(t, errors.Is(err, interceptors.ErrRateLimited))
+}
+
+func Test_Client_WithConsentChecker(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0).
+		WithConsentChecker(logging.NewNoop(), interceptors.ConsentRule{Vendors: []string{"google"}})
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(context.Background(), io.Discard, req)
+	assert.True(t, errors.Is(err, interceptors.ErrConsentMissing))
+}
+
+func Test_Client_WithDeadlinePropagation(t *testing.T) {
+	var timeout time.Duration
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		timeout, _ = polarisheaders.ParseTimeout(req.Header.Get(polarisheaders.Deadline))
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0).
+		WithDeadlinePropagation()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(ctx, io.Discard, req)
+	require.NoError(t, err)
+	assert.Greater(t, timeout, time.Duration(0))
+	assert.LessOrEqual(t, timeout, 500*time.Millisecond)
+}
+
+func Test_Client_WithSigner(t *testing.T) {
+	h := signing.NewHMAC("key-1", "secret")
+	ts := httptest.NewServer(middleware.NewSignatureVerifier(h, nil).Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = io.Copy(w, req.Body)
+		}),
+	))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0).
+		WithSigner(h)
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader("body"))
+	require.NoError(t, err)
+
+	var body bytes.Buffer
+	statusCode, err := client.Do(context.Background(), &body, req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "body", body.String())
+}
+
+func Test_Client_WithFaultInjection(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0).
+		WithFaultInjection(interceptors.FaultRule{Percentage: 100, StatusCode: http.StatusServiceUnavailable})
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	statusCode, err := client.Do(context.Background(), io.Discard, req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, statusCode)
+
+	client.SetFaultRules()
+	statusCode, err = client.Do(context.Background(), io.Discard, req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	assert.Panics(t, func() {
+		client.WithFaultInjection()
+	})
+}
+
+func Test_Client_WithMaxResponseSize(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.(http.Flusher).Flush()
+		_, _ = io.WriteString(w, `{"key": "a long value"}`)
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0).
+		WithMaxResponseSize(10)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = client.Do(context.Background(), &buf, req)
+	assert.True(t, errors.Is(err, interceptors.ErrResponseTooLarge))
+
+	var v map[string]string
+	_, err = client.DoAndUnmarshalJSON(context.Background(), &v, req)
+	assert.True(t, errors.Is(err, interceptors.ErrResponseTooLarge))
+
+	ctx := interceptors.ContextWithMaxResponseSize(context.Background(), 100)
+	_, err = client.DoAndUnmarshalJSON(ctx, &v, req)
+	require.NoError(t, err)
+	assert.Equal(t, "a long value", v["key"])
+}
+
+func Test_Client_WithCompression(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "gzip", req.Header.Get("Content-Encoding"))
+		r, err := gzip.NewReader(req.Body)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, r)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gw := gzip.NewWriter(w)
+		_, _ = io.WriteString(gw, `{"key": "value"}`)
+		_ = gw.Close()
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0).
+		WithCompression(10)
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"key": "a long enough value"}`))
+	require.NoError(t, err)
+
+	var v map[string]string
+	statusCode, err := client.DoAndUnmarshalJSON(context.Background(), &v, req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "value", v["key"])
+}
+
+func Test_Client_WithTLSReloader(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	reloader, err := tlsx.NewReloader(tlsx.Conf{CA: ca})
+	require.NoError(t, err)
+
+	client := NewClient(time.Second, 0).
+		WithTLSReloader(reloader)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+
+	statusCode, err := client.Do(context.Background(), io.Discard, req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	// Without the CA, the server certificate is not trusted.
+	_, err = NewClient(time.Second, 0).Do(context.Background(), io.Discard, req)
+	require.Error(t, err)
+}
+
+func Test_Client_WithHTTP2(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.WriteString(w, req.Proto)
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		ts := httptest.NewUnstartedServer(handler)
+		ts.EnableHTTP2 = true
+		ts.StartTLS()
+		defer ts.Close()
+
+		client := NewClient(time.Second, 0).
+			WithTLSConfig(ts.Client().Transport.(*http.Transport).TLSClientConfig.Clone()).
+			WithHTTP2(HTTP2Config{})
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+
+		var body bytes.Buffer
+		_, err = client.Do(context.Background(), &body, req)
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/2.0", body.String())
+	})
+
+	t.Run("h2c", func(t *testing.T) {
+		ts := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+		defer ts.Close()
+
+		client := NewClient(time.Second, 0).
+			WithHTTP2(HTTP2Config{H2C: true, MaxConcurrentStreamsPerHost: 10})
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+
+		var body bytes.Buffer
+		_, err = client.Do(context.Background(), &body, req)
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/2.0", body.String())
+	})
+}
+
+func Test_Client_WithObservabilityBackend(t *testing.T) {
+	t.Run("uses the OTEL tracing interceptor", func(t *testing.T) {
+		client := NewClient(time.Second, 0).
+			WithObservabilityBackend(BackendOTEL).
+			WithTracer()
+
+		tr := client.Transport.(*HTTPTransportWithInterceptors)
+		require.Len(t, tr.interceptors, 4)
+		assert.IsType(t, &interceptors.OTELTracing{}, tr.interceptors[0])
+	})
+
+	t.Run("panics when tracer is already set", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewClient(time.Second, time.Second).
+				WithTracer().
+				WithObservabilityBackend(BackendOTEL)
+		})
+	})
+}
@@ -1,11 +1,404 @@
 package httputils
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/eapache/go-resiliency/retrier"
+	"golang.org/x/net/http2"
+
+	"github.com/monorepo/common/configloader"
+	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/httputils/svcauth"
+	"github.com/monorepo/common/monitoring/metrics"
+	"github.com/monorepo/common/retrierx"
+	"github.com/monorepo/common/tlsx"
 )
 
 // HTTPClient contains configuration for HTTP clients
+//
+// Use NewClientFromConfig to build a Client from it. Zero values disable the
+// corresponding options.
 type HTTPClient struct {
 	Timeout   time.Duration `mapstructure:"timeout"`
 	KeepAlive time.Duration `mapstructure:"keepalive"`
+
+	// MaxIdleConns and MaxIdleConnsPerHost override the transport ones.
+	MaxIdleConns        int `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int `mapstructure:"max_idle_conns_per_host"`
+	// Proxy is the URL of the proxy, "none" to disable the proxy of the
+	// environment.
+	Proxy string      `mapstructure:"proxy"`
+	TLS   tlsx.Conf   `mapstructure:"tls"`
+	HTTP2 HTTP2Config `mapstructure:"http2"`
+
+	UserAgent           UserAgentConfig `mapstructure:"user_agent"`
+	AuthorizationHeader bool            `mapstructure:"authorization_header"`
+	DeadlinePropagation bool            `mapstructure:"deadline_propagation"`
+	ServiceAuth         svcauth.Conf    `mapstructure:"service_auth"`
+	SecretQueryParams   []string        `mapstructure:"secret_query_params"`
+
+	// Limiter is the maximum number of concurrent requests.
+	Limiter        int                  `mapstructure:"limiter"`
+	RateLimit      RateLimitConfig      `mapstructure:"rate_limit"`
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+	Retry          RetryConfig          `mapstructure:"retry"`
+	Hedging        HedgingConfig        `mapstructure:"hedging"`
+	// CacheSize is the number of responses kept in an in memory cache. With
+	// AuthorizationHeader, only the responses explicitly public are cached.
+	CacheSize   int               `mapstructure:"cache_size"`
+	Compression CompressionConfig `mapstructure:"compression"`
+
+	MaxResponseSize int64 `mapstructure:"max_response_size"`
+	MaxRequestSize  int64 `mapstructure:"max_request_size"`
+
+	Observability  ObservabilityConfig  `mapstructure:"observability"`
+	FaultInjection FaultInjectionConfig `mapstructure:"fault_injection"`
+}
+
+// UserAgentConfig contains the application name and version sent in the
+// User-Agent header.
+type UserAgentConfig struct {
+	Name    string `mapstructure:"name"`
+	Version string `mapstructure:"version"`
+}
+
+// HTTP2Config contains the HTTP/2 configuration, see Client.WithHTTP2.
+type HTTP2Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// H2C sends the "http" requests with HTTP/2 with prior knowledge, e.g.
+	// for the in-cluster traffic.
+	H2C bool `mapstructure:"h2c"`
+	// MaxConcurrentStreamsPerHost limits the number of concurrent requests
+	// for each target host, 0 means no limit.
+	MaxConcurrentStreamsPerHost int `mapstructure:"max_concurrent_streams_per_host"`
+	// StrictMaxConcurrentStreams waits for a stream of an open connection
+	// when the server limit is reached, instead of opening a new connection.
+	StrictMaxConcurrentStreams bool `mapstructure:"strict_max_concurrent_streams"`
+	// ReadIdleTimeout is the delay without frames after which a connection
+	// is health checked with a ping, 0 disables the health checks.
+	ReadIdleTimeout time.Duration `mapstructure:"read_idle_timeout"`
+	// PingTimeout is the delay after which a connection is closed if the ping
+	// is not answered, 15s by default.
+	PingTimeout time.Duration `mapstructure:"ping_timeout"`
+}
+
+// configure sets the options of the HTTP/2 transport.
+func (c HTTP2Config) configure(t *http2.Transport) {
+	t.StrictMaxConcurrentStreams = c.StrictMaxConcurrentStreams
+	t.ReadIdleTimeout = c.ReadIdleTimeout
+	t.PingTimeout = c.PingTimeout
+}
+
+// RateLimitConfig contains the configuration of the rate limiter, see
+// Client.WithRateLimit.
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
+	Burst             int     `mapstructure:"burst"`
+}
+
+// CircuitBreakerConfig contains the configuration of the circuit breaker, see
+// Client.WithCircuitBreaker.
+type CircuitBreakerConfig struct {
+	Name      string        `mapstructure:"name"`
+	Threshold uint64        `mapstructure:"threshold"`
+	Duration  time.Duration `mapstructure:"duration"`
+}
+
+// RetryConfig contains the configuration of the retries, see
+// interceptors.NewRetry.
+type RetryConfig struct {
+	Count int           `mapstructure:"count"`
+	Delay time.Duration `mapstructure:"delay"`
+	// Backoff is the evolution of the delay between retries: "constant",
+	// "linear" or "exponential".
+	Backoff              string        `mapstructure:"backoff"`
+	StatusCodes          []int         `mapstructure:"status_codes"`
+	NonIdempotentMethods bool          `mapstructure:"non_idempotent_methods"`
+	MaxRetryAfter        time.Duration `mapstructure:"max_retry_after"`
+}
+
+// HedgingConfig contains the configuration of the hedged requests, see
+// interceptors.NewHedging.
+type HedgingConfig struct {
+	Delay      time.Duration `mapstructure:"delay"`
+	Percentile float64       `mapstructure:"percentile"`
+	MaxHedges  int           `mapstructure:"max_hedges"`
+}
+
+// CompressionConfig contains the configuration of the compression, see
+// Client.WithCompression.
+type CompressionConfig struct {
+	Enabled          bool  `mapstructure:"enabled"`
+	RequestThreshold int64 `mapstructure:"request_threshold"`
+}
+
+// ObservabilityConfig contains the monitoring and tracing configuration of
+// HTTP clients.
+type ObservabilityConfig struct {
+	// Backend is "datadog" or "otel".
+	Backend string `mapstructure:"backend"`
+	Monitor bool   `mapstructure:"monitor"`
+	Trace   bool   `mapstructure:"trace"`
+	// RoutePatterns are "METHOD /path" static routes tagged in the metrics.
+	RoutePatterns []string `mapstructure:"route_patterns"`
+	// Connections monitors the connection pool, see
+	// Client.ObserveConnections.
+	Connections bool `mapstructure:"connections"`
+}
+
+// FaultInjectionConfig contains the fault injection configuration, for chaos
+// testing, see Client.WithFaultInjection.
+type FaultInjectionConfig struct {
+	Enabled bool              `mapstructure:"enabled"`
+	Rules   []FaultRuleConfig `mapstructure:"rules"`
+}
+
+// FaultRuleConfig contains the configuration of a fault injection rule, see
+// interceptors.FaultRule.
+type FaultRuleConfig struct {
+	Hosts []string `mapstructure:"hosts"`
+	// Routes are "METHOD /path" static routes.
+	Routes     []string `mapstructure:"routes"`
+	Percentage float64  `mapstructure:"percentage"`
+
+	Delay        time.Duration `mapstructure:"delay"`
+	Error        bool          `mapstructure:"error"`
+	StatusCode   int           `mapstructure:"status_code"`
+	TruncateBody int64         `mapstructure:"truncate_body"`
+}
+
+// FaultRules returns the fault injection rules, e.g. to update them at
+// runtime with Client.SetFaultRules.
+func (c FaultInjectionConfig) FaultRules() ([]interceptors.FaultRule, error) {
+	rules := make([]interceptors.FaultRule, 0, len(c.Rules))
+	for _, rc := range c.Rules {
+		rule := interceptors.FaultRule{
+			Hosts:        rc.Hosts,
+			Percentage:   rc.Percentage,
+			Delay:        rc.Delay,
+			Error:        rc.Error,
+			StatusCode:   rc.StatusCode,
+			TruncateBody: rc.TruncateBody,
+		}
+		for _, pattern := range rc.Routes {
+			rm, err := newRouteMatcher(pattern)
+			if err != nil {
+				return nil, err
+			}
+			rule.Routes = append(rule.Routes, rm)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Defaults sets default configuration values.
+func (*HTTPClient) Defaults(l *configloader.Loader) {
+	l.SetDefault("timeout", 10*time.Second)
+	l.SetDefault("keepalive", 30*time.Second)
+	// The svcauth.Conf default is enabled, it must be opted in for clients.
+	l.SetDefault("service_auth.enabled", false)
+	l.SetDefault("retry.backoff", "exponential")
+	l.SetDefault("hedging.max_hedges", 1)
+	l.SetDefault("observability.backend", "datadog")
+	l.SetDefault("observability.monitor", true)
+	l.SetDefault("observability.trace", true)
+}
+
+// Envs bind environment keys to env variables
+func (*HTTPClient) Envs(l *configloader.Loader) {
+	l.BindEnv("timeout")
+	l.BindEnv("keepalive")
+	l.BindEnv("max_idle_conns")
+	l.BindEnv("max_idle_conns_per_host")
+	l.BindEnv("proxy")
+	l.BindEnv("tls.ca_file")
+	l.BindEnv("tls.cert_file")
+	l.BindEnv("tls.key_file")
+	l.BindEnv("tls.insecure_skip_verify")
+	l.BindEnv("http2.enabled")
+	l.BindEnv("http2.h2c")
+	l.BindEnv("deadline_propagation")
+	l.BindEnv("limiter")
+	l.BindEnv("rate_limit.requests_per_second")
+	l.BindEnv("rate_limit.burst")
+	l.BindEnv("retry.count")
+	l.BindEnv("retry.delay")
+	l.BindEnv("hedging.delay")
+	l.BindEnv("max_response_size")
+	l.BindEnv("max_request_size")
+	l.BindEnv("observability.backend")
+	l.BindEnv("observability.monitor")
+	l.BindEnv("observability.trace")
+	l.BindEnv("observability.connections")
+	l.BindEnv("fault_injection.enabled")
+}
+
+// NewClientFromConfig returns a *Client with all the options of the given
+// configuration, registered in the order documented by the Client methods.
+func NewClientFromConfig(conf HTTPClient) (*Client, error) {
+	client := NewClient(conf.Timeout, conf.KeepAlive)
+
+	if conf.MaxIdleConns > 0 {
+		client.WithMaxIdleConns(conf.MaxIdleConns)
+	}
+	if conf.MaxIdleConnsPerHost > 0 {
+		client.WithMaxIdleConnsPerHost(conf.MaxIdleConnsPerHost)
+	}
+	if conf.Proxy == "none" {
+		client.WithProxy(nil)
+	} else if conf.Proxy != "" {
+		proxyURL, err := url.Parse(conf.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy url: %w", err)
+		}
+		client.WithProxy(proxyURL)
+	}
+	if !conf.TLS.IsZero() {
+		reloader, err := tlsx.NewReloader(conf.TLS)
+		if err != nil {
+			return nil, err
+		}
+		client.WithTLSReloader(reloader)
+	}
+	if conf.HTTP2.Enabled {
+		client.WithHTTP2(conf.HTTP2)
+	}
+
+	backend, err := conf.Observability.backend()
+	if err != nil {
+		return nil, err
+	}
+	client.WithObservabilityBackend(backend)
+
+	if len(conf.SecretQueryParams) > 0 {
+		client.WithSecretQueryParams(conf.SecretQueryParams...)
+	}
+	if conf.UserAgent.Name != "" {
+		client.WithUserAgent(conf.UserAgent.Name, conf.UserAgent.Version)
+	}
+	if conf.AuthorizationHeader {
+		client.WithAuthorizationHeader()
+	}
+	if conf.ServiceAuth.Enabled {
+		client.WithServiceAuth(conf.ServiceAuth)
+	}
+	if conf.DeadlinePropagation {
+		client.WithDeadlinePropagation()
+	}
+	client.WithMaxResponseSize(conf.MaxResponseSize)
+	client.WithMaxRequestSize(conf.MaxRequestSize)
+	if conf.Compression.Enabled {
+		client.WithCompression(conf.Compression.RequestThreshold)
+	}
+
+	if conf.Limiter > 0 {
+		client.WithLimiter(conf.Limiter)
+	}
+	if conf.RateLimit.RequestsPerSecond > 0 {
+		client.WithRateLimit(conf.RateLimit.RequestsPerSecond, conf.RateLimit.Burst)
+	}
+	if conf.CircuitBreaker.Threshold > 0 {
+		client.WithCircuitBreaker(conf.CircuitBreaker.Name, conf.CircuitBreaker.Threshold, conf.CircuitBreaker.Duration)
+	}
+	if conf.Hedging.Delay > 0 {
+		hedging := interceptors.NewHedging(conf.Hedging.Delay).WithMaxHedges(conf.Hedging.MaxHedges)
+		if conf.Hedging.Percentile > 0 {
+			hedging.WithPercentileDelay(conf.Hedging.Percentile)
+		}
+		client.WithHedging(hedging)
+	}
+
+	var rms []interceptors.RouteMatcher
+	for _, pattern := range conf.Observability.RoutePatterns {
+		rm, err := newRouteMatcher(pattern)
+		if err != nil {
+			return nil, err
+		}
+		rms = append(rms, rm)
+	}
+	switch {
+	case conf.Observability.Monitor && conf.Observability.Trace:
+		client.Observe(rms...)
+	case conf.Observability.Trace:
+		client.WithTracer()
+	case conf.Observability.Monitor && backend == BackendOTEL:
+		client.WithOTELMonitor(metrics.GetGlobalMeterProvider(), rms...)
+	case conf.Observability.Monitor:
+		client.WithMonitor(metrics.GetGlobalStatsdHandler(), rms...)
+	}
+	if conf.Observability.Connections {
+		client.ObserveConnections()
+	}
+	if conf.FaultInjection.Enabled {
+		rules, err := conf.FaultInjection.FaultRules()
+		if err != nil {
+			return nil, err
+		}
+		client.WithFaultInjection(rules...)
+	}
+
+	if conf.Retry.Count > 0 {
+		retry, err := conf.Retry.build()
+		if err != nil {
+			return nil, err
+		}
+		client.WithRetry(retry)
+	}
+	if conf.CacheSize > 0 {
+		client.WithCache(interceptors.NewLRUCacheStore(conf.CacheSize))
+	}
+
+	return client, nil
+}
+
+// build returns the retry interceptor.
+func (c RetryConfig) build() (*interceptors.Retry, error) {
+	var backoff []time.Duration
+	switch c.Backoff {
+	case "constant":
+		backoff = retrier.ConstantBackoff(c.Count, c.Delay)
+	case "linear":
+		backoff = retrierx.LinearBackoff(c.Count, c.Delay)
+	case "exponential", "":
+		backoff = retrier.ExponentialBackoff(c.Count, c.Delay)
+	default:
+		return nil, fmt.Errorf("unknown retry backoff %q", c.Backoff)
+	}
+
+	retry := interceptors.NewRetry(backoff)
+	if len(c.StatusCodes) > 0 {
+		retry.WithStatusCodes(c.StatusCodes...)
+	}
+	if c.NonIdempotentMethods {
+		retry.WithNonIdempotentMethods()
+	}
+	if c.MaxRetryAfter > 0 {
+		retry.WithMaxRetryAfter(c.MaxRetryAfter)
+	}
+	return retry, nil
+}
+
+// backend returns the observability backend.
+func (c ObservabilityConfig) backend() (ObservabilityBackend, error) {
+	switch strings.ToLower(c.Backend) {
+	case "datadog", "":
+		return BackendDatadog, nil
+	case "otel":
+		return BackendOTEL, nil
+	default:
+		return 0, fmt.Errorf("unknown observability backend %q", c.Backend)
+	}
+}
+
+// newRouteMatcher returns the route matcher of a "METHOD /path" pattern.
+func newRouteMatcher(pattern string) (interceptors.RouteMatcher, error) {
+	method, path, ok := strings.Cut(strings.TrimSpace(pattern), " ")
+	if !ok || path == "" {
+		return nil, fmt.Errorf(`invalid route pattern %q, expected "METHOD /path"`, pattern)
+	}
+	return interceptors.StaticRouteMatcher(strings.ToUpper(method), strings.TrimSpace(path)), nil
 }
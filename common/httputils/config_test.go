@@ -0,0 +1,205 @@
+package httputils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/configloader"
+	"github.com/monorepo/common/contextkeys"
+	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/tlsx"
+)
+
+type testConfig struct {
+	Client HTTPClient `mapstructure:"client"`
+}
+
+func TestHTTPClient_load(t *testing.T) {
+	t.Setenv("TEST_CLIENT_LIMITER", "12")
+
+	yaml := `
+client:
+  timeout: 2s
+  proxy: http://proxy:3128
+  tls:
+    ca: "-----BEGIN CERTIFICATE-----"
+    reload_interval: 1m
+  http2:
+    enabled: true
+    max_concurrent_streams_per_host: 100
+  user_agent:
+    name: app
+    version: 1.0.0
+  secret_query_params: [api_key]
+  retry:
+    count: 3
+    delay: 10ms
+    status_codes: [502, 503]
+  service_auth:
+    client_id: my-client
+  observability:
+    backend: otel
+    route_patterns: ["GET /users"]
+`
+	var conf testConfig
+	err := configloader.New("test").
+		AddConfigFileReader("config", "yaml", strings.NewReader(yaml)).
+		Load(&conf)
+	require.NoError(t, err)
+
+	assert.Equal(t, HTTPClient{
+		Timeout:   2 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Proxy:     "http://proxy:3128",
+		TLS: tlsx.Conf{
+			CA:             []byte("-----BEGIN CERTIFICATE-----"),
+			ReloadInterval: time.Minute,
+		},
+		HTTP2:             HTTP2Config{Enabled: true, MaxConcurrentStreamsPerHost: 100},
+		UserAgent:         UserAgentConfig{Name: "app", Version: "1.0.0"},
+		SecretQueryParams: []string{"api_key"},
+		Limiter:           12,
+		Retry: RetryConfig{
+			Count:       3,
+			Delay:       10 * time.Millisecond,
+			Backoff:     "exponential",
+			StatusCodes: []int{502, 503},
+		},
+		Hedging:     HedgingConfig{MaxHedges: 1},
+		ServiceAuth: conf.Client.ServiceAuth,
+		Observability: ObservabilityConfig{
+			Backend:       "otel",
+			Monitor:       true,
+			Trace:         true,
+			RoutePatterns: []string{"GET /users"},
+		},
+	}, conf.Client)
+	assert.Equal(t, "my-client", conf.Client.ServiceAuth.ClientID)
+	assert.False(t, conf.Client.ServiceAuth.Enabled)
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	var counter int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "app/1.0.0", req.Header.Get("User-Agent"))
+		if atomic.AddInt64(&counter, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = io.WriteString(w, "Hello World")
+	}))
+	defer ts.Close()
+
+	client, err := NewClientFromConfig(HTTPClient{
+		Timeout:         time.Second,
+		UserAgent:       UserAgentConfig{Name: "app", Version: "1.0.0"},
+		Limiter:         2,
+		MaxResponseSize: 5,
+		Retry:           RetryConfig{Count: 1, Delay: time.Millisecond, Backoff: "constant"},
+		Observability:   ObservabilityConfig{Monitor: true, Trace: true, Connections: true},
+	})
+	require.NoError(t, err)
+
+	tr := client.Transport.(*HTTPTransportWithInterceptors)
+	require.Len(t, tr.interceptors, 9)
+	assert.IsType(t, &interceptors.Retry{}, tr.interceptors[0])
+	assert.IsType(t, &interceptors.Tracing{}, tr.interceptors[1])
+	assert.IsType(t, &interceptors.Monitoring{}, tr.interceptors[7])
+	assert.IsType(t, &interceptors.ConnMonitoring{}, tr.interceptors[8])
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(context.Background(), io.Discard, req)
+	assert.True(t, errors.Is(err, interceptors.ErrResponseTooLarge))
+	assert.Equal(t, int64(2), atomic.LoadInt64(&counter))
+}
+
+func TestNewClientFromConfig_cache_with_authorization(t *testing.T) {
+	var counter int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&counter, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, req.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+
+	client, err := NewClientFromConfig(HTTPClient{
+		Timeout:             time.Second,
+		AuthorizationHeader: true,
+		Retry:               RetryConfig{Count: 1, Delay: time.Millisecond, Backoff: "constant"},
+		CacheSize:           10,
+	})
+	require.NoError(t, err)
+
+	for _, token := range []string{"alice", "bob"} {
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+
+		var body strings.Builder
+		ctx := context.WithValue(context.Background(), contextkeys.AuthToken, token)
+		_, err = client.Do(ctx, &body, req)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer "+token, body.String())
+	}
+	assert.Equal(t, int64(2), atomic.LoadInt64(&counter))
+}
+
+func TestFaultInjectionConfig_FaultRules(t *testing.T) {
+	conf := FaultInjectionConfig{
+		Enabled: true,
+		Rules: []FaultRuleConfig{{
+			Hosts:      []string{"api.example.com"},
+			Routes:     []string{"get /users"},
+			Percentage: 10,
+			Delay:      time.Second,
+			StatusCode: http.StatusServiceUnavailable,
+		}},
+	}
+
+	rules, err := conf.FaultRules()
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, []string{"api.example.com"}, rules[0].Hosts)
+	assert.Equal(t, 10.0, rules[0].Percentage)
+	assert.Equal(t, time.Second, rules[0].Delay)
+	assert.Equal(t, http.StatusServiceUnavailable, rules[0].StatusCode)
+	require.Len(t, rules[0].Routes, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	route, ok := rules[0].Routes[0].MatchRequest(req)
+	assert.True(t, ok)
+	assert.Equal(t, "get:/users", route)
+}
+
+func TestNewClientFromConfig_errors(t *testing.T) {
+	tests := map[string]HTTPClient{
+		"proxy":           {Proxy: "http://[::1"},
+		"tls version":     {TLS: tlsx.Conf{MinVersion: "2.0"}},
+		"tls ca file":     {TLS: tlsx.Conf{CAFile: "missing.pem"}},
+		"tls certificate": {TLS: tlsx.Conf{CertFile: "missing.pem", KeyFile: "missing.key"}},
+		"backend":         {Observability: ObservabilityConfig{Backend: "unknown"}},
+		"route pattern":   {Observability: ObservabilityConfig{Monitor: true, RoutePatterns: []string{"/users"}}},
+		"retry backoff":   {Retry: RetryConfig{Count: 1, Backoff: "unknown"}},
+		"fault route": {FaultInjection: FaultInjectionConfig{
+			Enabled: true,
+			Rules:   []FaultRuleConfig{{Routes: []string{"/users"}}},
+		}},
+	}
+
+	for name, conf := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewClientFromConfig(conf)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package httputils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/monorepo/common/health"
+)
+
+// HealthCheck returns a health check of the dependency served at rawURL, e.g.
+// its readiness probe. The check sends a GET request with the client, and
+// fails on request errors and on status codes >= 400.
+func (client *Client) HealthCheck(rawURL string) health.CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return err
+		}
+
+		statusCode, err := client.Do(ctx, io.Discard, req)
+		if err != nil {
+			return err
+		}
+		if statusCode >= http.StatusBadRequest {
+			return fmt.Errorf("unhealthy status code %d", statusCode)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,26 @@
+package httputils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Client_HealthCheck(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/readyz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0)
+
+	assert.NoError(t, client.HealthCheck(ts.URL+"/healthz").Check(context.Background()))
+	assert.EqualError(t, client.HealthCheck(ts.URL+"/readyz").Check(context.Background()), "unhealthy status code 503")
+	assert.Error(t, client.HealthCheck("http://127.0.0.1:0").Check(context.Background()))
+}
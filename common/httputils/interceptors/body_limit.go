@@ -0,0 +1,169 @@
+package interceptors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/f2prateek/train"
+)
+
+var (
+	// ErrResponseTooLarge is the error returned when a response body exceeds
+	// the maximum size of the BodyLimit interceptor.
+	ErrResponseTooLarge = errors.New("response body too large")
+	// ErrRequestTooLarge is the error returned when a request body exceeds the
+	// maximum size of the BodyLimit interceptor.
+	ErrRequestTooLarge = errors.New("request body too large")
+)
+
+// BodyTooLargeError is the error returned by the BodyLimit interceptor when a
+// body exceeds its maximum size. Use errors.Is(err, ErrResponseTooLarge) or
+// errors.Is(err, ErrRequestTooLarge) to detect it.
+type BodyTooLargeError struct {
+	// Err is either ErrResponseTooLarge or ErrRequestTooLarge.
+	Err error
+	// Limit is the maximum body size.
+	Limit int64
+	// ContentLength is the announced body size, -1 if unknown.
+	ContentLength int64
+}
+
+// Error implements the error interface.
+func (e *BodyTooLargeError) Error() string {
+	if e.ContentLength >= 0 {
+		return fmt.Sprintf("%s: %d bytes, limit is %d bytes", e.Err, e.ContentLength, e.Limit)
+	}
+	return fmt.Sprintf("%s: limit is %d bytes", e.Err, e.Limit)
+}
+
+// Unwrap allows to match the error with ErrResponseTooLarge or
+// ErrRequestTooLarge.
+func (e *BodyTooLargeError) Unwrap() error {
+	return e.Err
+}
+
+// BodyLimit is a HTTP client middleware limiting the size of the request and
+// response bodies.
+//
+// A response whose Content-Length exceeds the limit is rejected before its
+// body is read. Otherwise, reading the body fails once the limit is exceeded.
+// The maximum response size can be overridden for a request with
+// ContextWithMaxResponseSize.
+//
+// The request bodies are limited before they are sent: the bodies of unknown
+// size are read in memory, up to the limit, to be checked.
+type BodyLimit struct {
+	maxResponseSize int64
+	maxRequestSize  int64
+}
+
+// NewBodyLimit instantiates a new BodyLimit interceptor limiting the response
+// bodies to maxResponseSize bytes. A size <= 0 means no limit.
+func NewBodyLimit(maxResponseSize int64) *BodyLimit {
+	return &BodyLimit{
+		maxResponseSize: maxResponseSize,
+	}
+}
+
+// WithMaxResponseSize sets the maximum response body size. A size <= 0 means
+// no limit.
+func (l *BodyLimit) WithMaxResponseSize(size int64) *BodyLimit {
+	l.maxResponseSize = size
+	return l
+}
+
+// WithMaxRequestSize sets the maximum request body size. A size <= 0 means no
+// limit, which is the default.
+func (l *BodyLimit) WithMaxRequestSize(size int64) *BodyLimit {
+	l.maxRequestSize = size
+	return l
+}
+
+// Intercept implements the train.Interceptor interface
+func (l *BodyLimit) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+
+	maxRequest, maxResponse := l.maxRequestSize, l.maxResponseSize
+	if size, ok := maxResponseSize(req.Context()); ok {
+		maxResponse = size
+	}
+
+	if maxRequest > 0 && req.Body != nil && req.Body != http.NoBody {
+		if req.ContentLength > maxRequest {
+			return nil, &BodyTooLargeError{Err: ErrRequestTooLarge, Limit: maxRequest, ContentLength: req.ContentLength}
+		}
+		// A zero content length with a body means an unknown size.
+		if req.ContentLength <= 0 {
+			if err := bufferRequestBody(req, maxRequest); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	resp, err := chain.Proceed(req)
+	if err != nil || maxResponse <= 0 {
+		return resp, err
+	}
+
+	if resp.ContentLength > maxResponse {
+		_ = resp.Body.Close()
+		return nil, &BodyTooLargeError{Err: ErrResponseTooLarge, Limit: maxResponse, ContentLength: resp.ContentLength}
+	}
+	if resp.ContentLength < 0 {
+		resp.Body = newLimitedBody(resp.Body, maxResponse, ErrResponseTooLarge)
+	}
+
+	return resp, nil
+}
+
+// bufferRequestBody reads the request body of unknown size in memory, up to
+// limit bytes, so that a too large body is rejected before being sent.
+func bufferRequestBody(req *http.Request, limit int64) error {
+	defer func() { _ = req.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err != nil {
+		return fmt.Errorf("read request body: %w", err)
+	}
+	if int64(len(body)) > limit {
+		return &BodyTooLargeError{Err: ErrRequestTooLarge, Limit: limit, ContentLength: -1}
+	}
+
+	setRequestBody(req, body)
+	return nil
+}
+
+// limitedBody fails with a BodyTooLargeError once more than limit bytes are
+// read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	err       *BodyTooLargeError
+}
+
+func newLimitedBody(body io.ReadCloser, limit int64, err error) *limitedBody {
+	return &limitedBody{
+		ReadCloser: body,
+		remaining:  limit,
+		err:        &BodyTooLargeError{Err: err, Limit: limit, ContentLength: -1},
+	}
+}
+
+// Read implements io.Reader.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, b.err
+	}
+	// Read one byte more than the limit to detect the bodies exceeding it.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), b.err
+	}
+	return n, err
+}
@@ -0,0 +1,107 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyLimit_Intercept_response(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("chunked") != "" {
+			w.(http.Flusher).Flush()
+		}
+		_, _ = io.WriteString(w, strings.Repeat("a", 10))
+	}))
+	defer ts.Close()
+
+	tests := map[string]struct {
+		limit             int64
+		ctxLimit          int64
+		chunked           bool
+		wantErr           bool
+		wantContentLength int64
+	}{
+		"under the limit":            {limit: 10},
+		"content length over limit":  {limit: 9, wantErr: true, wantContentLength: 10},
+		"chunked under the limit":    {limit: 10, chunked: true},
+		"chunked over the limit":     {limit: 9, chunked: true, wantErr: true, wantContentLength: -1},
+		"no limit":                   {},
+		"context limit":              {limit: 100, ctxLimit: 5, wantErr: true, wantContentLength: 10},
+		"context disables the limit": {limit: 5, ctxLimit: -1},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := http.Client{Transport: train.Transport(NewBodyLimit(tt.limit))}
+
+			ctx := context.Background()
+			if tt.ctxLimit != 0 {
+				ctx = ContextWithMaxResponseSize(ctx, tt.ctxLimit)
+			}
+			url := ts.URL
+			if tt.chunked {
+				url += "?chunked=1"
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			var body []byte
+			resp, err := client.Do(req)
+			if err == nil {
+				body, err = io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+			}
+
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Equal(t, strings.Repeat("a", 10), string(body))
+				return
+			}
+			assert.True(t, errors.Is(err, ErrResponseTooLarge))
+			var btlErr *BodyTooLargeError
+			require.True(t, errors.As(err, &btlErr))
+			assert.Equal(t, tt.wantContentLength, btlErr.ContentLength)
+			if tt.chunked {
+				assert.Equal(t, strings.Repeat("a", int(tt.limit)), string(body))
+			}
+		})
+	}
+}
+
+func TestBodyLimit_Intercept_request(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		_, _ = io.Copy(io.Discard, req.Body)
+	}))
+	defer ts.Close()
+
+	client := http.Client{Transport: train.Transport(NewBodyLimit(0).WithMaxRequestSize(5))}
+
+	resp, err := client.Post(ts.URL, "text/plain", strings.NewReader("12345"))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	_, err = client.Post(ts.URL, "text/plain", strings.NewReader("123456"))
+	assert.True(t, errors.Is(err, ErrRequestTooLarge))
+
+	// Unknown content length.
+	_, err = client.Post(ts.URL, "text/plain", io.MultiReader(strings.NewReader("123456")))
+	assert.True(t, errors.Is(err, ErrRequestTooLarge))
+
+	// Unknown content length under the limit.
+	resp, err = client.Post(ts.URL, "text/plain", io.MultiReader(strings.NewReader("123")))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,378 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eapache/go-resiliency/breaker"
+	"github.com/f2prateek/train"
+
+	"github.com/monorepo/common/monitoring/metrics"
+)
+
+// ErrCircuitOpen is the error returned when a request is not sent because the
+// circuit breaker of its target host is open.
+//
+// The errors returned by the Breaker interceptor also match the
+// breaker.ErrBreakerOpen error of the go-resiliency package.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is the error returned by the Breaker interceptor when it
+// fails fast. Use errors.Is(err, ErrCircuitOpen) to detect it.
+type CircuitOpenError struct {
+	Name   string
+	Target string
+}
+
+// Error implements the error interface.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s: %s for target %s", e.Name, ErrCircuitOpen, e.Target)
+}
+
+// Is allows to match the error with ErrCircuitOpen and breaker.ErrBreakerOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen || target == breaker.ErrBreakerOpen
+}
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState uint8
+
+// All available values for BreakerState.
+const (
+	BreakerStateClosed BreakerState = iota
+	BreakerStateOpen
+	BreakerStateHalfOpen
+)
+
+// String implements Stringer.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerStateClosed:
+		return "closed"
+	case BreakerStateOpen:
+		return "open"
+	case BreakerStateHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerClassifier tells if the result of a request is a failure which must
+// be accounted by the circuit breaker.
+type BreakerClassifier func(resp *http.Response, err error) bool
+
+// BreakOnErrors classifies all request errors as failures, including the
+// ones of the next interceptors, e.g. ErrRateLimited or ErrConsentMissing.
+func BreakOnErrors(_ *http.Response, err error) bool {
+	return err != nil
+}
+
+// BreakOnTransportErrors classifies the errors of the transport as failures:
+// connection and TLS failures, network timeouts and interrupted responses
+// (see IsTransientError). The errors of the next interceptors, e.g.
+// ErrRateLimited or ErrConsentMissing, say nothing about the target health
+// and are not failures.
+func BreakOnTransportErrors(_ *http.Response, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var (
+		netErr    net.Error
+		recordErr tls.RecordHeaderError
+		certErr   *tls.CertificateVerificationError
+	)
+	return IsTransientError(err) || errors.As(err, &netErr) ||
+		errors.As(err, &recordErr) || errors.As(err, &certErr)
+}
+
+// BreakOnServerErrors classifies 5xx responses as failures.
+func BreakOnServerErrors(resp *http.Response, err error) bool {
+	return err == nil && resp.StatusCode >= http.StatusInternalServerError
+}
+
+// BreakOnTimeouts classifies timeouts, either from the network or from the
+// upstream server (504), as failures.
+func BreakOnTimeouts(resp *http.Response, err error) bool {
+	if err == nil {
+		return resp.StatusCode == http.StatusGatewayTimeout
+	}
+
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// BreakOn combines classifiers, a result is a failure if at least one of them
+// classifies it as such.
+func BreakOn(classifiers ...BreakerClassifier) BreakerClassifier {
+	return func(resp *http.Response, err error) bool {
+		for _, c := range classifiers {
+			if c(resp, err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Breaker is a HTTP client middleware implementing the circuit breaker pattern
+// for each target host.
+//
+// It follows the semantics of the go-resiliency breaker: from closed, the
+// circuit opens if errorThreshold failures are seen without a failure-free
+// period of at least timeout. From open, the circuit half-opens after timeout.
+// From half-open, the circuit closes after successThreshold consecutive
+// successes, or opens again on a single failure. While half-open, at most
+// successThreshold requests are in flight to the target, the others fail fast.
+//
+// Only the targets with failures have a circuit, the idle ones are forgotten.
+type Breaker struct {
+	name             string
+	errorThreshold   int
+	successThreshold int
+	timeout          time.Duration
+	classifier       BreakerClassifier
+	monitor          metrics.StatsdHandler
+
+	mu        sync.Mutex
+	circuits  map[string]*circuit
+	lastSweep time.Time
+}
+
+type circuit struct {
+	state     BreakerState
+	failures  int
+	successes int
+	lastError time.Time
+	openedAt  time.Time
+
+	// probes is the number of requests in flight while half-open, and
+	// generation identifies the current state, so that the probes sent in a
+	// previous state are not released twice.
+	probes     int
+	generation uint64
+}
+
+// NewBreaker instantiates a new Breaker interceptor.
+// errorThreshold is the number of failures opening a circuit and timeout is the
+// duration of an open circuit.
+func NewBreaker(name string, errorThreshold uint64, timeout time.Duration) *Breaker {
+	return &Breaker{
+		name:             name,
+		errorThreshold:   int(errorThreshold),
+		successThreshold: 1,
+		timeout:          timeout,
+		classifier:       BreakOn(BreakOnTransportErrors, BreakOnServerErrors),
+		monitor:          metrics.NoopStatsdHandler,
+		circuits:         make(map[string]*circuit),
+	}
+}
+
+// WithSuccessThreshold sets the number of consecutive successes closing a
+// half-open circuit. Default is 1.
+func (b *Breaker) WithSuccessThreshold(count uint64) *Breaker {
+	b.successThreshold = int(count)
+	return b
+}
+
+// WithClassifier sets the classifier of failures.
+// Default is BreakOn(BreakOnTransportErrors, BreakOnServerErrors).
+func (b *Breaker) WithClassifier(c BreakerClassifier) *Breaker {
+	b.classifier = c
+	return b
+}
+
+// WithMonitor sends the state transitions and the rejected requests of the
+// circuits as metrics.
+func (b *Breaker) WithMonitor(sh metrics.StatsdHandler) *Breaker {
+	b.monitor = sh
+	return b
+}
+
+// State returns the current state of the circuit of the given target host.
+func (b *Breaker) State(target string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[target]
+	if !ok {
+		return BreakerStateClosed
+	}
+	b.halfOpenIfExpired(target, c)
+	return c.state
+}
+
+// Intercept implements the train.Interceptor interface
+func (b *Breaker) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+	target := req.URL.Host
+
+	release, ok := b.allow(target)
+	if !ok {
+		b.monitor.Count("http.circuit_breaker.rejected", 1, b.tags(target), 1)
+		return nil, &CircuitOpenError{Name: b.name, Target: target}
+	}
+	defer release()
+
+	resp, err := chain.Proceed(req)
+
+	// A request canceled by the caller says nothing about the target health.
+	if errors.Is(req.Context().Err(), context.Canceled) {
+		return resp, err
+	}
+
+	b.record(target, b.classifier(resp, err))
+
+	return resp, err
+}
+
+// allow reports whether a request may be sent to the target. The release
+// function must be called once the request is done, to free its slot of
+// half-open probe.
+func (b *Breaker) allow(target string) (release func(), ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[target]
+	if !ok {
+		return func() {}, true
+	}
+	b.halfOpenIfExpired(target, c)
+
+	switch c.state {
+	case BreakerStateOpen:
+		return nil, false
+	case BreakerStateHalfOpen:
+		// The target just failed: only probe it with the requests which may
+		// close the circuit.
+		if c.probes >= b.successThreshold {
+			return nil, false
+		}
+		c.probes++
+		generation := c.generation
+		return func() { b.release(c, generation) }, true
+	default:
+		return func() {}, true
+	}
+}
+
+// release frees the slot of a half-open probe, unless the circuit state
+// changed since it was sent.
+func (b *Breaker) release(c *circuit, generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c.generation == generation && c.probes > 0 {
+		c.probes--
+	}
+}
+
+func (b *Breaker) record(target string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[target]
+	if !ok {
+		if !failed {
+			// Fast path, nothing to record on a healthy target.
+			return
+		}
+		b.sweep()
+		c = &circuit{}
+		b.circuits[target] = c
+	}
+
+	now := time.Now()
+
+	if !failed {
+		if c.state == BreakerStateHalfOpen {
+			c.successes++
+			if c.successes >= b.successThreshold {
+				b.transition(target, c, BreakerStateClosed)
+			}
+		}
+		return
+	}
+
+	switch c.state {
+	case BreakerStateClosed:
+		if c.failures > 0 && now.After(c.lastError.Add(b.timeout)) {
+			c.failures = 0
+		}
+		c.failures++
+		c.lastError = now
+		if c.failures >= b.errorThreshold {
+			b.transition(target, c, BreakerStateOpen)
+		}
+	case BreakerStateHalfOpen:
+		b.transition(target, c, BreakerStateOpen)
+	}
+}
+
+// halfOpenIfExpired half-opens the circuit if it has been open for longer than
+// the timeout. The lock must be held.
+func (b *Breaker) halfOpenIfExpired(target string, c *circuit) {
+	if c.state == BreakerStateOpen && time.Since(c.openedAt) >= b.timeout {
+		b.transition(target, c, BreakerStateHalfOpen)
+	}
+}
+
+// transition changes the state of the circuit. The lock must be held.
+func (b *Breaker) transition(target string, c *circuit, state BreakerState) {
+	from := c.state
+
+	c.state = state
+	c.failures = 0
+	c.successes = 0
+	c.probes = 0
+	c.generation++
+	switch state {
+	case BreakerStateOpen:
+		c.openedAt = time.Now()
+	case BreakerStateClosed:
+		// A closed circuit without failures is a healthy target.
+		delete(b.circuits, target)
+	}
+
+	tags := b.tags(target)
+	b.monitor.Gauge("http.circuit_breaker.state", float64(state), tags, 1)
+	b.monitor.Count("http.circuit_breaker.transition", 1,
+		append(tags, "from:"+from.String(), "to:"+state.String()), 1)
+}
+
+// sweep forgets the circuits of the idle targets, at most once per timeout:
+// the closed circuits whose failures expired, and the circuits which would
+// half-open for more than the timeout without any probe. The lock must be
+// held.
+func (b *Breaker) sweep() {
+	now := time.Now()
+	if now.Sub(b.lastSweep) < b.timeout {
+		return
+	}
+	b.lastSweep = now
+
+	for target, c := range b.circuits {
+		switch c.state {
+		case BreakerStateClosed:
+			if now.After(c.lastError.Add(b.timeout)) {
+				delete(b.circuits, target)
+			}
+		default:
+			if c.probes == 0 && now.After(c.openedAt.Add(2*b.timeout)) {
+				delete(b.circuits, target)
+				b.monitor.Gauge("http.circuit_breaker.state", float64(BreakerStateClosed), b.tags(target), 1)
+			}
+		}
+	}
+}
+
+func (b *Breaker) tags(target string) []string {
+	return []string{"breaker:" + b.name, "target:" + target}
+}
@@ -0,0 +1,216 @@
+package interceptors
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/breaker"
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker_Intercept(t *testing.T) {
+	var (
+		calls  int32
+		status int32 = http.StatusInternalServerError
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer ts.Close()
+
+	b := NewBreaker("test", 2, 50*time.Millisecond)
+	client := http.Client{Transport: train.Transport(b)}
+
+	do := func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	target := ts.Listener.Addr().String()
+
+	// Two failures open the circuit.
+	for i := 0; i < 2; i++ {
+		resp, err := do()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	}
+	assert.Equal(t, BreakerStateOpen, b.State(target))
+
+	// The open circuit fails fast.
+	_, err := do()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.ErrorIs(t, err, breaker.ErrBreakerOpen)
+	var coErr *CircuitOpenError
+	require.True(t, errors.As(err, &coErr))
+	assert.Equal(t, target, coErr.Target)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+
+	// After the timeout the circuit half-opens and a success closes it.
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, BreakerStateHalfOpen, b.State(target))
+
+	atomic.StoreInt32(&status, http.StatusOK)
+	resp, err := do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, BreakerStateClosed, b.State(target))
+}
+
+func TestBreaker_Intercept_half_open_probes(t *testing.T) {
+	var (
+		calls   int32
+		healthy int32
+	)
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		<-unblock
+	}))
+	defer ts.Close()
+	target := ts.Listener.Addr().String()
+
+	b := NewBreaker("test", 1, 50*time.Millisecond).WithSuccessThreshold(2)
+	client := http.Client{Transport: train.Transport(b)}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, BreakerStateOpen, b.State(target))
+
+	time.Sleep(60 * time.Millisecond)
+	require.Equal(t, BreakerStateHalfOpen, b.State(target))
+	atomic.StoreInt32(&healthy, 1)
+
+	// Only successThreshold probes reach the target, the other concurrent
+	// requests fail fast.
+	const requests = 10
+	var (
+		wg       sync.WaitGroup
+		rejected int32
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(ts.URL)
+			if err != nil {
+				assert.ErrorIs(t, err, ErrCircuitOpen)
+				atomic.AddInt32(&rejected, 1)
+				return
+			}
+			_ = resp.Body.Close()
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&rejected) == requests-2 && atomic.LoadInt32(&calls) == 3
+	}, time.Second, time.Millisecond)
+
+	close(unblock)
+	wg.Wait()
+	assert.Equal(t, BreakerStateClosed, b.State(target))
+}
+
+func TestBreaker_Intercept_with_classifier(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	b := NewBreaker("test", 1, time.Minute).WithClassifier(BreakOnTimeouts)
+	client := http.Client{Transport: train.Transport(b)}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, BreakerStateClosed, b.State(ts.Listener.Addr().String()))
+}
+
+func TestBreaker_Intercept_local_errors(t *testing.T) {
+	local := train.InterceptorFunc(func(chain train.Chain) (*http.Response, error) {
+		return nil, &RateLimitedError{Key: chain.Request().URL.Host, Wait: time.Second}
+	})
+
+	b := NewBreaker("test", 1, time.Minute)
+	client := http.Client{Transport: train.Transport(b, local)}
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Get("http://example.com")
+		assert.ErrorIs(t, err, ErrRateLimited)
+	}
+	assert.Equal(t, BreakerStateClosed, b.State("example.com"))
+}
+
+func TestBreaker_forgets_idle_targets(t *testing.T) {
+	fail := train.InterceptorFunc(func(chain train.Chain) (*http.Response, error) {
+		return nil, io.ErrUnexpectedEOF
+	})
+
+	b := NewBreaker("test", 2, 10*time.Millisecond)
+	client := http.Client{Transport: train.Transport(b, fail)}
+
+	// A failure for each target.
+	_, _ = client.Get("http://a.example.com")
+	_, _ = client.Get("http://b.example.com")
+	_, _ = client.Get("http://b.example.com")
+	assert.Equal(t, BreakerStateOpen, b.State("b.example.com"))
+	assert.Len(t, b.circuits, 2)
+
+	time.Sleep(25 * time.Millisecond)
+	_, _ = client.Get("http://c.example.com")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	assert.Len(t, b.circuits, 1)
+	assert.Contains(t, b.circuits, "c.example.com")
+}
+
+func TestBreakerClassifiers(t *testing.T) {
+	ok := &http.Response{StatusCode: http.StatusOK}
+	unavailable := &http.Response{StatusCode: http.StatusServiceUnavailable}
+	timeout := &http.Response{StatusCode: http.StatusGatewayTimeout}
+
+	assert.False(t, BreakOnErrors(ok, nil))
+	assert.True(t, BreakOnErrors(nil, errors.New("connection refused")))
+
+	assert.False(t, BreakOnTransportErrors(ok, nil))
+	assert.True(t, BreakOnTransportErrors(nil, &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}))
+	assert.True(t, BreakOnTransportErrors(nil, &net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true}))
+	assert.True(t, BreakOnTransportErrors(nil, io.ErrUnexpectedEOF))
+	assert.False(t, BreakOnTransportErrors(nil, &RateLimitedError{Key: "example.com"}))
+	assert.False(t, BreakOnTransportErrors(nil, &ConsentMissingError{Host: "example.com"}))
+	assert.False(t, BreakOnTransportErrors(nil, errors.New("sign the request: missing credentials")))
+
+	assert.False(t, BreakOnServerErrors(ok, nil))
+	assert.True(t, BreakOnServerErrors(unavailable, nil))
+
+	assert.False(t, BreakOnTimeouts(unavailable, nil))
+	assert.True(t, BreakOnTimeouts(timeout, nil))
+
+	assert.True(t, BreakOn(BreakOnTimeouts, BreakOnServerErrors)(unavailable, nil))
+	assert.False(t, BreakOn(BreakOnTimeouts, BreakOnErrors)(unavailable, nil))
+}
@@ -0,0 +1,467 @@
+package interceptors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f2prateek/train"
+
+	"github.com/monorepo/common/monitoring/metrics"
+)
+
+const (
+	// defaultCacheMaxBodySize is the default maximum size of a cached
+	// response body.
+	defaultCacheMaxBodySize = 1 << 20
+	// defaultCacheRevalidationTimeout is the default timeout of the
+	// stale-while-revalidate background requests.
+	defaultCacheRevalidationTimeout = 10 * time.Second
+)
+
+// cacheableStatusCodes are the status codes of the responses which may be
+// cached, see RFC 7231 section 6.1.
+var cacheableStatusCodes = map[int]struct{}{
+	http.StatusOK:                   {},
+	http.StatusNonAuthoritativeInfo: {},
+	http.StatusNoContent:            {},
+	http.StatusMultipleChoices:      {},
+	http.StatusMovedPermanently:     {},
+	http.StatusNotFound:             {},
+	http.StatusMethodNotAllowed:     {},
+	http.StatusGone:                 {},
+	http.StatusRequestURITooLong:    {},
+	http.StatusNotImplemented:       {},
+}
+
+// Cache is a HTTP client middleware caching the GET responses, following the
+// HTTP caching rules of a shared cache (RFC 7234):
+//   - responses are cached when their Cache-Control or Expires header gives
+//     them a freshness lifetime, or when they can be revalidated (ETag or
+//     Last-Modified header). no-store and private responses are not cached,
+//     nor are responses to requests with an Authorization header unless
+//     explicitly public. The request which was actually sent is checked, with
+//     the headers set by the next interceptors, e.g. Authorization.
+//   - fresh responses are served from the cache.
+//   - stale responses are revalidated with a conditional request
+//     (If-None-Match, If-Modified-Since), or served while being revalidated in
+//     background within their stale-while-revalidate delay.
+//   - the Vary response header is honoured.
+//
+// Requests with their own conditional or Range headers are not cached.
+type Cache struct {
+	store               CacheStore
+	monitor             metrics.StatsdHandler
+	maxBodySize         int
+	revalidationTimeout time.Duration
+	now                 func() time.Time
+
+	mu           sync.Mutex
+	revalidating map[string]struct{}
+}
+
+// NewCache instantiates a new Cache interceptor, storing the responses in the
+// given store. See NewLRUCacheStore for an in-memory store.
+func NewCache(store CacheStore) *Cache {
+	return &Cache{
+		store:               store,
+		monitor:             metrics.NoopStatsdHandler,
+		maxBodySize:         defaultCacheMaxBodySize,
+		revalidationTimeout: defaultCacheRevalidationTimeout,
+		now:                 time.Now,
+		revalidating:        make(map[string]struct{}),
+	}
+}
+
+// WithMonitor sends the cache hits, misses and revalidations as metrics.
+func (c *Cache) WithMonitor(sh metrics.StatsdHandler) *Cache {
+	c.monitor = sh
+	return c
+}
+
+// WithMaxBodySize sets the maximum size in bytes of a cached response body.
+// Larger responses are not cached. Default is 1MiB.
+func (c *Cache) WithMaxBodySize(size int) *Cache {
+	c.maxBodySize = size
+	return c
+}
+
+// WithRevalidationTimeout sets the timeout of the background revalidation
+// requests, sent for the responses served stale. Default is 10s.
+func (c *Cache) WithRevalidationTimeout(timeout time.Duration) *Cache {
+	c.revalidationTimeout = timeout
+	return c
+}
+
+// Intercept implements the train.Interceptor interface
+func (c *Cache) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+
+	reqCC := parseCacheControl(req.Header)
+	if !isCacheableRequest(req, reqCC) {
+		return chain.Proceed(req)
+	}
+
+	key := cacheKey(req)
+	entry, ok := c.store.Get(key)
+	if !ok || !entry.matches(req) {
+		c.count("http.cache.miss", req)
+		return c.fetch(chain, req, key)
+	}
+
+	age := c.age(entry)
+	cc := parseCacheControl(entry.Header)
+	lifetime := freshnessLifetime(entry.Header, cc)
+
+	if !reqCC.has("no-cache") {
+		if age < lifetime {
+			c.count("http.cache.hit", req, "stale:false")
+			return entry.response(req, age), nil
+		}
+
+		if swr, ok := cc.seconds("stale-while-revalidate"); ok && age < lifetime+swr {
+			c.count("http.cache.hit", req, "stale:true")
+			c.revalidateAsync(chain, req, key, entry)
+			return entry.response(req, age), nil
+		}
+	}
+
+	if !entry.hasValidators() {
+		c.count("http.cache.miss", req)
+		return c.fetch(chain, req, key)
+	}
+
+	return c.revalidate(chain, req, key, entry)
+}
+
+// fetch sends the request and caches its response if possible.
+func (c *Cache) fetch(chain train.Chain, req *http.Request, key string) (*http.Response, error) {
+	resp, err := chain.Proceed(req)
+	if err != nil {
+		return resp, err
+	}
+	return c.storeResponse(req, key, resp), nil
+}
+
+// revalidate sends a conditional request for the stale entry, and serves it
+// if it is not modified.
+func (c *Cache) revalidate(chain train.Chain, req *http.Request, key string, entry *CacheEntry) (*http.Response, error) {
+	creq := req.Clone(req.Context())
+	if etag := entry.Header.Get("ETag"); etag != "" {
+		creq.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+		creq.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := chain.Proceed(creq)
+	if err != nil {
+		c.count("http.cache.revalidate", req, "result:error")
+		return resp, err
+	}
+
+	if resp.StatusCode != http.StatusNotModified {
+		c.count("http.cache.revalidate", req, "result:modified")
+		resp = c.storeResponse(req, key, resp)
+		resp.Request = req
+		return resp, nil
+	}
+
+	discard(resp)
+	c.count("http.cache.revalidate", req, "result:not_modified")
+
+	updated := entry.revalidated(resp.Header, c.now())
+	c.store.Set(key, updated)
+	return updated.response(req, c.age(updated)), nil
+}
+
+// revalidateAsync revalidates the entry in background, unless it is already
+// being revalidated.
+func (c *Cache) revalidateAsync(chain train.Chain, req *http.Request, key string, entry *CacheEntry) {
+	c.mu.Lock()
+	if _, ok := c.revalidating[key]; ok {
+		c.mu.Unlock()
+		return
+	}
+	c.revalidating[key] = struct{}{}
+	c.mu.Unlock()
+
+	// The revalidation outlives the request.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), c.revalidationTimeout)
+	req = req.Clone(ctx)
+
+	go func() {
+		defer func() {
+			cancel()
+			c.mu.Lock()
+			delete(c.revalidating, key)
+			c.mu.Unlock()
+		}()
+
+		resp, err := c.revalidate(chain, req, key, entry)
+		if err != nil {
+			return
+		}
+		// Reading the body up to EOF stores a modified response.
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+}
+
+// storeResponse caches the response once its body is read, if it is
+// cacheable.
+func (c *Cache) storeResponse(req *http.Request, key string, resp *http.Response) *http.Response {
+	// The next interceptors may have sent a copy of the request, with more
+	// headers, e.g. the Retry and Authorization interceptors.
+	sent := resp.Request
+	if sent == nil {
+		sent = req
+	}
+
+	cc := parseCacheControl(resp.Header)
+	if !isCacheableResponse(req, sent, resp, cc) {
+		return resp
+	}
+
+	entry := &CacheEntry{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		VaryHeader: varyHeader(req, resp.Header),
+		StoredAt:   c.now(),
+	}
+
+	if resp.ContentLength == 0 {
+		c.store.Set(key, entry)
+		return resp
+	}
+	if resp.ContentLength > int64(c.maxBodySize) {
+		return resp
+	}
+
+	resp.Body = &cacheRecorderBody{
+		ReadCloser: resp.Body,
+		maxSize:    c.maxBodySize,
+		store: func(body []byte) {
+			entry.Body = body
+			c.store.Set(key, entry)
+		},
+	}
+	return resp
+}
+
+func (c *Cache) age(entry *CacheEntry) time.Duration {
+	age := c.now().Sub(entry.StoredAt)
+	if s, err := strconv.Atoi(entry.Header.Get("Age")); err == nil && s > 0 {
+		age += time.Duration(s) * time.Second
+	}
+	return age
+}
+
+func (c *Cache) count(name string, req *http.Request, tags ...string) {
+	c.monitor.Count(name, 1, append(tags, "target:"+req.URL.Host), 1)
+}
+
+// matches tells if the entry was stored for a request with the same values of
+// the Vary header fields.
+func (e *CacheEntry) matches(req *http.Request) bool {
+	for k := range e.VaryHeader {
+		if req.Header.Get(k) != e.VaryHeader.Get(k) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *CacheEntry) hasValidators() bool {
+	return e.Header.Get("ETag") != "" || e.Header.Get("Last-Modified") != ""
+}
+
+// revalidated returns a copy of the entry, updated with the header of a 304
+// response.
+func (e *CacheEntry) revalidated(header http.Header, now time.Time) *CacheEntry {
+	updated := *e
+	updated.Header = e.Header.Clone()
+	updated.Header.Del("Age")
+	for k, v := range header {
+		if k == "Content-Length" {
+			continue
+		}
+		updated.Header[k] = v
+	}
+	updated.StoredAt = now
+	return &updated
+}
+
+// response returns a new response with the entry content.
+func (e *CacheEntry) response(req *http.Request, age time.Duration) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(age.Seconds())))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func cacheKey(req *http.Request) string {
+	return req.Method + " " + req.URL.String()
+}
+
+func isCacheableRequest(req *http.Request, cc cacheControl) bool {
+	if req.Method != http.MethodGet || cc.has("no-store") {
+		return false
+	}
+	for _, k := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "Range"} {
+		if req.Header.Get(k) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// isCacheableResponse tells if the response may be cached for the request
+// received by the interceptor, given the request which was actually sent.
+func isCacheableResponse(req, sent *http.Request, resp *http.Response, cc cacheControl) bool {
+	if _, ok := cacheableStatusCodes[resp.StatusCode]; !ok {
+		return false
+	}
+	if cc.has("no-store") || cc.has("private") {
+		return false
+	}
+	if resp.Header.Get("Vary") == "*" {
+		return false
+	}
+	// See RFC 7234 section 3.2.
+	if sent.Header.Get("Authorization") != "" && !cc.has("public") && !cc.has("s-maxage") {
+		return false
+	}
+	// The entries are matched with the received requests: a Vary header field
+	// set by the next interceptors can't be matched before sending them.
+	for k := range varyHeader(sent, resp.Header) {
+		if sent.Header.Get(k) != req.Header.Get(k) {
+			return false
+		}
+	}
+
+	if freshnessLifetime(resp.Header, cc) > 0 {
+		return true
+	}
+	return resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// freshnessLifetime returns the freshness lifetime of a response, see RFC 7234
+// section 4.2.1. No heuristic freshness is used.
+func freshnessLifetime(header http.Header, cc cacheControl) time.Duration {
+	if cc.has("no-cache") {
+		return 0
+	}
+	if d, ok := cc.seconds("s-maxage"); ok {
+		return d
+	}
+	if d, ok := cc.seconds("max-age"); ok {
+		return d
+	}
+
+	expires, err := http.ParseTime(header.Get("Expires"))
+	if err != nil {
+		return 0
+	}
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return 0
+	}
+	return expires.Sub(date)
+}
+
+// varyHeader returns the request header fields listed in the response Vary
+// header.
+func varyHeader(req *http.Request, header http.Header) http.Header {
+	var vary http.Header
+	for _, v := range header.Values("Vary") {
+		for _, k := range strings.Split(v, ",") {
+			k = strings.TrimSpace(k)
+			if k == "" {
+				continue
+			}
+			if vary == nil {
+				vary = make(http.Header)
+			}
+			vary.Set(k, req.Header.Get(k))
+		}
+	}
+	return vary
+}
+
+// cacheControl holds the directives of Cache-Control header, by lower-cased
+// name.
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, v := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	s, err := strconv.Atoi(cc[name])
+	if err != nil || s < 0 {
+		return 0, false
+	}
+	return time.Duration(s) * time.Second, true
+}
+
+// cacheRecorderBody buffers a response body while it is read, and stores it
+// once fully read, if not larger than maxSize.
+type cacheRecorderBody struct {
+	io.ReadCloser
+	maxSize int
+	store   func(body []byte)
+
+	buf      bytes.Buffer
+	overflow bool
+	stored   bool
+}
+
+// Read implements io.Reader.
+func (b *cacheRecorderBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.overflow {
+		if b.buf.Len()+n > b.maxSize {
+			b.overflow = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !b.overflow && !b.stored {
+		b.stored = true
+		b.store(b.buf.Bytes())
+	}
+	return n, err
+}
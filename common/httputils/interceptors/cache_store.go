@@ -0,0 +1,107 @@
+package interceptors
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a response stored by the Cache interceptor.
+//
+// Entries are shared between requests and must not be modified once stored.
+type CacheEntry struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// VaryHeader holds the request header fields listed in the Vary response
+	// header, used to select the entry.
+	VaryHeader http.Header
+	// StoredAt is the time the response was received.
+	StoredAt time.Time
+}
+
+// CacheStore stores the responses of the Cache interceptor.
+//
+// Implementations must be safe for concurrent use.
+type CacheStore interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// LRUCacheStore is an in-memory CacheStore, which evicts the least recently
+// used entries beyond its size.
+type LRUCacheStore struct {
+	size int
+
+	mu      sync.Mutex
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewLRUCacheStore instantiates a new LRUCacheStore holding at most size
+// entries.
+func NewLRUCacheStore(size int) *LRUCacheStore {
+	return &LRUCacheStore{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get implements CacheStore.
+func (s *LRUCacheStore) Get(key string) (*CacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.ll.MoveToFront(e)
+	return e.Value.(*lruItem).entry, true
+}
+
+// Set implements CacheStore.
+func (s *LRUCacheStore) Set(key string, entry *CacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.Value.(*lruItem).entry = entry
+		s.ll.MoveToFront(e)
+		return
+	}
+
+	s.entries[key] = s.ll.PushFront(&lruItem{key: key, entry: entry})
+	for s.ll.Len() > s.size {
+		oldest := s.ll.Back()
+		s.ll.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruItem).key)
+	}
+}
+
+// Delete implements CacheStore.
+func (s *LRUCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		s.ll.Remove(e)
+		delete(s.entries, key)
+	}
+}
+
+// Len returns the number of stored entries.
+func (s *LRUCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ll.Len()
+}
@@ -0,0 +1,415 @@
+package interceptors
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/monitoring/metrics"
+)
+
+// countStatsdHandler records the counts sent by name and tags.
+type countStatsdHandler struct {
+	metrics.StatsdHandler
+
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func newCountStatsdHandler() *countStatsdHandler {
+	return &countStatsdHandler{
+		StatsdHandler: metrics.NoopStatsdHandler,
+		counts:        make(map[string]int64),
+	}
+}
+
+func (h *countStatsdHandler) Count(name string, value int64, tags []string, rate float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[name+" "+strings.Join(tags[:len(tags)-1], ",")] += value
+}
+
+func (h *countStatsdHandler) get(name string) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.counts[name]
+}
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestCache(t *testing.T) (*Cache, *fakeClock, *countStatsdHandler) {
+	t.Helper()
+	clock := &fakeClock{now: time.Now()}
+	sh := newCountStatsdHandler()
+	c := NewCache(NewLRUCacheStore(10)).WithMonitor(sh)
+	c.now = clock.Now
+	return c, clock, sh
+}
+
+func getBody(t *testing.T, client *http.Client, url string, header ...string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(b)
+}
+
+func TestCache_max_age(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, strings.Repeat("a", int(n)))
+	}))
+	defer ts.Close()
+
+	c, clock, sh := newTestCache(t)
+	client := &http.Client{Transport: train.Transport(c)}
+
+	_, body := getBody(t, client, ts.URL)
+	assert.Equal(t, "a", body)
+
+	clock.Add(30 * time.Second)
+	resp, body := getBody(t, client, ts.URL)
+	assert.Equal(t, "a", body)
+	assert.Equal(t, "30", resp.Header.Get("Age"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	clock.Add(31 * time.Second)
+	_, body = getBody(t, client, ts.URL)
+	assert.Equal(t, "aa", body)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	assert.Equal(t, int64(2), sh.get("http.cache.miss "))
+	assert.Equal(t, int64(1), sh.get("http.cache.hit stale:false"))
+}
+
+func TestCache_not_cacheable(t *testing.T) {
+	tests := map[string]struct {
+		reqHeader     []string
+		cacheControl  string
+		status        int
+		wantCallCount int32
+	}{
+		"no cache control": {
+			wantCallCount: 2,
+		},
+		"no-store response": {
+			cacheControl:  "no-store, max-age=60",
+			wantCallCount: 2,
+		},
+		"private response": {
+			cacheControl:  "private, max-age=60",
+			wantCallCount: 2,
+		},
+		"no-store request": {
+			reqHeader:     []string{"Cache-Control", "no-store"},
+			cacheControl:  "max-age=60",
+			wantCallCount: 2,
+		},
+		"authorization": {
+			reqHeader:     []string{"Authorization", "Bearer token"},
+			cacheControl:  "max-age=60",
+			wantCallCount: 2,
+		},
+		"authorization with public response": {
+			reqHeader:     []string{"Authorization", "Bearer token"},
+			cacheControl:  "public, max-age=60",
+			wantCallCount: 1,
+		},
+		"server error": {
+			cacheControl:  "max-age=60",
+			status:        http.StatusInternalServerError,
+			wantCallCount: 2,
+		},
+		"not found": {
+			cacheControl:  "max-age=60",
+			status:        http.StatusNotFound,
+			wantCallCount: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				if tt.cacheControl != "" {
+					w.Header().Set("Cache-Control", tt.cacheControl)
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = io.WriteString(w, "body")
+			}))
+			defer ts.Close()
+
+			c, _, _ := newTestCache(t)
+			client := &http.Client{Transport: train.Transport(c)}
+
+			getBody(t, client, ts.URL, tt.reqHeader...)
+			getBody(t, client, ts.URL, tt.reqHeader...)
+			assert.Equal(t, tt.wantCallCount, atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestCache_revalidation(t *testing.T) {
+	var calls, notModified int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = io.WriteString(w, "v1")
+	}))
+	defer ts.Close()
+
+	c, _, sh := newTestCache(t)
+	client := &http.Client{Transport: train.Transport(c)}
+
+	_, body := getBody(t, client, ts.URL)
+	assert.Equal(t, "v1", body)
+
+	resp, body := getBody(t, client, ts.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "v1", body)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&notModified))
+	assert.Equal(t, int64(1), sh.get("http.cache.revalidate result:not_modified"))
+}
+
+func TestCache_revalidation_last_modified(t *testing.T) {
+	lastModified := time.Now().UTC().Format(http.TimeFormat)
+
+	var version int32 = 1
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10")
+		if atomic.LoadInt32(&version) == 1 {
+			w.Header().Set("Last-Modified", lastModified)
+			if req.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		_, _ = io.WriteString(w, strings.Repeat("v", int(atomic.LoadInt32(&version))))
+	}))
+	defer ts.Close()
+
+	c, clock, sh := newTestCache(t)
+	client := &http.Client{Transport: train.Transport(c)}
+
+	_, body := getBody(t, client, ts.URL)
+	assert.Equal(t, "v", body)
+
+	clock.Add(11 * time.Second)
+	resp, body := getBody(t, client, ts.URL)
+	assert.Equal(t, "v", body)
+	assert.Equal(t, "0", resp.Header.Get("Age"))
+
+	atomic.StoreInt32(&version, 2)
+	clock.Add(11 * time.Second)
+	_, body = getBody(t, client, ts.URL)
+	assert.Equal(t, "vv", body)
+
+	assert.Equal(t, int64(1), sh.get("http.cache.revalidate result:not_modified"))
+	assert.Equal(t, int64(1), sh.get("http.cache.revalidate result:modified"))
+}
+
+func TestCache_stale_while_revalidate(t *testing.T) {
+	var calls int32
+	revalidated := make(chan struct{}, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=10, stale-while-revalidate=20")
+		_, _ = io.WriteString(w, strings.Repeat("a", int(n)))
+		if n > 1 {
+			revalidated <- struct{}{}
+		}
+	}))
+	defer ts.Close()
+
+	store := NewLRUCacheStore(10)
+	c, clock, sh := newTestCache(t)
+	c.store = store
+	client := &http.Client{Transport: train.Transport(c)}
+
+	getBody(t, client, ts.URL)
+
+	// Stale, served while revalidated.
+	clock.Add(15 * time.Second)
+	_, body := getBody(t, client, ts.URL)
+	assert.Equal(t, "a", body)
+
+	select {
+	case <-revalidated:
+	case <-time.After(time.Second):
+		t.Fatal("stale response not revalidated")
+	}
+	assert.Eventually(t, func() bool {
+		e, ok := store.Get(cacheKey(httptest.NewRequest(http.MethodGet, ts.URL, nil)))
+		return ok && string(e.Body) == "aa"
+	}, time.Second, 10*time.Millisecond)
+
+	_, body = getBody(t, client, ts.URL)
+	assert.Equal(t, "aa", body)
+	assert.Equal(t, int64(1), sh.get("http.cache.hit stale:true"))
+
+	// Beyond stale-while-revalidate.
+	clock.Add(31 * time.Second)
+	_, body = getBody(t, client, ts.URL)
+	assert.Equal(t, "aaa", body)
+}
+
+func TestCache_vary(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = io.WriteString(w, req.Header.Get("Accept-Language"))
+	}))
+	defer ts.Close()
+
+	c, _, _ := newTestCache(t)
+	client := &http.Client{Transport: train.Transport(c)}
+
+	_, body := getBody(t, client, ts.URL, "Accept-Language", "fr")
+	assert.Equal(t, "fr", body)
+	_, body = getBody(t, client, ts.URL, "Accept-Language", "fr")
+	assert.Equal(t, "fr", body)
+	_, body = getBody(t, client, ts.URL, "Accept-Language", "en")
+	assert.Equal(t, "en", body)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCache_headers_set_by_next_interceptors(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "X-Brand")
+		_, _ = io.WriteString(w, req.Header.Get("Authorization")+req.Header.Get("X-Brand"))
+	}))
+	defer ts.Close()
+
+	// The header is set on a copy of the request, as done by the Retry
+	// interceptor before the Authorization or BrandForwarding ones.
+	setHeader := func(k, v string) train.Interceptor {
+		return train.InterceptorFunc(func(chain train.Chain) (*http.Response, error) {
+			req := chain.Request().Clone(chain.Request().Context())
+			req.Header.Set(k, v)
+			return chain.Proceed(req)
+		})
+	}
+
+	t.Run("authorization", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		c, _, _ := newTestCache(t)
+		token := "a"
+		client := &http.Client{Transport: train.Transport(c, train.InterceptorFunc(func(chain train.Chain) (*http.Response, error) {
+			return setHeader("Authorization", token).Intercept(chain)
+		}))}
+
+		_, body := getBody(t, client, ts.URL)
+		assert.Equal(t, "a", body)
+		token = "b"
+		_, body = getBody(t, client, ts.URL)
+		assert.Equal(t, "b", body)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("vary", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		c, _, _ := newTestCache(t)
+		client := &http.Client{Transport: train.Transport(c, setHeader("X-Brand", "brand"))}
+
+		for i := 0; i < 2; i++ {
+			_, body := getBody(t, client, ts.URL)
+			assert.Equal(t, "brand", body)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestCache_max_body_size(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, strings.Repeat("a", 100))
+	}))
+	defer ts.Close()
+
+	c, _, _ := newTestCache(t)
+	client := &http.Client{Transport: train.Transport(c.WithMaxBodySize(50))}
+
+	_, body := getBody(t, client, ts.URL)
+	assert.Len(t, body, 100)
+	_, body = getBody(t, client, ts.URL)
+	assert.Len(t, body, 100)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	s := NewLRUCacheStore(2)
+
+	s.Set("a", &CacheEntry{Body: []byte("a")})
+	s.Set("b", &CacheEntry{Body: []byte("b")})
+	_, ok := s.Get("a")
+	require.True(t, ok)
+
+	// b is the least recently used.
+	s.Set("c", &CacheEntry{Body: []byte("c")})
+	assert.Equal(t, 2, s.Len())
+	_, ok = s.Get("b")
+	assert.False(t, ok)
+
+	e, ok := s.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, "a", string(e.Body))
+
+	s.Delete("a")
+	_, ok = s.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, s.Len())
+}
@@ -0,0 +1,82 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync/atomic"
+)
+
+// attemptTracer builds the httptrace.ClientTrace of a tracing interceptor,
+// creating the DNS, connect and TLS child spans of a request.
+//
+// The hooks of a ClientTrace share their spans, so a ClientTrace must not be
+// used by concurrent attempts of a request. The Hedging interceptor then
+// installs a ClientTrace of its own for each attempt, see traceAttempt, and
+// the ClientTrace installed by the tracing interceptor is disabled.
+type attemptTracer struct {
+	newTrace func(ctx context.Context) *httptrace.ClientTrace
+	// delegated is set once the attempts have their own ClientTrace.
+	delegated atomic.Bool
+	// parent is the attemptTracer of an outer tracing interceptor, if any.
+	parent *attemptTracer
+}
+
+// withAttemptTracer returns a copy of ctx with the ClientTrace built by
+// newTrace, which can be delegated to the concurrent attempts of the request.
+func withAttemptTracer(ctx context.Context, newTrace func(ctx context.Context) *httptrace.ClientTrace) context.Context {
+	t := &attemptTracer{newTrace: newTrace}
+	t.parent, _ = ctx.Value(attemptTracerCtxKey).(*attemptTracer)
+
+	ctx = context.WithValue(ctx, attemptTracerCtxKey, t)
+	return httptrace.WithClientTrace(ctx, t.guard(newTrace(ctx)))
+}
+
+// traceAttempt returns a copy of the context of a concurrent attempt of a
+// request, with ClientTraces of its own for the tracing interceptors of the
+// request.
+func traceAttempt(ctx context.Context) context.Context {
+	t, _ := ctx.Value(attemptTracerCtxKey).(*attemptTracer)
+	for ; t != nil; t = t.parent {
+		t.delegated.Store(true)
+		ctx = httptrace.WithClientTrace(ctx, t.newTrace(ctx))
+	}
+	return ctx
+}
+
+// guard disables the hooks of the given ClientTrace once delegated to the
+// attempts.
+func (t *attemptTracer) guard(trace *httptrace.ClientTrace) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			if !t.delegated.Load() {
+				trace.DNSStart(info)
+			}
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			if !t.delegated.Load() {
+				trace.DNSDone(info)
+			}
+		},
+		ConnectStart: func(network, addr string) {
+			if !t.delegated.Load() {
+				trace.ConnectStart(network, addr)
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if !t.delegated.Load() {
+				trace.ConnectDone(network, addr, err)
+			}
+		},
+		TLSHandshakeStart: func() {
+			if !t.delegated.Load() {
+				trace.TLSHandshakeStart()
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if !t.delegated.Load() {
+				trace.TLSHandshakeDone(state, err)
+			}
+		},
+	}
+}
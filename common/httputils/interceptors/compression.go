@@ -0,0 +1,356 @@
+package interceptors
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/f2prateek/train"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/monorepo/common/monitoring/metrics"
+)
+
+// Codec compresses and decompresses HTTP bodies for a content coding.
+//
+// The gzip, deflate, zstd and br content codings are provided. Other ones can
+// be supported by implementing this interface around their libraries.
+type Codec interface {
+	// Encoding returns the content coding name, as used in the
+	// Content-Encoding and Accept-Encoding headers.
+	Encoding() string
+	// NewWriter returns a writer compressing to w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader decompressing r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// All provided codecs.
+var (
+	// GzipCodec is the gzip content coding (RFC 1952).
+	GzipCodec Codec = gzipCodec{}
+	// DeflateCodec is the deflate content coding (RFC 1950). Raw deflate
+	// streams, sent by some servers, are also decompressed.
+	DeflateCodec Codec = deflateCodec{}
+	// ZstdCodec is the zstd content coding (RFC 8878).
+	ZstdCodec Codec = zstdCodec{}
+	// BrotliCodec is the br content coding (RFC 7932).
+	BrotliCodec Codec = brotliCodec{}
+)
+
+type gzipCodec struct{}
+
+func (gzipCodec) Encoding() string { return "gzip" }
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type deflateCodec struct{}
+
+func (deflateCodec) Encoding() string { return "deflate" }
+
+func (deflateCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
+
+func (deflateCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	// A zlib header uses the deflate method, and is a multiple of 31.
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) Encoding() string { return "zstd" }
+
+func (zstdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+}
+
+func (zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	// A single goroutine decodes each body, the bodies being read
+	// concurrently.
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+type brotliCodec struct{}
+
+func (brotliCodec) Encoding() string { return "br" }
+
+func (brotliCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return brotli.NewWriter(w), nil
+}
+
+func (brotliCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
+
+// Compression is a HTTP client middleware compressing the request bodies and
+// decompressing the response bodies.
+//
+// The Accept-Encoding header of the requests is set with the codecs
+// encodings, and the responses with one of them are decompressed
+// transparently: their Content-Encoding and Content-Length headers are
+// removed. Responses with several content codings are decoded in the reverse
+// order they were applied, and fail if one of them is unknown. Requests with an Accept-Encoding header already set are left
+// untouched, as their caller wants the compressed responses.
+//
+// When enabled, the request bodies larger than a threshold are compressed with
+// the first codec, and sent with the Content-Encoding header. They are
+// compressed in memory.
+type Compression struct {
+	codecs           []Codec
+	acceptEncoding   string
+	requestThreshold int64
+	monitor          metrics.StatsdHandler
+}
+
+// NewCompression instantiates a new Compression interceptor accepting the
+// given codecs, in order of preference. GzipCodec, DeflateCodec, ZstdCodec and
+// BrotliCodec are used when none is given, the request bodies being then
+// compressed with gzip.
+func NewCompression(codecs ...Codec) *Compression {
+	if len(codecs) == 0 {
+		codecs = []Codec{GzipCodec, DeflateCodec, ZstdCodec, BrotliCodec}
+	}
+	encodings := make([]string, 0, len(codecs))
+	for _, c := range codecs {
+		encodings = append(encodings, c.Encoding())
+	}
+	return &Compression{
+		codecs:         codecs,
+		acceptEncoding: strings.Join(encodings, ", "),
+		monitor:        metrics.NoopStatsdHandler,
+	}
+}
+
+// WithRequestCompression compresses the request bodies of at least threshold
+// bytes with the first codec. Bodies of unknown size are read in memory to be
+// measured.
+func (c *Compression) WithRequestCompression(threshold int64) *Compression {
+	if threshold < 1 {
+		threshold = 1
+	}
+	c.requestThreshold = threshold
+	return c
+}
+
+// WithMonitor sends the compressed and uncompressed bytes counts as metrics.
+func (c *Compression) WithMonitor(sh metrics.StatsdHandler) *Compression {
+	c.monitor = sh
+	return c
+}
+
+// Intercept implements the train.Interceptor interface
+func (c *Compression) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+
+	if c.requestThreshold > 0 {
+		if err := c.compressRequest(req); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Header.Get("Accept-Encoding") != "" {
+		return chain.Proceed(req)
+	}
+	req.Header.Set("Accept-Encoding", c.acceptEncoding)
+
+	resp, err := chain.Proceed(req)
+	if err != nil {
+		return resp, err
+	}
+
+	encodings := contentCodings(resp.Header.Values("Content-Encoding"))
+	if len(encodings) == 0 || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, nil
+	}
+
+	// The codings are listed in the order they were applied, and are
+	// decoded in the reverse order.
+	codecs := make([]Codec, len(encodings))
+	for i, encoding := range encodings {
+		if codecs[i] = c.codec(encoding); codecs[i] == nil {
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("decompress response: unsupported content coding %q", encoding)
+		}
+	}
+
+	compressed := &countingReader{Reader: resp.Body}
+	body := &decompressedBody{compressed: []io.Closer{resp.Body}}
+	var reader io.ReadCloser = io.NopCloser(compressed)
+	for i := len(codecs) - 1; i >= 0; i-- {
+		r, err := codecs[i].NewReader(reader)
+		if err != nil {
+			_ = body.closeCompressed()
+			return nil, fmt.Errorf("decompress %s response: %w", codecs[i].Encoding(), err)
+		}
+		if i > 0 {
+			body.compressed = append([]io.Closer{r}, body.compressed...)
+		}
+		reader = r
+	}
+
+	tags := c.tags("response", strings.Join(encodings, "+"), req)
+	body.sizeRecorderBody = sizeRecorderBody{
+		ReadCloser: reader,
+		record: func(size int64) {
+			c.monitor.Count("http.compression.compressed_bytes", compressed.n, tags, 1)
+			c.monitor.Count("http.compression.uncompressed_bytes", size, tags, 1)
+		},
+	}
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
+
+// compressRequest compresses the request body with the first codec, if large
+// enough and not already encoded.
+func (c *Compression) compressRequest(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" ||
+		(req.ContentLength > 0 && req.ContentLength < c.requestThreshold) {
+		return nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return fmt.Errorf("read request body: %w", err)
+	}
+
+	if int64(len(body)) < c.requestThreshold {
+		setRequestBody(req, body)
+		return nil
+	}
+
+	codec := c.codecs[0]
+	var buf bytes.Buffer
+	w, err := codec.NewWriter(&buf)
+	if err != nil {
+		return fmt.Errorf("compress %s request: %w", codec.Encoding(), err)
+	}
+	if _, err = w.Write(body); err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("compress %s request: %w", codec.Encoding(), err)
+	}
+
+	tags := c.tags("request", codec.Encoding(), req)
+	c.monitor.Count("http.compression.compressed_bytes", int64(buf.Len()), tags, 1)
+	c.monitor.Count("http.compression.uncompressed_bytes", int64(len(body)), tags, 1)
+
+	setRequestBody(req, buf.Bytes())
+	req.Header.Set("Content-Encoding", codec.Encoding())
+	return nil
+}
+
+// codec returns the codec of the content coding, if any.
+func (c *Compression) codec(encoding string) Codec {
+	for _, codec := range c.codecs {
+		if strings.EqualFold(codec.Encoding(), encoding) {
+			return codec
+		}
+	}
+	return nil
+}
+
+// contentCodings returns the content codings listed in the Content-Encoding
+// header values, the identity one excepted.
+func contentCodings(values []string) []string {
+	var encodings []string
+	for _, value := range values {
+		for _, encoding := range strings.Split(value, ",") {
+			encoding = strings.TrimSpace(encoding)
+			if encoding != "" && !strings.EqualFold(encoding, "identity") {
+				encodings = append(encodings, encoding)
+			}
+		}
+	}
+	return encodings
+}
+
+func (c *Compression) tags(direction, encoding string, req *http.Request) []string {
+	return []string{
+		"direction:" + direction,
+		"encoding:" + encoding,
+		"target:" + req.URL.Host,
+	}
+}
+
+// setRequestBody replaces the request body with an in memory one, which can be
+// rewound.
+func setRequestBody(req *http.Request, body []byte) {
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
+}
+
+// countingReader counts the bytes read.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+// Read implements io.Reader.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// decompressedBody is a decompressed response body, closing the inner
+// decompressors and the compressed body along with the outer decompressor.
+type decompressedBody struct {
+	sizeRecorderBody
+	compressed []io.Closer
+}
+
+// Close implements io.Closer.
+func (b *decompressedBody) Close() error {
+	err := b.sizeRecorderBody.Close()
+	if cerr := b.closeCompressed(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// closeCompressed closes the inner decompressors, then the compressed body.
+func (b *decompressedBody) closeCompressed() error {
+	var err error
+	for _, c := range b.compressed {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
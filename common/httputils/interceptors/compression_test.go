@@ -0,0 +1,274 @@
+package interceptors
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/f2prateek/train"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, encoding, s string) []byte {
+	t.Helper()
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "zstd":
+		w, _ = zstd.NewWriter(&buf)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	}
+	_, err := io.WriteString(w, s)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestCompression_Intercept_response(t *testing.T) {
+	payload := strings.Repeat("compressible ", 100)
+
+	tests := map[string]struct {
+		encoding       string
+		acceptEncoding string
+		wantEncoding   string
+		wantBody       string
+	}{
+		"gzip": {
+			encoding: "gzip",
+			wantBody: payload,
+		},
+		"deflate": {
+			encoding: "deflate",
+			wantBody: payload,
+		},
+		"raw deflate": {
+			encoding: "raw deflate",
+			wantBody: payload,
+		},
+		"zstd": {
+			encoding: "zstd",
+			wantBody: payload,
+		},
+		"br": {
+			encoding: "br",
+			wantBody: payload,
+		},
+		"accept encoding set by the caller": {
+			encoding:       "gzip",
+			acceptEncoding: "gzip",
+			wantEncoding:   "gzip",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var acceptEncoding string
+			body := compress(t, tt.encoding, payload)
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				acceptEncoding = req.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Encoding", strings.TrimPrefix(tt.encoding, "raw "))
+				_, _ = w.Write(body)
+			}))
+			defer ts.Close()
+
+			sh := newCountStatsdHandler()
+			client := http.Client{Transport: train.Transport(NewCompression().WithMonitor(sh))}
+
+			req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+			require.NoError(t, err)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			got, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+
+			assert.Equal(t, tt.wantEncoding, resp.Header.Get("Content-Encoding"))
+			if tt.wantBody == "" {
+				assert.Equal(t, tt.acceptEncoding, acceptEncoding)
+				assert.Equal(t, body, got)
+				return
+			}
+
+			assert.Equal(t, "gzip, deflate, zstd, br", acceptEncoding)
+			assert.Equal(t, tt.wantBody, string(got))
+			assert.Equal(t, int64(-1), resp.ContentLength)
+
+			tags := "direction:response,encoding:" + strings.TrimPrefix(tt.encoding, "raw ")
+			assert.Equal(t, int64(len(body)), sh.get("http.compression.compressed_bytes "+tags))
+			assert.Equal(t, int64(len(payload)), sh.get("http.compression.uncompressed_bytes "+tags))
+		})
+	}
+}
+
+func TestCompression_Intercept_response_codings(t *testing.T) {
+	payload := strings.Repeat("compressible ", 100)
+
+	tests := map[string]struct {
+		contentEncoding []string
+		body            []byte
+		wantErr         string
+		wantTags        string
+	}{
+		"several codings": {
+			contentEncoding: []string{"gzip, br"},
+			body:            compress(t, "br", string(compress(t, "gzip", payload))),
+			wantTags:        "direction:response,encoding:gzip+br",
+		},
+		"several headers": {
+			contentEncoding: []string{"zstd", "gzip"},
+			body:            compress(t, "gzip", string(compress(t, "zstd", payload))),
+			wantTags:        "direction:response,encoding:zstd+gzip",
+		},
+		"identity": {
+			contentEncoding: []string{"identity, gzip"},
+			body:            compress(t, "gzip", payload),
+			wantTags:        "direction:response,encoding:gzip",
+		},
+		"unknown coding": {
+			contentEncoding: []string{"gzip, compress"},
+			body:            []byte("compressed"),
+			wantErr:         `unsupported content coding "compress"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				for _, v := range tt.contentEncoding {
+					w.Header().Add("Content-Encoding", v)
+				}
+				_, _ = w.Write(tt.body)
+			}))
+			defer ts.Close()
+
+			sh := newCountStatsdHandler()
+			client := http.Client{Transport: train.Transport(NewCompression().WithMonitor(sh))}
+
+			resp, err := client.Get(ts.URL)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			got, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+
+			assert.Equal(t, payload, string(got))
+			assert.Empty(t, resp.Header.Values("Content-Encoding"))
+			assert.Equal(t, int64(len(tt.body)), sh.get("http.compression.compressed_bytes "+tt.wantTags))
+			assert.Equal(t, int64(len(payload)), sh.get("http.compression.uncompressed_bytes "+tt.wantTags))
+		})
+	}
+}
+
+func TestCompression_Intercept_request(t *testing.T) {
+	var (
+		contentEncoding string
+		received        []byte
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		contentEncoding = req.Header.Get("Content-Encoding")
+		received, _ = io.ReadAll(req.Body)
+	}))
+	defer ts.Close()
+
+	sh := newCountStatsdHandler()
+	client := http.Client{Transport: train.Transport(NewCompression().WithRequestCompression(100).WithMonitor(sh))}
+
+	tests := map[string]struct {
+		body         io.Reader
+		wantEncoding string
+	}{
+		"small":        {body: strings.NewReader("small")},
+		"large":        {body: strings.NewReader(strings.Repeat("a", 200)), wantEncoding: "gzip"},
+		"unknown size": {body: io.MultiReader(strings.NewReader(strings.Repeat("a", 200))), wantEncoding: "gzip"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := client.Post(ts.URL, "text/plain", tt.body)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			assert.Equal(t, tt.wantEncoding, contentEncoding)
+			if tt.wantEncoding == "" {
+				assert.Equal(t, "small", string(received))
+				return
+			}
+			r, err := gzip.NewReader(bytes.NewReader(received))
+			require.NoError(t, err)
+			decompressed, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, strings.Repeat("a", 200), string(decompressed))
+		})
+	}
+
+	assert.Equal(t, int64(400), sh.get("http.compression.uncompressed_bytes direction:request,encoding:gzip"))
+	assert.Less(t, sh.get("http.compression.compressed_bytes direction:request,encoding:gzip"), int64(100))
+}
+
+func TestCompression_Intercept_request_codecs(t *testing.T) {
+	payload := strings.Repeat("compressible ", 100)
+
+	for _, codec := range []Codec{GzipCodec, DeflateCodec, ZstdCodec, BrotliCodec} {
+		t.Run(codec.Encoding(), func(t *testing.T) {
+			var (
+				contentEncoding string
+				received        []byte
+			)
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				contentEncoding = req.Header.Get("Content-Encoding")
+				received, _ = io.ReadAll(req.Body)
+
+				// The response is compressed back with the same codec.
+				w.Header().Set("Content-Encoding", codec.Encoding())
+				cw, err := codec.NewWriter(w)
+				require.NoError(t, err)
+				_, _ = io.WriteString(cw, payload)
+				_ = cw.Close()
+			}))
+			defer ts.Close()
+
+			client := http.Client{Transport: train.Transport(NewCompression(codec).WithRequestCompression(100))}
+
+			resp, err := client.Post(ts.URL, "text/plain", strings.NewReader(payload))
+			require.NoError(t, err)
+			got, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			assert.Equal(t, payload, string(got))
+
+			assert.Equal(t, codec.Encoding(), contentEncoding)
+			assert.Less(t, len(received), len(payload))
+			r, err := codec.NewReader(bytes.NewReader(received))
+			require.NoError(t, err)
+			decompressed, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, payload, string(decompressed))
+		})
+	}
+}
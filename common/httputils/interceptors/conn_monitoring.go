@@ -0,0 +1,65 @@
+package interceptors
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+
+	"github.com/f2prateek/train"
+
+	"github.com/monorepo/common/monitoring/metrics"
+)
+
+// ConnMonitoring monitors the connection pool of the HTTP transport, to tune
+// its MaxIdleConns and MaxIdleConnsPerHost:
+//   - http.connection.count counts the connections obtained for the requests,
+//     tagged reused:false for the new ones and reused:true for the ones taken
+//     from the pool,
+//   - http.connection.idle_time is the time spent in the pool by the reused
+//     idle connections,
+//   - http.connection.in_flight is the number of in-flight requests per target
+//     host.
+//
+// It should be registered after the retry and hedging interceptors, for each
+// attempt to be monitored.
+type ConnMonitoring struct {
+	Monitor metrics.StatsdHandler
+
+	// inFlight is the *int64 number of in-flight requests by target host.
+	inFlight sync.Map
+}
+
+// NewConnMonitoring instantiates a new ConnMonitoring interceptor.
+func NewConnMonitoring(sh metrics.StatsdHandler) *ConnMonitoring {
+	return &ConnMonitoring{
+		Monitor: sh,
+	}
+}
+
+// Intercept implements the train.Interceptor interface
+func (m *ConnMonitoring) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+	tags := []string{"target:" + req.URL.Host}
+
+	v, _ := m.inFlight.LoadOrStore(req.URL.Host, new(int64))
+	inFlight := v.(*int64)
+	m.Monitor.Gauge("http.connection.in_flight", float64(atomic.AddInt64(inFlight, 1)), tags, 1)
+	defer func() {
+		m.Monitor.Gauge("http.connection.in_flight", float64(atomic.AddInt64(inFlight, -1)), tags, 1)
+	}()
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			connTags := append([]string{fmt.Sprintf("reused:%t", info.Reused)}, tags...)
+			m.Monitor.Count("http.connection.count", 1, connTags, 1)
+			if info.WasIdle {
+				m.Monitor.Timing("http.connection.idle_time", info.IdleTime, tags, 1)
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	return chain.Proceed(req)
+}
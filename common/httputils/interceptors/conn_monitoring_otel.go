@@ -0,0 +1,106 @@
+package interceptors
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+
+	"github.com/f2prateek/train"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	otelnoop "go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/monorepo/common/monitoring/metrics"
+	"github.com/monorepo/common/monitoring/semconv"
+)
+
+// The connection pool metrics have no OTEL semantic conventions yet.
+const (
+	otelConnCountName        = "http.client.connection.count"
+	otelConnCountUnit        = "{connection}"
+	otelConnCountDescription = "Number of connections obtained for the HTTP client requests, new or reused"
+
+	otelConnIdleTimeName        = "http.client.connection.idle_time"
+	otelConnIdleTimeUnit        = "s"
+	otelConnIdleTimeDescription = "Time spent in the pool by the reused idle connections"
+
+	// otelConnReusedKey is the attribute telling whether a connection was
+	// taken from the pool.
+	otelConnReusedKey = attribute.Key("http.connection.reused")
+)
+
+// OTELConnMonitoring is the OpenTelemetry flavour of the ConnMonitoring
+// interceptor.
+//
+// The in-flight requests per target host are the http.client.active_requests
+// metric of OTELMonitoring.
+type OTELConnMonitoring struct {
+	count    otelmetric.Int64Counter
+	idleTime otelmetric.Float64Histogram
+}
+
+// NewOTELConnMonitoring instantiates a new OTELConnMonitoring interceptor.
+//
+// If mp is nil, the global meter provider is used (see
+// metrics.SetGlobalMeterProvider).
+func NewOTELConnMonitoring(mp metrics.MeterProvider) *OTELConnMonitoring {
+	if mp == nil {
+		mp = metrics.GetGlobalMeterProvider()
+	}
+
+	meter := mp.Meter(otelMeterName, otelmetric.WithSchemaURL(semconv.SchemaURL))
+	noopMeter := otelnoop.Meter{}
+
+	m := &OTELConnMonitoring{}
+
+	var err error
+
+	m.count, err = meter.Int64Counter(
+		otelConnCountName,
+		otelmetric.WithUnit(otelConnCountUnit),
+		otelmetric.WithDescription(otelConnCountDescription),
+	)
+	if err != nil {
+		otel.Handle(err)
+		m.count, _ = noopMeter.Int64Counter(otelConnCountName)
+	}
+
+	m.idleTime, err = meter.Float64Histogram(
+		otelConnIdleTimeName,
+		otelmetric.WithUnit(otelConnIdleTimeUnit),
+		otelmetric.WithDescription(otelConnIdleTimeDescription),
+	)
+	if err != nil {
+		otel.Handle(err)
+		m.idleTime, _ = noopMeter.Float64Histogram(otelConnIdleTimeName)
+	}
+
+	return m
+}
+
+// Intercept implements the train.Interceptor interface
+func (m *OTELConnMonitoring) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+	ctx := req.Context()
+
+	attrs := []attribute.KeyValue{
+		semconv.ServerAddressKey.String(req.URL.Hostname()),
+	}
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		attrs = append(attrs, semconv.ServerPortKey.Int(port))
+	}
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			connAttrs := append(attrs[:len(attrs):len(attrs)], otelConnReusedKey.Bool(info.Reused))
+			m.count.Add(ctx, 1, otelmetric.WithAttributes(connAttrs...))
+			if info.WasIdle {
+				m.idleTime.Record(ctx, info.IdleTime.Seconds(), otelmetric.WithAttributes(attrs...))
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, trace))
+
+	return chain.Proceed(req)
+}
@@ -0,0 +1,57 @@
+package interceptors
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestOTELConnMonitoring_Intercept(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("Hello World"))
+	}))
+	defer ts.Close()
+
+	client := http.Client{Transport: train.TransportWith(&http.Transport{}, NewOTELConnMonitoring(mp))}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	got := make(map[string]metricdata.Aggregation)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		got[m.Name] = m.Data
+	}
+
+	count, ok := got[otelConnCountName].(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, count.DataPoints, 2)
+	for _, dp := range count.DataPoints {
+		assert.Equal(t, int64(1), dp.Value)
+		_, ok := dp.Attributes.Value(otelConnReusedKey)
+		assert.True(t, ok)
+	}
+
+	idleTime, ok := got[otelConnIdleTimeName].(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, idleTime.DataPoints, 1)
+	assert.Equal(t, uint64(1), idleTime.DataPoints[0].Count)
+}
@@ -0,0 +1,62 @@
+package interceptors
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connStatsdHandler records the counts, the gauges and the timings sent by
+// name.
+type connStatsdHandler struct {
+	*countStatsdHandler
+
+	mu      sync.Mutex
+	gauges  map[string][]float64
+	timings map[string][]time.Duration
+}
+
+func (h *connStatsdHandler) Gauge(name string, value float64, tags []string, rate float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.gauges[name] = append(h.gauges[name], value)
+}
+
+func (h *connStatsdHandler) Timing(name string, value time.Duration, tags []string, rate float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timings[name] = append(h.timings[name], value)
+}
+
+func TestConnMonitoring_Intercept(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("Hello World"))
+	}))
+	defer ts.Close()
+
+	sh := &connStatsdHandler{
+		countStatsdHandler: newCountStatsdHandler(),
+		gauges:             make(map[string][]float64),
+		timings:            make(map[string][]time.Duration),
+	}
+	client := http.Client{Transport: train.TransportWith(&http.Transport{}, NewConnMonitoring(sh))}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	assert.Equal(t, int64(1), sh.get("http.connection.count reused:false"))
+	assert.Equal(t, int64(1), sh.get("http.connection.count reused:true"))
+	assert.Len(t, sh.timings["http.connection.idle_time"], 1)
+	assert.Equal(t, []float64{1, 0, 1, 0}, sh.gauges["http.connection.in_flight"])
+}
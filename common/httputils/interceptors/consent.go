@@ -0,0 +1,172 @@
+package interceptors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/f2prateek/train"
+
+	"github.com/monorepo/common/consent"
+	"github.com/monorepo/common/logging"
+)
+
+// ErrConsentMissing is the error returned when a request is not sent because
+// the user did not consent to its purposes or vendors.
+var ErrConsentMissing = errors.New("user consent missing")
+
+// ConsentMissingError is the error returned by the ConsentChecker interceptor
+// when it blocks a request. Use errors.Is(err, ErrConsentMissing) to detect it.
+type ConsentMissingError struct {
+	// Host is the target host of the blocked request.
+	Host string
+	// Purposes are the purposes the user did not consent to.
+	Purposes []string
+	// Vendors are the vendors the user did not consent to.
+	Vendors []string
+}
+
+// Error implements the error interface.
+func (e *ConsentMissingError) Error() string {
+	return fmt.Sprintf("%s for %s, purposes: %v, vendors: %v", ErrConsentMissing, e.Host, e.Purposes, e.Vendors)
+}
+
+// Is allows to match the error with ErrConsentMissing.
+func (e *ConsentMissingError) Is(target error) bool {
+	return target == ErrConsentMissing
+}
+
+// ConsentRule declares the purposes and vendors the user must consent to for
+// the requests to some hosts.
+type ConsentRule struct {
+	// Hosts are the target hosts of the rule, all the hosts when empty.
+	Hosts []string
+	// Purposes are the Didomi purposes the user must consent to.
+	Purposes []string
+	// Vendors are the Didomi vendors the user must consent to.
+	Vendors []string
+	// StripHeaders are the headers removed from the request when a consent is
+	// missing. The request is blocked when empty.
+	StripHeaders []string
+}
+
+// ConsentChecker propagates the user consents through HTTP using the Didomi
+// token of the request context (see consent.NewContext), in the consent.Header
+// header. The token is only sent to the first-party hosts (see
+// WithFirstPartyHosts) and to the hosts listed by the rules, a rule without
+// purposes nor vendors then only forwards it.
+//
+// The requests matching a ConsentRule are blocked with a ConsentMissingError,
+// or stripped of some headers, when the user did not consent to the purposes
+// and vendors of the rule. A request without consent in its context is
+// considered without any consent.
+type ConsentChecker struct {
+	logger     logging.Logger
+	rules      []ConsentRule
+	firstParty []string
+}
+
+// NewConsentChecker instantiates a ConsentChecker interceptor, logging its
+// decisions with the given logger.
+func NewConsentChecker(logger logging.Logger) *ConsentChecker {
+	if logger == nil {
+		logger = logging.NewNoop()
+	}
+	return &ConsentChecker{
+		logger: logger,
+	}
+}
+
+// WithRules adds consent rules. A request must comply with all its matching
+// rules.
+func (c *ConsentChecker) WithRules(rules ...ConsentRule) *ConsentChecker {
+	c.rules = append(c.rules, rules...)
+	return c
+}
+
+// WithFirstPartyHosts adds the hosts the Didomi token is sent to, without
+// any consent rule.
+func (c *ConsentChecker) WithFirstPartyHosts(hosts ...string) *ConsentChecker {
+	c.firstParty = append(c.firstParty, hosts...)
+	return c
+}
+
+// Intercept implements the train.Interceptor interface
+func (c *ConsentChecker) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+	userConsent, hasConsent := consent.FromContext(req.Context())
+
+	// The request of the caller is left untouched.
+	cloned := false
+	clone := func() {
+		if !cloned {
+			req = req.Clone(req.Context())
+			cloned = true
+		}
+	}
+
+	if hasConsent && userConsent.Token() != "" && req.Header.Get(consent.Header) == "" && c.forwardsToken(req.URL.Hostname()) {
+		clone()
+		req.Header.Set(consent.Header, userConsent.Token())
+	}
+
+	for _, rule := range c.rules {
+		if !rule.matches(req.URL.Hostname()) {
+			continue
+		}
+
+		purposes, vendors := userConsent.Missing(rule.Purposes, rule.Vendors)
+		if len(purposes) == 0 && len(vendors) == 0 {
+			continue
+		}
+
+		logger := c.logger.WithFields(logging.Fields{
+			"host":             req.URL.Host,
+			"missing_purposes": purposes,
+			"missing_vendors":  vendors,
+		})
+
+		if len(rule.StripHeaders) == 0 {
+			logger.Info("HTTP request blocked: user consent missing")
+			return nil, &ConsentMissingError{Host: req.URL.Host, Purposes: purposes, Vendors: vendors}
+		}
+
+		logger.WithField("stripped_headers", rule.StripHeaders).Info("HTTP request headers stripped: user consent missing")
+		clone()
+		for _, h := range rule.StripHeaders {
+			req.Header.Del(h)
+		}
+	}
+
+	return chain.Proceed(req)
+}
+
+// forwardsToken reports whether the Didomi token is sent to the host: a
+// first-party host, or a host listed by a rule.
+func (c *ConsentChecker) forwardsToken(host string) bool {
+	if containsFold(c.firstParty, host) {
+		return true
+	}
+	for _, rule := range c.rules {
+		if containsFold(rule.Hosts, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the rule applies to the host.
+func (r ConsentRule) matches(host string) bool {
+	return len(r.Hosts) == 0 || containsFold(r.Hosts, host)
+}
+
+// containsFold reports whether the host is one of the hosts, ignoring case.
+func containsFold(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package interceptors
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/consent"
+	"github.com/monorepo/common/logging/loggingtest"
+)
+
+func TestConsentChecker_Intercept(t *testing.T) {
+	token := base64.StdEncoding.EncodeToString([]byte(`{
+		"purposes": {"enabled": ["analytics"]},
+		"vendors": {"enabled": ["google"]}
+	}`))
+	userConsent, err := consent.Parse(token)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		consent    *consent.Consent
+		rule       ConsentRule
+		firstParty []string
+		wantErr    bool
+		wantHeader http.Header
+		wantLog    string
+	}{
+		"consented": {
+			consent: userConsent,
+			rule:    ConsentRule{Hosts: []string{"127.0.0.1"}, Purposes: []string{"analytics"}, Vendors: []string{"google"}},
+			wantHeader: http.Header{
+				consent.Header: {token},
+				"Cookie":       {"id=1"},
+			},
+		},
+		"consented for all hosts": {
+			consent: userConsent,
+			rule:    ConsentRule{Purposes: []string{"analytics"}, Vendors: []string{"google"}},
+			wantHeader: http.Header{
+				consent.Header: nil,
+				"Cookie":       {"id=1"},
+			},
+		},
+		"first party": {
+			consent:    userConsent,
+			firstParty: []string{"127.0.0.1"},
+			wantHeader: http.Header{
+				consent.Header: {token},
+				"Cookie":       {"id=1"},
+			},
+		},
+		"other host": {
+			consent: userConsent,
+			rule:    ConsentRule{Hosts: []string{"tracking.example.com"}, Purposes: []string{"advertising"}},
+			wantHeader: http.Header{
+				consent.Header: nil,
+				"Cookie":       {"id=1"},
+			},
+		},
+		"blocked": {
+			consent: userConsent,
+			rule:    ConsentRule{Hosts: []string{"127.0.0.1"}, Purposes: []string{"advertising"}},
+			wantErr: true,
+			wantLog: "HTTP request blocked: user consent missing",
+		},
+		"blocked without consent": {
+			rule:    ConsentRule{Vendors: []string{"google"}},
+			wantErr: true,
+			wantLog: "HTTP request blocked: user consent missing",
+		},
+		"stripped": {
+			consent: userConsent,
+			rule:    ConsentRule{Hosts: []string{"127.0.0.1"}, Vendors: []string{"c:criteo"}, StripHeaders: []string{"Cookie", consent.Header}},
+			wantHeader: http.Header{
+				consent.Header: nil,
+				"Cookie":       nil,
+			},
+			wantLog: "HTTP request headers stripped: user consent missing",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var header http.Header
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				header = req.Header
+			}))
+			defer ts.Close()
+
+			logger := loggingtest.NewMock(t)
+			if tt.wantLog != "" {
+				logger.On("WithFields", mock.AnythingOfType("logging.Fields")).Return(logger)
+				logger.On("WithField", "stripped_headers", mock.Anything).Return(logger).Maybe()
+				logger.On("Info", []interface{}{tt.wantLog}).Return()
+			}
+
+			checker := NewConsentChecker(logger).WithRules(tt.rule).WithFirstPartyHosts(tt.firstParty...)
+			client := http.Client{Transport: train.Transport(checker)}
+
+			ctx := context.Background()
+			if tt.consent != nil {
+				ctx = consent.NewContext(ctx, tt.consent)
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+			require.NoError(t, err)
+			req.Header.Set("Cookie", "id=1")
+
+			resp, err := client.Do(req)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrConsentMissing))
+				assert.Nil(t, header)
+				return
+			}
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			for k, v := range tt.wantHeader {
+				assert.Equal(t, v, header[k], k)
+			}
+			// The request of the caller is left untouched.
+			assert.Equal(t, http.Header{"Cookie": {"id=1"}}, req.Header)
+		})
+	}
+}
+
+func TestConsentMissingError(t *testing.T) {
+	var err error = &ConsentMissingError{Host: "example.com", Purposes: []string{"advertising"}}
+
+	var cmErr *ConsentMissingError
+	require.True(t, errors.As(err, &cmErr))
+	assert.Equal(t, []string{"advertising"}, cmErr.Purposes)
+	assert.Equal(t, "user consent missing for example.com, purposes: [advertising], vendors: []", err.Error())
+}
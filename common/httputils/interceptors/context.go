@@ -0,0 +1,60 @@
+package interceptors
+
+import (
+	"context"
+)
+
+type ctxKey uint8
+
+const (
+	retryAttemptCtxKey ctxKey = iota
+	hedgeAttemptCtxKey
+	maxResponseSizeCtxKey
+	faultRulesCtxKey
+	attemptTracerCtxKey
+)
+
+// RetryAttempt returns the attempt number of the request attached to the
+// given context: 0 for the first attempt, 1 for the first retry, and so on.
+// The boolean is false if the request is not sent through a Retry interceptor.
+func RetryAttempt(ctx context.Context) (int, bool) {
+	attempt, ok := ctx.Value(retryAttemptCtxKey).(int)
+	return attempt, ok
+}
+
+// HedgeAttempt returns the hedging attempt number of the request attached to
+// the given context: 0 for the original request, 1 for the first hedged
+// request, and so on.
+// The boolean is false if the request is not sent through a Hedging
+// interceptor.
+func HedgeAttempt(ctx context.Context) (int, bool) {
+	attempt, ok := ctx.Value(hedgeAttemptCtxKey).(int)
+	return attempt, ok
+}
+
+// ContextWithMaxResponseSize returns a copy of the context overriding the
+// maximum response body size of the BodyLimit interceptor for the requests
+// sent with it. A size <= 0 means no limit.
+func ContextWithMaxResponseSize(ctx context.Context, size int64) context.Context {
+	return context.WithValue(ctx, maxResponseSizeCtxKey, size)
+}
+
+// maxResponseSize returns the maximum response body size of the context, if
+// any.
+func maxResponseSize(ctx context.Context) (int64, bool) {
+	size, ok := ctx.Value(maxResponseSizeCtxKey).(int64)
+	return size, ok
+}
+
+// ContextWithFaultRules returns a copy of the context with fault rules for
+// the requests sent with it, evaluated before the rules of the FaultInjection
+// interceptor. See FaultInjection.
+func ContextWithFaultRules(ctx context.Context, rules ...FaultRule) context.Context {
+	return context.WithValue(ctx, faultRulesCtxKey, rules)
+}
+
+// faultRules returns the fault rules of the context, if any.
+func faultRules(ctx context.Context) []FaultRule {
+	rules, _ := ctx.Value(faultRulesCtxKey).([]FaultRule)
+	return rules
+}
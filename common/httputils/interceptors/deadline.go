@@ -0,0 +1,64 @@
+package interceptors
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/f2prateek/train"
+
+	"github.com/monorepo/common/httputils/polarisheaders"
+)
+
+// DefaultDeadlineHeader is the default header carrying the remaining time
+// before the request deadline.
+const DefaultDeadlineHeader = polarisheaders.Deadline
+
+// Deadline propagates the deadline of the request context in a header, so
+// that the called service does not work longer than the caller waits for it.
+//
+// The header carries the remaining time before the deadline, in the format of
+// polarisheaders.FormatTimeout, to be independent of the clocks of the
+// services. The requests without deadline are sent without header, and a
+// header already set on the request is kept as is.
+//
+// It should be registered after the retry and hedging interceptors, for the
+// remaining time to be computed for each attempt.
+type Deadline struct {
+	header string
+	margin time.Duration
+	now    func() time.Time
+}
+
+// NewDeadline instantiates a Deadline interceptor, setting the
+// DefaultDeadlineHeader header.
+func NewDeadline() *Deadline {
+	return &Deadline{
+		header: DefaultDeadlineHeader,
+		now:    time.Now,
+	}
+}
+
+// WithHeader sets the name of the header carrying the remaining time.
+func (d *Deadline) WithHeader(name string) *Deadline {
+	d.header = http.CanonicalHeaderKey(name)
+	return d
+}
+
+// WithMargin subtracts margin from the remaining time sent, to leave the time
+// to the response to come back, e.g. the network latency.
+func (d *Deadline) WithMargin(margin time.Duration) *Deadline {
+	d.margin = margin
+	return d
+}
+
+// Intercept implements the train.Interceptor interface
+func (d *Deadline) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+
+	if deadline, ok := req.Context().Deadline(); ok && req.Header.Get(d.header) == "" {
+		remaining := deadline.Sub(d.now()) - d.margin
+		req.Header.Set(d.header, polarisheaders.FormatTimeout(remaining))
+	}
+
+	return chain.Proceed(req)
+}
@@ -0,0 +1,86 @@
+package interceptors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadline_Intercept(t *testing.T) {
+	now := time.Now()
+	withDeadline := func() context.Context {
+		ctx, cancel := context.WithDeadline(context.Background(), now.Add(2*time.Second))
+		t.Cleanup(cancel)
+		return ctx
+	}
+
+	tests := map[string]struct {
+		interceptor *Deadline
+		header      string
+		ctx         context.Context
+		reqHeader   string
+		want        string
+	}{
+		"from context": {
+			interceptor: NewDeadline(),
+			header:      "X-Request-Deadline",
+			ctx:         withDeadline(),
+			want:        "2000000u",
+		},
+		"with margin": {
+			interceptor: NewDeadline().WithMargin(500 * time.Millisecond),
+			header:      "X-Request-Deadline",
+			ctx:         withDeadline(),
+			want:        "1500000u",
+		},
+		"no deadline": {
+			interceptor: NewDeadline(),
+			header:      "X-Request-Deadline",
+			ctx:         context.Background(),
+		},
+		"already set": {
+			interceptor: NewDeadline(),
+			header:      "X-Request-Deadline",
+			ctx:         withDeadline(),
+			reqHeader:   "1S",
+			want:        "1S",
+		},
+		"custom header": {
+			interceptor: NewDeadline().WithHeader("grpc-timeout"),
+			header:      "Grpc-Timeout",
+			ctx:         withDeadline(),
+			want:        "2000000u",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				got = req.Header.Get(tt.header)
+			}))
+			defer ts.Close()
+
+			tt.interceptor.now = func() time.Time { return now }
+			client := http.Client{Transport: train.Transport(tt.interceptor)}
+
+			req, err := http.NewRequestWithContext(tt.ctx, http.MethodGet, ts.URL, nil)
+			require.NoError(t, err)
+			if tt.reqHeader != "" {
+				req.Header.Set(tt.header, tt.reqHeader)
+			}
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
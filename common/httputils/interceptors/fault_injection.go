@@ -0,0 +1,257 @@
+package interceptors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f2prateek/train"
+)
+
+// ErrFaultInjected is the error of the connection failures injected by the
+// FaultInjection interceptor.
+var ErrFaultInjected = errors.New("fault injected")
+
+// FaultInjectedHeader is the response header listing the faults injected by
+// the FaultInjection interceptor, see FaultInjected.
+const FaultInjectedHeader = "X-Fault-Injected"
+
+// faultInjectedKey is the span tag and metric attribute of the injected
+// faults.
+const faultInjectedKey = "fault.injected"
+
+// All the kinds of injected faults.
+const (
+	FaultLatency  = "latency"
+	FaultError    = "error"
+	FaultStatus   = "status"
+	FaultTruncate = "truncate"
+)
+
+// FaultInjectedError is the error returned by the FaultInjection interceptor
+// when it injects a connection failure. Use errors.Is(err, ErrFaultInjected)
+// to detect it.
+type FaultInjectedError struct {
+	Host string
+	// Delay is the latency injected before the failure, if any.
+	Delay time.Duration
+}
+
+// Error implements the error interface.
+func (e *FaultInjectedError) Error() string {
+	return fmt.Sprintf("%s: connection to %s failed", ErrFaultInjected, e.Host)
+}
+
+// Is allows to match the error with ErrFaultInjected.
+func (e *FaultInjectedError) Is(target error) bool {
+	return target == ErrFaultInjected
+}
+
+// FaultRule is a fault injection rule.
+type FaultRule struct {
+	// Hosts are the target hosts, or host:port, of the rule. Empty means
+	// all the hosts.
+	Hosts []string
+	// Routes are the routes of the rule. Empty means all the routes.
+	Routes []RouteMatcher
+	// Percentage is the percentage of the matching requests with faults,
+	// between 0 and 100.
+	Percentage float64
+
+	// Delay is the latency added before the request is sent.
+	Delay time.Duration
+	// Error fails the request with a FaultInjectedError, as a connection
+	// failure: the Retry interceptor retries it.
+	Error bool
+	// StatusCode is the status code of the response returned instead of
+	// sending the request.
+	StatusCode int
+	// TruncateBody truncates the response body after the given number of
+	// bytes, reading it then fails with io.ErrUnexpectedEOF.
+	TruncateBody int64
+}
+
+// matches reports whether the request matches the hosts and routes of the
+// rule.
+func (r FaultRule) matches(req *http.Request) bool {
+	if len(r.Hosts) > 0 && !containsString(r.Hosts, req.URL.Host) && !containsString(r.Hosts, req.URL.Hostname()) {
+		return false
+	}
+	if len(r.Routes) == 0 {
+		return true
+	}
+	for _, rm := range r.Routes {
+		if _, ok := rm.MatchRequest(req); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FaultInjection is a HTTP client middleware injecting faults in the
+// requests matching its rules, for chaos testing: latency, connection
+// failures, error status codes or truncated response bodies.
+//
+// The first matching rule is applied, the rules of the request context first
+// (see ContextWithFaultRules). The rules can be changed at runtime with
+// SetRules.
+//
+// The injected faults are listed in the FaultInjectedHeader header of the
+// responses, and the injected connection failures match ErrFaultInjected, so
+// that the Monitoring and Tracing interceptors tag them. FaultInjection must
+// then be registered after them.
+type FaultInjection struct {
+	mu    sync.RWMutex
+	rules []FaultRule
+
+	rand  func() float64
+	sleep func(req *http.Request, d time.Duration) error
+}
+
+// NewFaultInjection instantiates a new FaultInjection interceptor with the
+// given rules.
+func NewFaultInjection(rules ...FaultRule) *FaultInjection {
+	return &FaultInjection{
+		rules: rules,
+		rand:  rand.Float64,
+		sleep: sleepContext,
+	}
+}
+
+// SetRules replaces the rules of the interceptor. No rules disables the fault
+// injection.
+func (f *FaultInjection) SetRules(rules ...FaultRule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = rules
+}
+
+// Intercept implements the train.Interceptor interface
+func (f *FaultInjection) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+
+	rule, ok := f.match(req)
+	if !ok || f.rand()*100 >= rule.Percentage {
+		return chain.Proceed(req)
+	}
+
+	var faults []string
+	if rule.Delay > 0 {
+		faults = append(faults, FaultLatency)
+		if err := f.sleep(req, rule.Delay); err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Error {
+		return nil, &FaultInjectedError{Host: req.URL.Host, Delay: rule.Delay}
+	}
+
+	if rule.StatusCode != 0 {
+		faults = append(faults, FaultStatus)
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", rule.StatusCode, http.StatusText(rule.StatusCode)),
+			StatusCode: rule.StatusCode,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{FaultInjectedHeader: {strings.Join(faults, ",")}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+
+	resp, err := chain.Proceed(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if rule.TruncateBody > 0 {
+		faults = append(faults, FaultTruncate)
+		resp.Body = &truncatedBody{
+			ReadCloser: resp.Body,
+			remaining:  rule.TruncateBody,
+		}
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+	}
+	if len(faults) > 0 {
+		resp.Header.Set(FaultInjectedHeader, strings.Join(faults, ","))
+	}
+	return resp, nil
+}
+
+// match returns the first rule matching the request.
+func (f *FaultInjection) match(req *http.Request) (FaultRule, bool) {
+	for _, rule := range faultRules(req.Context()) {
+		if rule.matches(req) {
+			return rule, true
+		}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, rule := range f.rules {
+		if rule.matches(req) {
+			return rule, true
+		}
+	}
+	return FaultRule{}, false
+}
+
+// FaultInjected returns the faults injected by the FaultInjection interceptor
+// in the response or the error of a request, e.g. "latency,status", or "" if
+// none.
+func FaultInjected(resp *http.Response, err error) string {
+	var faultErr *FaultInjectedError
+	if errors.As(err, &faultErr) && faultErr.Delay > 0 {
+		return FaultLatency + "," + FaultError
+	}
+	if errors.Is(err, ErrFaultInjected) {
+		return FaultError
+	}
+	if resp != nil {
+		return resp.Header.Get(FaultInjectedHeader)
+	}
+	return ""
+}
+
+// truncatedBody is a response body failing with io.ErrUnexpectedEOF once
+// its remaining bytes are read.
+type truncatedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+// Read implements io.Reader.
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// sleepContext waits for the given duration, or until the request context is
+// done.
+func sleepContext(req *http.Request, d time.Duration) error {
+	return waitContext(req.Context(), d)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
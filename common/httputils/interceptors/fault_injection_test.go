@@ -0,0 +1,163 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+)
+
+func newTestFaultInjection(rules ...FaultRule) (*FaultInjection, *[]time.Duration) {
+	var sleeps []time.Duration
+	f := NewFaultInjection(rules...)
+	f.rand = func() float64 { return 0.5 }
+	f.sleep = func(_ *http.Request, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	return f, &sleeps
+}
+
+func TestFaultInjection_Intercept(t *testing.T) {
+	var calls int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		_, _ = io.WriteString(w, "Hello World")
+	}))
+	defer ts.Close()
+
+	t.Run("latency", func(t *testing.T) {
+		f, sleeps := newTestFaultInjection(FaultRule{Percentage: 100, Delay: time.Second})
+		client := http.Client{Transport: train.Transport(f)}
+
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, []time.Duration{time.Second}, *sleeps)
+		assert.Equal(t, "latency", FaultInjected(resp, err))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		f, _ := newTestFaultInjection(FaultRule{Percentage: 100, Error: true})
+		client := http.Client{Transport: train.Transport(f)}
+
+		before := atomic.LoadInt64(&calls)
+		_, err := client.Get(ts.URL)
+		assert.True(t, errors.Is(err, ErrFaultInjected))
+		assert.Equal(t, "error", FaultInjected(nil, err))
+		assert.Equal(t, before, atomic.LoadInt64(&calls))
+	})
+
+	t.Run("latency and error", func(t *testing.T) {
+		f, sleeps := newTestFaultInjection(FaultRule{Percentage: 100, Delay: time.Second, Error: true})
+		client := http.Client{Transport: train.Transport(f)}
+
+		_, err := client.Get(ts.URL)
+		assert.ErrorIs(t, err, ErrFaultInjected)
+		assert.Equal(t, []time.Duration{time.Second}, *sleeps)
+		assert.Equal(t, "latency,error", FaultInjected(nil, err))
+	})
+
+	t.Run("status code", func(t *testing.T) {
+		f, _ := newTestFaultInjection(FaultRule{Percentage: 100, Delay: time.Millisecond, StatusCode: http.StatusServiceUnavailable})
+		client := http.Client{Transport: train.Transport(f)}
+
+		before := atomic.LoadInt64(&calls)
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, "latency,status", FaultInjected(resp, err))
+		assert.Equal(t, before, atomic.LoadInt64(&calls))
+	})
+
+	t.Run("truncated body", func(t *testing.T) {
+		f, _ := newTestFaultInjection(FaultRule{Percentage: 100, TruncateBody: 5})
+		client := http.Client{Transport: train.Transport(f)}
+
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, "Hello", string(body))
+		assert.Equal(t, "truncate", FaultInjected(resp, nil))
+	})
+
+	t.Run("percentage", func(t *testing.T) {
+		f, _ := newTestFaultInjection(FaultRule{Percentage: 40, Error: true})
+		client := http.Client{Transport: train.Transport(f)}
+
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Empty(t, FaultInjected(resp, err))
+	})
+}
+
+func TestFaultInjection_Intercept_rules(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	f, _ := newTestFaultInjection(
+		FaultRule{Hosts: []string{"other.example.com"}, Percentage: 100, StatusCode: http.StatusBadGateway},
+		FaultRule{Hosts: []string{host}, Routes: []RouteMatcher{StaticRouteMatcher(http.MethodGet, "/users")}, Percentage: 100, StatusCode: http.StatusServiceUnavailable},
+		FaultRule{Hosts: []string{"127.0.0.1"}, Percentage: 100, StatusCode: http.StatusInternalServerError},
+	)
+	client := http.Client{Transport: train.Transport(f)}
+
+	get := func(ctx context.Context, path string) int {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, get(context.Background(), "/users"))
+	assert.Equal(t, http.StatusInternalServerError, get(context.Background(), "/ads"))
+
+	ctx := ContextWithFaultRules(context.Background(), FaultRule{Percentage: 100, StatusCode: http.StatusTooManyRequests})
+	assert.Equal(t, http.StatusTooManyRequests, get(ctx, "/users"))
+
+	f.SetRules()
+	assert.Equal(t, http.StatusOK, get(context.Background(), "/users"))
+}
+
+func TestFaultInjection_Intercept_observability(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+
+	sh := newCountStatsdHandler()
+	f, _ := newTestFaultInjection(FaultRule{Percentage: 100, Delay: time.Millisecond, StatusCode: http.StatusServiceUnavailable})
+	client := http.Client{Transport: train.Transport(NewTracing(), NewMonitoring(sh), f)}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	// One tag for each fault, the commas separating the DogStatsD tags.
+	assert.Equal(t, int64(1), sh.get("http.request.count status_code:503,status_class:5xx,fault_injected:latency,fault_injected:status"))
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "latency,status", spans[0].Tag("fault.injected"))
+}
@@ -0,0 +1,39 @@
+package interceptors
+
+import (
+	"net/http"
+
+	"github.com/f2prateek/train"
+
+	"github.com/monorepo/common/httputils/polarisheaders"
+)
+
+// HeaderPropagation sets the headers of the requests from their context
+// values, according to polarisheaders mappings. Headers already set on the
+// request are kept as is.
+type HeaderPropagation struct {
+	mappings []polarisheaders.Mapping
+}
+
+// NewHeaderPropagation instantiates a HeaderPropagation interceptor with the
+// given mappings, e.g. polarisheaders.Default.
+func NewHeaderPropagation(mappings ...polarisheaders.Mapping) *HeaderPropagation {
+	return &HeaderPropagation{
+		mappings: mappings,
+	}
+}
+
+// NewBrandForwarding instantiates a HeaderPropagation interceptor forwarding
+// the brand, brand origin host and language headers.
+func NewBrandForwarding() *HeaderPropagation {
+	return NewHeaderPropagation(polarisheaders.BrandMappings...)
+}
+
+// Intercept implements the train.Interceptor interface
+func (p *HeaderPropagation) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+
+	polarisheaders.Inject(req.Context(), req.Header, p.mappings...)
+
+	return chain.Proceed(req)
+}
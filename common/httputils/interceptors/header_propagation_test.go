@@ -0,0 +1,80 @@
+package interceptors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/contextkeys"
+	"github.com/monorepo/common/httputils/polarisheaders"
+)
+
+func TestHeaderPropagation_Intercept(t *testing.T) {
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		header = req.Header
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, contextkeys.AuthToken, "token")
+	ctx = context.WithValue(ctx, contextkeys.Brand, "brand")
+	ctx = context.WithValue(ctx, contextkeys.BrandOriginHost, "brand.example.com")
+	ctx = context.WithValue(ctx, contextkeys.Language, "fr-FR")
+
+	tests := map[string]struct {
+		interceptor *HeaderPropagation
+		want        map[string]string
+	}{
+		"default": {
+			interceptor: NewHeaderPropagation(polarisheaders.Default...),
+			want: map[string]string{
+				polarisheaders.Authorization: "",
+				polarisheaders.Brand:         "my-brand",
+				polarisheaders.ForwardedHost: "brand.example.com",
+				polarisheaders.Language:      "fr-FR",
+			},
+		},
+		"authorization": {
+			interceptor: NewHeaderPropagation(polarisheaders.AuthorizationMapping),
+			want: map[string]string{
+				polarisheaders.Authorization: "Bearer token",
+				polarisheaders.Brand:         "my-brand",
+				polarisheaders.ForwardedHost: "",
+				polarisheaders.Language:      "",
+			},
+		},
+		"brand forwarding": {
+			interceptor: NewBrandForwarding(),
+			want: map[string]string{
+				polarisheaders.Authorization: "",
+				polarisheaders.Brand:         "my-brand",
+				polarisheaders.ForwardedHost: "brand.example.com",
+				polarisheaders.Language:      "fr-FR",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := http.Client{Transport: train.Transport(tt.interceptor)}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+			require.NoError(t, err)
+			req.Header.Set(polarisheaders.Brand, "my-brand")
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			for k, v := range tt.want {
+				assert.Equal(t, v, header.Get(k), k)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/f2prateek/train"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+const (
+	// hedgingLatencyWindow is the number of latest latencies used to compute
+	// the percentile delay.
+	hedgingLatencyWindow = 512
+	// hedgingMinSamples is the number of latencies needed before using the
+	// percentile delay.
+	hedgingMinSamples = 20
+	// hedgingRefreshInterval is the number of new latencies after which the
+	// percentile delay is computed again.
+	hedgingRefreshInterval = 16
+	// hedgingSaturatedPollInterval is the interval at which the limiter is
+	// checked again when it is saturated at the time of a hedged request.
+	hedgingSaturatedPollInterval = 5 * time.Millisecond
+)
+
+// Hedging is a HTTP client middleware sending hedged requests: when a request
+// takes longer than a delay, a duplicate request is sent, and the first
+// successful response (no error and status code < 500) is returned. The other
+// pending requests are cancelled.
+//
+// Only idempotent requests are hedged, see NewRetry for the definition.
+//
+// The hedged requests are delayed while the given Limiter is saturated, so
+// that they don't take the place of new requests.
+type Hedging struct {
+	delay      time.Duration
+	percentile float64
+	maxHedges  int
+	limiter    Limiter
+
+	mu             sync.Mutex
+	latencies      []time.Duration
+	next           int
+	sinceRefresh   int
+	percentileWait time.Duration
+}
+
+type hedgeResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+	latency time.Duration
+}
+
+// NewHedging instantiates a new Hedging interceptor, sending one hedged
+// request after the given delay.
+func NewHedging(delay time.Duration) *Hedging {
+	return &Hedging{
+		delay:     delay,
+		maxHedges: 1,
+	}
+}
+
+// WithPercentileDelay derives the delay from the latencies of the latest
+// successful requests: hedged requests are sent once a request is slower than
+// the given percentile (between 0 and 1) of them, e.g. 0.95.
+//
+// The fixed delay given to NewHedging is used until enough latencies are
+// observed.
+func (h *Hedging) WithPercentileDelay(percentile float64) *Hedging {
+	h.percentile = percentile
+	h.latencies = make([]time.Duration, 0, hedgingLatencyWindow)
+	return h
+}
+
+// WithMaxHedges sets the maximum number of hedged requests sent for a
+// request, each one after the delay. Default is 1.
+func (h *Hedging) WithMaxHedges(count int) *Hedging {
+	h.maxHedges = count
+	return h
+}
+
+// WithLimiter sets the Limiter whose budget must be respected: no hedged
+// request is sent while it is saturated.
+func (h *Hedging) WithLimiter(l Limiter) *Hedging {
+	h.limiter = l
+	return h
+}
+
+// Intercept implements the train.Interceptor interface
+func (h *Hedging) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+	ctx := req.Context()
+
+	if h.maxHedges <= 0 || !isIdempotent(req) ||
+		(req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return chain.Proceed(req)
+	}
+
+	results := make(chan hedgeResult, h.maxHedges+1)
+	cancels := make([]context.CancelFunc, 0, h.maxHedges+1)
+
+	send := func(attempt int) error {
+		attemptReq, cancel, err := newHedgeRequest(req, attempt)
+		if err != nil {
+			return err
+		}
+		cancels = append(cancels, cancel)
+
+		go func() {
+			start := time.Now()
+			resp, err := chain.Proceed(attemptReq)
+			results <- hedgeResult{attempt: attempt, resp: resp, err: err, latency: time.Since(start)}
+		}()
+		return nil
+	}
+
+	if err := send(0); err != nil {
+		return nil, err
+	}
+	sent, pending := 1, 1
+
+	timer := time.NewTimer(h.currentDelay())
+	defer timer.Stop()
+
+	var (
+		failure hedgeResult
+		failed  bool
+	)
+	for {
+		select {
+		case <-timer.C:
+			if sent > h.maxHedges {
+				continue
+			}
+			if h.limiter.saturated() {
+				// Wait for the limiter to have room, rather than giving up.
+				timer.Reset(hedgingSaturatedPollInterval)
+				continue
+			}
+			if err := send(sent); err != nil {
+				timer.Reset(h.currentDelay())
+				continue
+			}
+			sent++
+			pending++
+			timer.Reset(h.currentDelay())
+
+		case r := <-results:
+			pending--
+
+			if r.err == nil && r.resp.StatusCode < http.StatusInternalServerError {
+				h.observe(r.latency)
+				markHedgeWinner(ctx, r.attempt, sent)
+				cancelLosers(cancels, r.attempt, results, pending)
+				r.resp.Body = &cancelOnCloseBody{ReadCloser: r.resp.Body, cancel: cancels[r.attempt]}
+				return r.resp, nil
+			}
+
+			// Only the last failure is returned, the previous one is released.
+			if failed {
+				if failure.resp != nil {
+					discard(failure.resp)
+				}
+				cancels[failure.attempt]()
+			}
+			failure, failed = r, true
+
+			if pending > 0 {
+				continue
+			}
+			if failure.resp != nil {
+				failure.resp.Body = &cancelOnCloseBody{ReadCloser: failure.resp.Body, cancel: cancels[failure.attempt]}
+			} else {
+				cancels[failure.attempt]()
+			}
+			return failure.resp, failure.err
+		}
+	}
+}
+
+// currentDelay returns the delay before sending a hedged request.
+func (h *Hedging) currentDelay() time.Duration {
+	if h.percentile == 0 {
+		return h.delay
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.percentileWait == 0 {
+		return h.delay
+	}
+	return h.percentileWait
+}
+
+// observe records the latency of a successful request, for the percentile
+// delay.
+func (h *Hedging) observe(latency time.Duration) {
+	if h.percentile == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < hedgingLatencyWindow {
+		h.latencies = append(h.latencies, latency)
+	} else {
+		h.latencies[h.next] = latency
+		h.next = (h.next + 1) % hedgingLatencyWindow
+	}
+
+	h.sinceRefresh++
+	if len(h.latencies) < hedgingMinSamples || (h.percentileWait != 0 && h.sinceRefresh < hedgingRefreshInterval) {
+		return
+	}
+	h.sinceRefresh = 0
+
+	sorted := make([]time.Duration, len(h.latencies))
+	copy(sorted, h.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(math.Ceil(h.percentile*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	} else if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	h.percentileWait = sorted[idx]
+}
+
+// newHedgeRequest returns a copy of the original request, with a rewound body
+// and its own cancelable context, for the given attempt. The attempts being
+// concurrent, the context has its own client trace, see traceAttempt.
+func newHedgeRequest(req *http.Request, attempt int) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(traceAttempt(context.WithValue(req.Context(), hedgeAttemptCtxKey, attempt)))
+	attemptReq := req.Clone(ctx)
+
+	if attempt == 0 || req.GetBody == nil {
+		return attemptReq, cancel, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("rewind body for hedging: %w", err)
+	}
+	attemptReq.Body = body
+
+	return attemptReq, cancel, nil
+}
+
+// cancelLosers cancels all the attempts but the winner one, and discards the
+// responses of the pending ones.
+func cancelLosers(cancels []context.CancelFunc, winner int, results <-chan hedgeResult, pending int) {
+	for attempt, cancel := range cancels {
+		if attempt != winner {
+			cancel()
+		}
+	}
+
+	if pending == 0 {
+		return
+	}
+	go func() {
+		for i := 0; i < pending; i++ {
+			if r := <-results; r.resp != nil {
+				discard(r.resp)
+			}
+		}
+	}()
+}
+
+// markHedgeWinner sets the winning attempt on the span of the request, if any.
+func markHedgeWinner(ctx context.Context, winner, sent int) {
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		span.SetTag("http.hedge.winner", winner)
+		span.SetTag("http.hedge.attempts", sent)
+	}
+	oteltrace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("http.hedge.winner", winner),
+		attribute.Int("http.hedge.attempts", sent),
+	)
+}
+
+// cancelOnCloseBody cancels the context of a request once its response body
+// is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements io.Closer.
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
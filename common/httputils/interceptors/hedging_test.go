@@ -0,0 +1,363 @@
+package interceptors
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+)
+
+// hedgingTestServer answers the requests of each attempt after the given
+// delay, with the given status code.
+func hedgingTestServer(t *testing.T, delays []time.Duration, statuses []int) (*httptest.Server, *int32, chan struct{}) {
+	t.Helper()
+	var calls int32
+	canceled := make(chan struct{}, len(delays))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1)) - 1
+		select {
+		case <-time.After(delays[n]):
+		case <-req.Context().Done():
+			canceled <- struct{}{}
+			return
+		}
+		w.WriteHeader(statuses[n])
+		_, _ = io.WriteString(w, strings.Repeat("a", n+1))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &calls, canceled
+}
+
+// hedgeAttemptRecorder records the hedging attempts going through it.
+type hedgeAttemptRecorder struct {
+	mu       sync.Mutex
+	attempts []int
+}
+
+func (r *hedgeAttemptRecorder) Intercept(chain train.Chain) (*http.Response, error) {
+	if attempt, ok := HedgeAttempt(chain.Request().Context()); ok {
+		r.mu.Lock()
+		r.attempts = append(r.attempts, attempt)
+		r.mu.Unlock()
+	}
+	return chain.Proceed(chain.Request())
+}
+
+func TestHedging_Intercept(t *testing.T) {
+	ts, calls, canceled := hedgingTestServer(t,
+		[]time.Duration{time.Second, 0},
+		[]int{http.StatusOK, http.StatusOK},
+	)
+
+	rec := &hedgeAttemptRecorder{}
+	client := http.Client{Transport: train.Transport(NewHedging(20*time.Millisecond), rec)}
+
+	start := time.Now()
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, "aa", string(body))
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	assert.ElementsMatch(t, []int{0, 1}, rec.attempts)
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("losing request not canceled")
+	}
+}
+
+func TestHedging_Intercept_fast_request_is_not_hedged(t *testing.T) {
+	ts, calls, _ := hedgingTestServer(t,
+		[]time.Duration{0, 0},
+		[]int{http.StatusOK, http.StatusOK},
+	)
+
+	client := http.Client{Transport: train.Transport(NewHedging(100 * time.Millisecond))}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestHedging_Intercept_waits_for_a_success(t *testing.T) {
+	ts, calls, _ := hedgingTestServer(t,
+		[]time.Duration{100 * time.Millisecond, 0},
+		[]int{http.StatusOK, http.StatusServiceUnavailable},
+	)
+
+	client := http.Client{Transport: train.Transport(NewHedging(20 * time.Millisecond))}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "a", string(body))
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestHedging_Intercept_all_failed(t *testing.T) {
+	ts, _, _ := hedgingTestServer(t,
+		[]time.Duration{50 * time.Millisecond, 0},
+		[]int{http.StatusBadGateway, http.StatusServiceUnavailable},
+	)
+
+	client := http.Client{Transport: train.Transport(NewHedging(20 * time.Millisecond))}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, "a", string(body))
+}
+
+func TestHedging_Intercept_all_errored(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		ctxs []context.Context
+	)
+	fail := train.InterceptorFunc(func(chain train.Chain) (*http.Response, error) {
+		ctx := chain.Request().Context()
+		mu.Lock()
+		ctxs = append(ctxs, ctx)
+		mu.Unlock()
+		if attempt, _ := HedgeAttempt(ctx); attempt == 0 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		return nil, io.ErrUnexpectedEOF
+	})
+
+	client := http.Client{Transport: train.Transport(NewHedging(10*time.Millisecond), fail)}
+
+	_, err := client.Get("http://example.com")
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// The contexts of all the errored attempts are released.
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, ctxs, 2)
+	for _, ctx := range ctxs {
+		assert.Error(t, ctx.Err())
+	}
+}
+
+func TestHedging_Intercept_not_hedged(t *testing.T) {
+	tests := map[string]struct {
+		method  string
+		limiter Limiter
+	}{
+		"non idempotent method": {
+			method: http.MethodPost,
+		},
+		"saturated limiter": {
+			method:  http.MethodGet,
+			limiter: NewLimiter(1),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts, calls, _ := hedgingTestServer(t,
+				[]time.Duration{100 * time.Millisecond, 0},
+				[]int{http.StatusOK, http.StatusOK},
+			)
+
+			h := NewHedging(20 * time.Millisecond).WithLimiter(tt.limiter)
+			interceptors := []train.Interceptor{h}
+			if tt.limiter != nil {
+				interceptors = append(interceptors, tt.limiter)
+			}
+			client := http.Client{Transport: train.Transport(interceptors...)}
+
+			req, err := http.NewRequest(tt.method, ts.URL, strings.NewReader("body"))
+			require.NoError(t, err)
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+		})
+	}
+}
+
+func TestHedging_Intercept_briefly_saturated_limiter(t *testing.T) {
+	ts, calls, _ := hedgingTestServer(t,
+		[]time.Duration{time.Second, 0},
+		[]int{http.StatusOK, http.StatusOK},
+	)
+
+	// The limiter is saturated by another request when the hedged request is
+	// due, and has room shortly after.
+	limiter := NewLimiter(1)
+	require.NoError(t, limiter.acquire(context.Background()))
+	time.AfterFunc(40*time.Millisecond, limiter.release)
+
+	client := http.Client{Transport: train.Transport(NewHedging(20 * time.Millisecond).WithLimiter(limiter))}
+
+	start := time.Now()
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestHedging_Intercept_marks_winner_on_span(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	ts, _, _ := hedgingTestServer(t,
+		[]time.Duration{time.Second, 0},
+		[]int{http.StatusOK, http.StatusOK},
+	)
+
+	client := http.Client{Transport: train.Transport(NewTracing(), NewHedging(20*time.Millisecond))}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	var span mocktracer.Span
+	for _, s := range mt.FinishedSpans() {
+		if s.OperationName() == "http.request" {
+			span = s
+		}
+	}
+	require.NotNil(t, span)
+	assert.Equal(t, 1, span.Tag("http.hedge.winner"))
+	assert.Equal(t, 2, span.Tag("http.hedge.attempts"))
+}
+
+// slowDialTransport returns a transport whose dials take the given delay, for
+// the dials of the hedged requests to be concurrent.
+func slowDialTransport(delay time.Duration) *http.Transport {
+	var d net.Dialer
+	return &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			time.Sleep(delay)
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// Run with -race: the concurrent attempts must not share their DNS, connect
+// and TLS spans.
+func TestHedging_Intercept_traces_each_attempt(t *testing.T) {
+	t.Run("datadog", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		ts, _, _ := hedgingTestServer(t,
+			[]time.Duration{50 * time.Millisecond, 0, 0, 0},
+			[]int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK},
+		)
+		client := http.Client{Transport: train.TransportWith(slowDialTransport(10*time.Millisecond),
+			NewTracing(), NewHedging(time.Millisecond).WithMaxHedges(3))}
+
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		assert.Eventually(t, func() bool {
+			var requestSpan mocktracer.Span
+			var connects []mocktracer.Span
+			for _, s := range mt.FinishedSpans() {
+				switch s.OperationName() {
+				case "http.request":
+					requestSpan = s
+				case "http.connect":
+					connects = append(connects, s)
+				}
+			}
+			if requestSpan == nil || len(connects) < 2 {
+				return false
+			}
+			for _, s := range connects {
+				if s.ParentID() != requestSpan.SpanID() {
+					return false
+				}
+			}
+			return true
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("otel", func(t *testing.T) {
+		sr := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+		ts, _, _ := hedgingTestServer(t,
+			[]time.Duration{50 * time.Millisecond, 0, 0, 0},
+			[]int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK},
+		)
+		client := http.Client{Transport: train.TransportWith(slowDialTransport(10*time.Millisecond),
+			NewOTELTracing().WithTracerProvider(tp), NewHedging(time.Millisecond).WithMaxHedges(3))}
+
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		assert.Eventually(t, func() bool {
+			var requestSpan sdktrace.ReadOnlySpan
+			var connects []sdktrace.ReadOnlySpan
+			for _, s := range sr.Ended() {
+				switch s.Name() {
+				case http.MethodGet:
+					requestSpan = s
+				case "http.connect":
+					connects = append(connects, s)
+				}
+			}
+			if requestSpan == nil || len(connects) < 2 {
+				return false
+			}
+			for _, s := range connects {
+				if s.Parent().SpanID() != requestSpan.SpanContext().SpanID() {
+					return false
+				}
+			}
+			return true
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestHedging_percentile_delay(t *testing.T) {
+	h := NewHedging(time.Second).WithPercentileDelay(0.9)
+
+	for i := 1; i < hedgingMinSamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, time.Second, h.currentDelay())
+
+	// 20 samples from 1ms to 20ms.
+	h.observe(20 * time.Millisecond)
+	assert.Equal(t, 18*time.Millisecond, h.currentDelay())
+}
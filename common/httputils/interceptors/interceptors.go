@@ -8,7 +8,7 @@ import (
 // GetDefaultInterceptors returns a list of default interceptors
 func GetDefaultInterceptors(appName string, appVersion string, sh metrics.StatsdHandler) []train.Interceptor {
 	return []train.Interceptor{
-		//NewUniqueID(),
+		NewUniqueID(),
 		NewUserAgent(appName, appVersion),
 		NewMonitoring(sh),
 		NewTracing(),
@@ -2,12 +2,18 @@ package interceptors
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/f2prateek/train"
 )
 
+// ErrLimited is the error of the requests interrupted while waiting for a
+// slot of a Limiter.
+var ErrLimited = errors.New("request interrupted while waiting in limit queue")
+
 // Limiter is a HTTP client middleware to limit the number of concurrent requests
 type Limiter chan struct{}
 
@@ -37,13 +43,43 @@ func (l Limiter) release() {
 	<-l
 }
 
+// saturated reports whether all the slots of the limiter are taken.
+func (l Limiter) saturated() bool {
+	return l != nil && len(l) == cap(l)
+}
+
 // Intercept implements train.Interceptor interface
 func (l Limiter) Intercept(chain train.Chain) (*http.Response, error) {
 	err := l.acquire(chain.Request().Context())
 	if err != nil {
-		return nil, fmt.Errorf("request interrupted while waiting in limit queue: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrLimited, err)
 	}
 
 	defer l.release()
 	return chain.Proceed(chain.Request())
 }
+
+// HostLimiter is a HTTP client middleware to limit the number of concurrent
+// requests for each target host, e.g. the number of concurrent HTTP/2 streams.
+type HostLimiter struct {
+	size int
+
+	// limiters is the Limiter by target host.
+	limiters sync.Map
+}
+
+// NewHostLimiter instantiates a new HostLimiter, allowing size concurrent
+// requests for each target host.
+func NewHostLimiter(size int) *HostLimiter {
+	return &HostLimiter{size: size}
+}
+
+// Intercept implements train.Interceptor interface
+func (l *HostLimiter) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+	v, ok := l.limiters.Load(req.URL.Host)
+	if !ok {
+		v, _ = l.limiters.LoadOrStore(req.URL.Host, NewLimiter(l.size))
+	}
+	return v.(Limiter).Intercept(chain)
+}
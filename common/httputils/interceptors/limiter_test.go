@@ -2,9 +2,12 @@ package interceptors
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/f2prateek/train"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -144,3 +147,39 @@ func Test_limiter_abort(t *testing.T) {
 
 	assert.EqualError(t, err, "context canceled")
 }
+
+func TestHostLimiter_Intercept(t *testing.T) {
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/block" {
+			<-release
+		}
+	})
+	ts1 := httptest.NewServer(handler)
+	defer ts1.Close()
+	ts2 := httptest.NewServer(handler)
+	defer ts2.Close()
+	defer close(release)
+
+	client := http.Client{Transport: train.Transport(NewHostLimiter(1))}
+
+	go func() {
+		resp, err := client.Get(ts1.URL + "/block")
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// The other hosts are not limited.
+	resp, err := client.Get(ts2.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts1.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
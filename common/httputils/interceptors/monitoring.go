@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/f2prateek/train"
@@ -48,7 +49,21 @@ func (m *Monitoring) Intercept(chain train.Chain) (*http.Response, error) {
 		tags = append(tags, fmt.Sprintf("status_class:%dxx", resp.StatusCode/100))
 	}
 
+	if fault := FaultInjected(resp, err); fault != "" {
+		// The faults are comma separated, as the DogStatsD tags: one tag is
+		// sent for each of them.
+		for _, f := range strings.Split(fault, ",") {
+			tags = append(tags, "fault_injected:"+f)
+		}
+	}
+
 	tags = append(tags, "target:"+req.URL.Host)
+	if attempt, ok := RetryAttempt(req.Context()); ok {
+		tags = append(tags, fmt.Sprintf("retry:%d", attempt))
+	}
+	if attempt, ok := HedgeAttempt(req.Context()); ok {
+		tags = append(tags, fmt.Sprintf("hedge:%d", attempt))
+	}
 	if m.RouteMatchers != nil {
 		for _, rm := range m.RouteMatchers {
 			if route, ok := rm.MatchRequest(req); ok {
@@ -65,5 +80,15 @@ func (m *Monitoring) Intercept(chain train.Chain) (*http.Response, error) {
 	m.Monitor.Timing("http.request.latency", time.Since(start), tags, 1)
 	m.Monitor.Count("http.request.count", 1, tags, 1)
 
+	if err == nil {
+		// The response body size is only known once it has been read.
+		resp.Body = &sizeRecorderBody{
+			ReadCloser: resp.Body,
+			record: func(size int64) {
+				m.Monitor.Histogram("http.response.size", float64(size), tags, 1)
+			},
+		}
+	}
+
 	return resp, err
 }
@@ -0,0 +1,224 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/f2prateek/train"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	otelnoop "go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/monorepo/common/monitoring"
+	"github.com/monorepo/common/monitoring/metrics"
+	"github.com/monorepo/common/monitoring/semconv"
+)
+
+// otelMeterName is the instrumentation name of the OTEL meter used by the
+// OTELMonitoring interceptor.
+const otelMeterName = "github.com/monorepo/common/httputils/interceptors"
+
+// OTELMonitoring is the OpenTelemetry flavour of the Monitoring interceptor.
+//
+// It records the http.client.* metrics of the OTEL semantic conventions.
+type OTELMonitoring struct {
+	RouteMatchers []RouteMatcher
+
+	duration       otelmetric.Float64Histogram
+	requestSize    otelmetric.Int64Histogram
+	responseSize   otelmetric.Int64Histogram
+	activeRequests otelmetric.Int64UpDownCounter
+}
+
+// NewOTELMonitoring instantiates a new OTELMonitoring interceptor.
+//
+// If mp is nil, the global meter provider is used (see
+// metrics.SetGlobalMeterProvider).
+func NewOTELMonitoring(mp metrics.MeterProvider, lrm ...RouteMatcher) *OTELMonitoring {
+	if mp == nil {
+		mp = metrics.GetGlobalMeterProvider()
+	}
+
+	meter := mp.Meter(otelMeterName, otelmetric.WithSchemaURL(semconv.SchemaURL))
+	noopMeter := otelnoop.Meter{}
+
+	m := &OTELMonitoring{
+		RouteMatchers: lrm,
+	}
+
+	var err error
+
+	m.duration, err = meter.Float64Histogram(
+		semconv.HTTPClientRequestDurationName,
+		otelmetric.WithUnit(semconv.HTTPClientRequestDurationUnit),
+		otelmetric.WithDescription(semconv.HTTPClientRequestDurationDescription),
+	)
+	if err != nil {
+		otel.Handle(err)
+		m.duration, _ = noopMeter.Float64Histogram(semconv.HTTPClientRequestDurationName)
+	}
+
+	m.requestSize, err = meter.Int64Histogram(
+		semconv.HTTPClientRequestBodySizeName,
+		otelmetric.WithUnit(semconv.HTTPClientRequestBodySizeUnit),
+		otelmetric.WithDescription(semconv.HTTPClientRequestBodySizeDescription),
+	)
+	if err != nil {
+		otel.Handle(err)
+		m.requestSize, _ = noopMeter.Int64Histogram(semconv.HTTPClientRequestBodySizeName)
+	}
+
+	m.responseSize, err = meter.Int64Histogram(
+		semconv.HTTPClientResponseBodySizeName,
+		otelmetric.WithUnit(semconv.HTTPClientResponseBodySizeUnit),
+		otelmetric.WithDescription(semconv.HTTPClientResponseBodySizeDescription),
+	)
+	if err != nil {
+		otel.Handle(err)
+		m.responseSize, _ = noopMeter.Int64Histogram(semconv.HTTPClientResponseBodySizeName)
+	}
+
+	m.activeRequests, err = meter.Int64UpDownCounter(
+		semconv.HTTPClientActiveRequestsName,
+		otelmetric.WithUnit(semconv.HTTPClientActiveRequestsUnit),
+		otelmetric.WithDescription(semconv.HTTPClientActiveRequestsDescription),
+	)
+	if err != nil {
+		otel.Handle(err)
+		m.activeRequests, _ = noopMeter.Int64UpDownCounter(semconv.HTTPClientActiveRequestsName)
+	}
+
+	return m
+}
+
+// Intercept implements the train.Interceptor interface
+func (m *OTELMonitoring) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+	ctx := req.Context()
+	start := time.Now()
+
+	serverAttrs := otelmetric.WithAttributes(m.serverAttributes(req)...)
+	m.activeRequests.Add(ctx, 1, serverAttrs)
+	defer m.activeRequests.Add(ctx, -1, serverAttrs)
+
+	resp, err := chain.Proceed(req)
+
+	attrs := append(m.serverAttributes(req), m.requestAttributes(req)...)
+	if err != nil {
+		attrs = append(attrs, semconv.ErrorTypeKey.String(errorType(ctx, err)))
+	} else {
+		attrs = append(attrs, semconv.HTTPResponseStatusCode(resp.StatusCode))
+		if resp.StatusCode >= http.StatusBadRequest {
+			attrs = append(attrs, semconv.ErrorTypeKey.String(strconv.Itoa(resp.StatusCode)))
+		}
+	}
+	if fault := FaultInjected(resp, err); fault != "" {
+		attrs = append(attrs, attribute.String(faultInjectedKey, fault))
+	}
+	set := otelmetric.WithAttributeSet(attribute.NewSet(attrs...))
+
+	m.duration.Record(ctx, time.Since(start).Seconds(), set)
+	if req.ContentLength > 0 {
+		m.requestSize.Record(ctx, req.ContentLength, set)
+	}
+
+	if err == nil && resp.StatusCode != http.StatusSwitchingProtocols {
+		// The response body size is only known once it has been read.
+		resp.Body = &sizeRecorderBody{
+			ReadCloser: resp.Body,
+			record: func(size int64) {
+				m.responseSize.Record(ctx, size, set)
+			},
+		}
+	}
+
+	return resp, err
+}
+
+// serverAttributes returns the attributes identifying the target server.
+func (m *OTELMonitoring) serverAttributes(req *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.ServerAddressKey.String(req.URL.Hostname()),
+		semconv.URLSchemeKey.String(req.URL.Scheme),
+	}
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		attrs = append(attrs, semconv.ServerPortKey.Int(port))
+	}
+	return attrs
+}
+
+// requestAttributes returns the route and context attributes of the request.
+func (m *OTELMonitoring) requestAttributes(req *http.Request) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+
+	for _, rm := range m.RouteMatchers {
+		if route, ok := rm.MatchRequest(req); ok {
+			attrs = append(attrs, semconv.HTTPRoute(route))
+			break
+		}
+	}
+
+	for k, v := range monitoring.GetTagsFromContext(req.Context()) {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	return attrs
+}
+
+// errorType returns a low cardinality error.type attribute value for a
+// request error.
+func errorType(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return "context_canceled"
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return "context_deadline_exceeded"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrConsentMissing):
+		return "consent_missing"
+	case errors.Is(err, ErrResponseTooLarge):
+		return "response_too_large"
+	case errors.Is(err, ErrRequestTooLarge):
+		return "request_too_large"
+	case errors.Is(err, ErrFaultInjected):
+		return "fault_injected"
+	default:
+		return semconv.ErrorTypeOther.Value.AsString()
+	}
+}
+
+// sizeRecorderBody counts the bytes read from a response body, and records
+// the total once the body is read or closed.
+type sizeRecorderBody struct {
+	io.ReadCloser
+	record func(size int64)
+
+	size int64
+	once sync.Once
+}
+
+// Read implements io.Reader.
+func (b *sizeRecorderBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if err == io.EOF {
+		b.once.Do(func() { b.record(b.size) })
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *sizeRecorderBody) Close() error {
+	b.once.Do(func() { b.record(b.size) })
+	return b.ReadCloser.Close()
+}
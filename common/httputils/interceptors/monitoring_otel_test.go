@@ -0,0 +1,85 @@
+package interceptors
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/monorepo/common/monitoring"
+	"github.com/monorepo/common/monitoring/semconv"
+)
+
+func TestOTELMonitoring_Intercept(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("Hello World"))
+	}))
+	defer ts.Close()
+
+	client := http.Client{Transport: train.Transport(
+		NewOTELMonitoring(mp, StaticRouteMatcher(http.MethodPost, "/users")),
+	)}
+
+	ctx := monitoring.AddTagsInContext(context.Background(), map[string]string{"caller": "test"})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/users", strings.NewReader("body"))
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	got := make(map[string]metricdata.Aggregation)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		got[m.Name] = m.Data
+	}
+
+	duration, ok := got[semconv.HTTPClientRequestDurationName].(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, duration.DataPoints, 1)
+	attrs := duration.DataPoints[0].Attributes
+	assertAttribute(t, attrs, semconv.HTTPRouteKey, attribute.StringValue("post:/users"))
+	assertAttribute(t, attrs, semconv.HTTPResponseStatusCodeKey, attribute.IntValue(http.StatusOK))
+	assertAttribute(t, attrs, semconv.HTTPRequestMethodKey, attribute.StringValue(http.MethodPost))
+	assertAttribute(t, attrs, "caller", attribute.StringValue("test"))
+
+	requestSize, ok := got[semconv.HTTPClientRequestBodySizeName].(metricdata.Histogram[int64])
+	require.True(t, ok)
+	require.Len(t, requestSize.DataPoints, 1)
+	assert.Equal(t, int64(4), requestSize.DataPoints[0].Sum)
+
+	responseSize, ok := got[semconv.HTTPClientResponseBodySizeName].(metricdata.Histogram[int64])
+	require.True(t, ok)
+	require.Len(t, responseSize.DataPoints, 1)
+	assert.Equal(t, int64(len("Hello World")), responseSize.DataPoints[0].Sum)
+
+	active, ok := got[semconv.HTTPClientActiveRequestsName].(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, active.DataPoints, 1)
+	assert.Equal(t, int64(0), active.DataPoints[0].Value)
+}
+
+func assertAttribute(t *testing.T, set attribute.Set, key attribute.Key, expected attribute.Value) {
+	t.Helper()
+
+	v, ok := set.Value(key)
+	if assert.True(t, ok, "missing attribute %s", key) {
+		assert.Equal(t, expected, v)
+	}
+}
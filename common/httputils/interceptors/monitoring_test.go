@@ -0,0 +1,53 @@
+package interceptors
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/monitoring/metrics"
+)
+
+// histogramStatsdHandler records the histogram values sent by name.
+type histogramStatsdHandler struct {
+	metrics.StatsdHandler
+
+	mu     sync.Mutex
+	values map[string][]float64
+}
+
+func (h *histogramStatsdHandler) Histogram(name string, value float64, tags []string, rate float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.values[name] = append(h.values[name], value)
+}
+
+func TestMonitoring_Intercept_response_size(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.WriteString(w, strings.Repeat("a", 42))
+	}))
+	defer ts.Close()
+
+	sh := &histogramStatsdHandler{
+		StatsdHandler: metrics.NoopStatsdHandler,
+		values:        make(map[string][]float64),
+	}
+	client := http.Client{Transport: train.Transport(NewMonitoring(sh))}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	assert.Empty(t, sh.values["http.response.size"])
+
+	_, err = io.Copy(io.Discard, resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, []float64{42}, sh.values["http.response.size"])
+}
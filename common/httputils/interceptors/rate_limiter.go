@@ -0,0 +1,309 @@
+package interceptors
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/f2prateek/train"
+
+	"github.com/monorepo/common/monitoring/metrics"
+)
+
+const (
+	// rateLimiterNever is the wait of a request which would never be sent,
+	// once the burst is exhausted with a non positive rate.
+	rateLimiterNever = time.Duration(math.MaxInt64)
+	// rateLimiterSweepInterval is the interval between two removals of the
+	// full buckets, which are the ones of the idle targets.
+	rateLimiterSweepInterval = time.Minute
+)
+
+// ErrRateLimited is the error returned when a request is not sent because it
+// would exceed the rate limit of its target.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimitedError is the error returned by the RateLimiter interceptor when
+// it rejects a request. Use errors.Is(err, ErrRateLimited) to detect it.
+type RateLimitedError struct {
+	// Key is the rate limited target host or route.
+	Key string
+	// Wait is the time the request should have waited to be sent.
+	Wait time.Duration
+}
+
+// Error implements the error interface.
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("%s for %s, would have waited %s", ErrRateLimited, e.Key, e.Wait)
+}
+
+// Is allows to match the error with ErrRateLimited.
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimiter is a HTTP client middleware limiting the rate of the requests
+// with a token bucket for each target host, or for each route when route
+// matchers are given.
+//
+// A request which can't be sent right away waits for its turn, unless the
+// wait would exceed the request context deadline: it then fails fast with a
+// RateLimitedError.
+//
+// The rate is also adjusted from the responses: the bucket is paused for the
+// Retry-After delay of the 429 and 503 responses, and drained according to the
+// X-RateLimit-Remaining and X-RateLimit-Reset headers.
+type RateLimiter struct {
+	rate          float64
+	burst         int
+	routeMatchers []RouteMatcher
+	failFast      bool
+	maxWait       time.Duration
+	adaptive      bool
+	monitor       metrics.StatsdHandler
+	now           func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket holds the available tokens at a point in time. This point in
+// time is in the future when the bucket is paused, or when tokens are
+// reserved by waiting requests (negative tokens).
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter instantiates a new RateLimiter interceptor allowing
+// requestsPerSecond requests per second for each target, with bursts of up
+// to burst requests. With a non positive rate, the requests beyond the burst
+// fail fast with a RateLimitedError.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:     requestsPerSecond,
+		burst:    burst,
+		adaptive: true,
+		monitor:  metrics.NoopStatsdHandler,
+		now:      time.Now,
+		buckets:  make(map[string]*tokenBucket),
+	}
+}
+
+// WithRouteMatchers limits the rate for each route instead of for each target
+// host. The requests matching none of the routes are limited by target host.
+func (r *RateLimiter) WithRouteMatchers(rms ...RouteMatcher) *RateLimiter {
+	r.routeMatchers = rms
+	return r
+}
+
+// WithFailFast makes the requests which can't be sent right away fail instead
+// of waiting.
+func (r *RateLimiter) WithFailFast() *RateLimiter {
+	r.failFast = true
+	return r
+}
+
+// WithMaxWait sets the maximum time a request may wait, including for
+// requests without context deadline.
+func (r *RateLimiter) WithMaxWait(d time.Duration) *RateLimiter {
+	r.maxWait = d
+	return r
+}
+
+// WithoutResponseHeaders disables the adjustment of the rate from the
+// responses headers.
+func (r *RateLimiter) WithoutResponseHeaders() *RateLimiter {
+	r.adaptive = false
+	return r
+}
+
+// WithMonitor sends the throttled waits and the rejected requests as metrics.
+func (r *RateLimiter) WithMonitor(sh metrics.StatsdHandler) *RateLimiter {
+	r.monitor = sh
+	return r
+}
+
+// Intercept implements the train.Interceptor interface
+func (r *RateLimiter) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+	ctx := req.Context()
+	key, tags := r.key(req)
+
+	wait := r.reserve(key)
+	if wait > 0 {
+		deadline, hasDeadline := ctx.Deadline()
+		if r.failFast || wait == rateLimiterNever || (r.maxWait > 0 && wait > r.maxWait) || (hasDeadline && deadline.Sub(r.now()) < wait) {
+			r.cancel(key)
+			r.monitor.Count("http.rate_limiter.rejected", 1, tags, 1)
+			return nil, &RateLimitedError{Key: key, Wait: wait}
+		}
+
+		r.monitor.Timing("http.rate_limiter.wait", wait, tags, 1)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			r.cancel(key)
+			return nil, fmt.Errorf("request interrupted while waiting for rate limit: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	resp, err := chain.Proceed(req)
+	if err == nil && r.adaptive {
+		r.adapt(key, resp)
+	}
+
+	return resp, err
+}
+
+// key returns the bucket key of the request, and the metrics tags.
+func (r *RateLimiter) key(req *http.Request) (string, []string) {
+	tags := []string{"target:" + req.URL.Host}
+	for _, rm := range r.routeMatchers {
+		if route, ok := rm.MatchRequest(req); ok {
+			return req.URL.Host + " " + route, append(tags, "route:"+route)
+		}
+	}
+	return req.URL.Host, tags
+}
+
+// bucket returns the bucket of the given key, created full if needed. It must
+// be called with the lock held.
+func (r *RateLimiter) bucket(key string, now time.Time) *tokenBucket {
+	b, ok := r.buckets[key]
+	if !ok {
+		r.sweep(now)
+		b = &tokenBucket{tokens: float64(r.burst), last: now}
+		r.buckets[key] = b
+	}
+	b.advance(now, r.rate, r.burst)
+	return b
+}
+
+// sweep removes the full buckets, at most once per rateLimiterSweepInterval:
+// they are the same as the new ones. It must be called with the lock held.
+func (r *RateLimiter) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < rateLimiterSweepInterval {
+		return
+	}
+	r.lastSweep = now
+
+	for key, b := range r.buckets {
+		b.advance(now, r.rate, r.burst)
+		if b.tokens >= float64(r.burst) && !b.last.After(now) {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+// reserve takes a token, and returns the time to wait for it to be
+// available.
+func (r *RateLimiter) reserve(key string) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	b := r.bucket(key, now)
+
+	var wait time.Duration
+	if b.last.After(now) {
+		wait = b.last.Sub(now)
+	}
+	b.tokens--
+	if b.tokens < 0 {
+		if r.rate <= 0 {
+			return rateLimiterNever
+		}
+		wait += time.Duration(-b.tokens / r.rate * float64(time.Second))
+	}
+	return wait
+}
+
+// cancel gives back a token taken by reserve.
+func (r *RateLimiter) cancel(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b, ok := r.buckets[key]; ok {
+		b.tokens = math.Min(b.tokens+1, float64(r.burst))
+	}
+}
+
+// adapt adjusts the bucket from the response headers.
+func (r *RateLimiter) adapt(key string, resp *http.Response) {
+	var pause time.Duration
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			pause = retryAfter
+		}
+	}
+
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	hasRemaining := err == nil && remaining >= 0
+	if hasRemaining && remaining == 0 {
+		if reset, ok := r.parseRateLimitReset(resp.Header.Get("X-RateLimit-Reset")); ok && reset > pause {
+			pause = reset
+		}
+	}
+
+	if pause == 0 && !hasRemaining {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	b := r.bucket(key, now)
+
+	if hasRemaining && float64(remaining) < b.tokens {
+		b.tokens = float64(remaining)
+	}
+	if pause > 0 {
+		b.tokens = math.Min(b.tokens, 0)
+		if until := now.Add(pause); until.After(b.last) {
+			b.last = until
+		}
+	}
+}
+
+// parseRateLimitReset parses a X-RateLimit-Reset header value, which is
+// either a number of seconds or a unix timestamp.
+func (r *RateLimiter) parseRateLimitReset(value string) (time.Duration, bool) {
+	reset, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || reset < 0 {
+		return 0, false
+	}
+
+	now := r.now()
+	// Delays are much smaller than the current unix timestamp.
+	if reset > now.Unix()/2 {
+		d := time.Unix(reset, 0).Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return time.Duration(reset) * time.Second, true
+}
+
+// advance adds the tokens earned since the last time.
+func (b *tokenBucket) advance(now time.Time, rate float64, burst int) {
+	if !now.After(b.last) {
+		return
+	}
+	b.tokens = math.Min(b.tokens+now.Sub(b.last).Seconds()*rate, float64(burst))
+	b.last = now
+}
@@ -0,0 +1,239 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_reserve(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	r := NewRateLimiter(10, 2)
+	r.now = clock.Now
+
+	assert.Equal(t, time.Duration(0), r.reserve("a"))
+	assert.Equal(t, time.Duration(0), r.reserve("a"))
+	assert.Equal(t, 100*time.Millisecond, r.reserve("a"))
+	assert.Equal(t, 200*time.Millisecond, r.reserve("a"))
+
+	// Other keys have their own bucket.
+	assert.Equal(t, time.Duration(0), r.reserve("b"))
+
+	r.cancel("a")
+	clock.Add(200 * time.Millisecond)
+	assert.Equal(t, time.Duration(0), r.reserve("a"))
+	assert.Equal(t, 100*time.Millisecond, r.reserve("a"))
+
+	// Never more tokens than burst.
+	clock.Add(time.Minute)
+	assert.Equal(t, time.Duration(0), r.reserve("a"))
+	assert.Equal(t, time.Duration(0), r.reserve("a"))
+	assert.Equal(t, 100*time.Millisecond, r.reserve("a"))
+}
+
+func TestRateLimiter_reserve_sweeps_full_buckets(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	r := NewRateLimiter(10, 2)
+	r.now = clock.Now
+
+	r.reserve("a")
+	r.reserve("b")
+	r.reserve("b")
+	r.reserve("b")
+	assert.Len(t, r.buckets, 2)
+
+	// The bucket of a is full again, the one of b is paused.
+	clock.Add(rateLimiterSweepInterval)
+	r.mu.Lock()
+	r.buckets["b"].last = clock.Now().Add(time.Minute)
+	r.mu.Unlock()
+	r.reserve("c")
+	assert.Len(t, r.buckets, 2)
+	assert.NotContains(t, r.buckets, "a")
+}
+
+func TestRateLimiter_Intercept_zero_rate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+
+	client := http.Client{Transport: train.Transport(NewRateLimiter(0, 1))}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	// The burst is exhausted, the request without deadline would never be
+	// sent.
+	_, err = client.Get(ts.URL)
+	assert.ErrorIs(t, err, ErrRateLimited)
+}
+
+func TestRateLimiter_Intercept_waits(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+
+	r := NewRateLimiter(50, 1)
+	client := http.Client{Transport: train.Transport(r)}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+}
+
+func TestRateLimiter_Intercept_rejects(t *testing.T) {
+	tests := map[string]struct {
+		limiter *RateLimiter
+		timeout time.Duration
+	}{
+		"fail fast": {
+			limiter: NewRateLimiter(1, 1).WithFailFast(),
+		},
+		"context deadline": {
+			limiter: NewRateLimiter(1, 1),
+			timeout: 100 * time.Millisecond,
+		},
+		"max wait": {
+			limiter: NewRateLimiter(1, 1).WithMaxWait(100 * time.Millisecond),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+			defer ts.Close()
+
+			sh := newCountStatsdHandler()
+			client := http.Client{Transport: train.Transport(tt.limiter.WithMonitor(sh))}
+
+			resp, err := client.Get(ts.URL)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+			require.NoError(t, err)
+
+			start := time.Now()
+			_, err = client.Do(req)
+			assert.Less(t, time.Since(start), 50*time.Millisecond)
+			assert.True(t, errors.Is(err, ErrRateLimited))
+
+			var rlErr *RateLimitedError
+			require.True(t, errors.As(err, &rlErr))
+			assert.Equal(t, ts.Listener.Addr().String(), rlErr.Key)
+			assert.Equal(t, int64(1), sh.get("http.rate_limiter.rejected "))
+		})
+	}
+}
+
+func TestRateLimiter_Intercept_by_route(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+
+	r := NewRateLimiter(1, 1).
+		WithFailFast().
+		WithRouteMatchers(
+			StaticRouteMatcher(http.MethodGet, "/a"),
+			DynamicRouteMatcher(http.MethodGet, "/b/{id}", regexp.MustCompile(`^/b/\d+$`)),
+		)
+	client := http.Client{Transport: train.Transport(r)}
+
+	for _, path := range []string{"/a", "/b/1", "/c"} {
+		resp, err := client.Get(ts.URL + path)
+		require.NoError(t, err, path)
+		_ = resp.Body.Close()
+	}
+
+	for _, path := range []string{"/a", "/b/2", "/d"} {
+		_, err := client.Get(ts.URL + path)
+		assert.True(t, errors.Is(err, ErrRateLimited), path)
+	}
+}
+
+func TestRateLimiter_Intercept_adapts_to_response_headers(t *testing.T) {
+	tests := map[string]struct {
+		status   int
+		header   map[string]string
+		wantWait time.Duration
+	}{
+		"retry after": {
+			status:   http.StatusTooManyRequests,
+			header:   map[string]string{"Retry-After": "10"},
+			wantWait: 10 * time.Second,
+		},
+		"rate limit reset delay": {
+			status:   http.StatusOK,
+			header:   map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "20"},
+			wantWait: 20 * time.Second,
+		},
+		"rate limit reset timestamp": {
+			status: http.StatusOK,
+			header: map[string]string{
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10),
+			},
+			wantWait: 30 * time.Second,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			r := NewRateLimiter(100, 10).WithFailFast()
+			client := http.Client{Transport: train.Transport(r)}
+
+			resp, err := client.Get(ts.URL)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			_, err = client.Get(ts.URL)
+			var rlErr *RateLimitedError
+			require.True(t, errors.As(err, &rlErr))
+			assert.InDelta(t, tt.wantWait, rlErr.Wait, float64(time.Second))
+		})
+	}
+}
+
+func TestRateLimiter_Intercept_remaining_drains_bucket(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "1")
+	}))
+	defer ts.Close()
+
+	r := NewRateLimiter(1, 10).WithFailFast()
+	client := http.Client{Transport: train.Transport(r)}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	_, err := client.Get(ts.URL)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+}
@@ -0,0 +1,279 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/f2prateek/train"
+)
+
+// retryDrainMaxSize is the maximum number of bytes read from the body of
+// a discarded response, to allow the reuse of its connection.
+const retryDrainMaxSize = 4 << 10
+
+// DefaultRetryStatusCodes is the list of HTTP status codes retried by default
+// by the Retry interceptor.
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Retry is a HTTP client middleware to retry requests on transient failures.
+//
+// A request is retried on transient connection errors (see IsTransientError)
+// and on the configured status codes, as long as its method is idempotent (see
+// WithNonIdempotentMethods) and its body can be rewound with
+// http.Request.GetBody.
+// The delay between two attempts is given by the backoff, unless the response
+// carries a Retry-After header.
+type Retry struct {
+	backoff          []time.Duration
+	statusCodes      map[int]struct{}
+	nonIdempotent    bool
+	maxRetryAfter    time.Duration
+	ignoreRetryAfter bool
+
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetry instantiates a new Retry interceptor.
+//
+// The backoff defines both the number of retries and the delay to wait before
+// each of them, e.g. retrierx.LinearBackoff(3, 100*time.Millisecond) or
+// retrier.ExponentialBackoff(3, 100*time.Millisecond).
+func NewRetry(backoff []time.Duration) *Retry {
+	r := &Retry{
+		backoff:       backoff,
+		maxRetryAfter: 30 * time.Second,
+		sleep:         waitContext,
+	}
+	return r.WithStatusCodes(DefaultRetryStatusCodes...)
+}
+
+// WithStatusCodes sets the HTTP status codes which trigger a retry.
+// It replaces the DefaultRetryStatusCodes.
+func (r *Retry) WithStatusCodes(codes ...int) *Retry {
+	r.statusCodes = make(map[int]struct{}, len(codes))
+	for _, code := range codes {
+		r.statusCodes[code] = struct{}{}
+	}
+	return r
+}
+
+// WithNonIdempotentMethods allows to retry requests whose method is not
+// idempotent (e.g. POST, PATCH).
+// Use it only if the called endpoints are safe to call several times.
+func (r *Retry) WithNonIdempotentMethods() *Retry {
+	r.nonIdempotent = true
+	return r
+}
+
+// WithMaxRetryAfter sets the maximum delay honoured from a Retry-After
+// response header. A longer delay makes the interceptor give up and return
+// the response as is. Default is 30s.
+func (r *Retry) WithMaxRetryAfter(d time.Duration) *Retry {
+	r.maxRetryAfter = d
+	return r
+}
+
+// WithoutRetryAfter disables the Retry-After response header handling, the
+// backoff is always used.
+func (r *Retry) WithoutRetryAfter() *Retry {
+	r.ignoreRetryAfter = true
+	return r
+}
+
+// Intercept implements the train.Interceptor interface
+func (r *Retry) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+	ctx := req.Context()
+
+	if !r.isRetryable(req) {
+		return chain.Proceed(req.WithContext(context.WithValue(ctx, retryAttemptCtxKey, 0)))
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq, err := r.newAttemptRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := chain.Proceed(attemptReq)
+		if attempt >= len(r.backoff) || !r.shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		wait, ok := r.delay(ctx, attempt, resp)
+		if !ok {
+			return resp, err
+		}
+
+		if resp != nil {
+			discard(resp)
+		}
+
+		if err := r.sleep(ctx, wait); err != nil {
+			return nil, fmt.Errorf("request interrupted while waiting for retry: %w", err)
+		}
+	}
+}
+
+// isRetryable reports whether the request may be sent more than once.
+func (r *Retry) isRetryable(req *http.Request) bool {
+	if len(r.backoff) == 0 {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	return r.nonIdempotent || isIdempotent(req)
+}
+
+// newAttemptRequest returns a copy of the original request, with a rewound
+// body, for the given attempt.
+//
+// The original request is never sent as is, as some interceptors (e.g.
+// QueryObfuscator) modify the request once it has been sent.
+func (r *Retry) newAttemptRequest(req *http.Request, attempt int) (*http.Request, error) {
+	ctx := context.WithValue(req.Context(), retryAttemptCtxKey, attempt)
+	attemptReq := req.Clone(ctx)
+
+	if attempt == 0 || req.GetBody == nil {
+		return attemptReq, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("rewind body for retry: %w", err)
+	}
+	attemptReq.Body = body
+
+	return attemptReq, nil
+}
+
+func (r *Retry) shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return IsTransientError(err)
+	}
+	_, ok := r.statusCodes[resp.StatusCode]
+	return ok
+}
+
+// IsTransientError reports whether the error of a request is a transient
+// connection error, which may not happen again if the request is retried:
+// connection refused or reset, connection closed before the end of the
+// response, or network timeout. The connection failures injected by the
+// FaultInjection interceptor are transient too, to test the retries.
+//
+// The errors of the interceptors (e.g. ErrCircuitOpen, ErrRateLimited or a
+// signing failure) are not transient.
+func IsTransientError(err error) bool {
+	if errors.Is(err, ErrFaultInjected) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// waitContext waits for the given duration, or until the context is done.
+func waitContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// delay returns the time to wait before the next attempt. The boolean is
+// false if the next attempt would not be possible before the end of the
+// request context.
+func (r *Retry) delay(ctx context.Context, attempt int, resp *http.Response) (time.Duration, bool) {
+	wait := r.backoff[attempt]
+
+	if resp != nil && !r.ignoreRetryAfter {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if retryAfter > r.maxRetryAfter {
+				return 0, false
+			}
+			wait = retryAfter
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		return 0, false
+	}
+
+	return wait, true
+}
+
+// parseRetryAfter parses a Retry-After header value, which is either a
+// number of seconds or a HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	d := time.Until(date)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// isIdempotent reports whether the request method is idempotent as defined by
+// RFC 9110, or whether the request carries an idempotency key.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	_, hasIdempotencyKey := req.Header["Idempotency-Key"]
+	_, hasXIdempotencyKey := req.Header["X-Idempotency-Key"]
+	return hasIdempotencyKey || hasXIdempotencyKey
+}
+
+// discard reads a bit of the response body, to allow the reuse of the
+// connection, then closes it.
+func discard(resp *http.Response) {
+	_, _ = io.CopyN(io.Discard, resp.Body, retryDrainMaxSize)
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,330 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry_Intercept(t *testing.T) {
+	backoff := []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
+
+	t.Run("retries on configured status codes", func(t *testing.T) {
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		client := http.Client{Transport: train.Transport(NewRetry(backoff))}
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("gives up after the backoff is exhausted", func(t *testing.T) {
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer ts.Close()
+
+		client := http.Client{Transport: train.Transport(NewRetry(backoff))}
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		assert.EqualValues(t, 4, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("does not retry other status codes", func(t *testing.T) {
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer ts.Close()
+
+		client := http.Client{Transport: train.Transport(NewRetry(backoff))}
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("does not retry non idempotent methods by default", func(t *testing.T) {
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		client := http.Client{Transport: train.Transport(NewRetry(backoff))}
+		req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader("body"))
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("rewinds the body of non idempotent methods when allowed", func(t *testing.T) {
+		var bodies []string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			b, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(b))
+			if len(bodies) < 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer ts.Close()
+
+		client := http.Client{Transport: train.Transport(NewRetry(backoff).WithNonIdempotentMethods())}
+		req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader("body"))
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"body", "body"}, bodies)
+	})
+
+	t.Run("honours Retry-After", func(t *testing.T) {
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.Header().Set("Retry-After", "3600")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		}))
+		defer ts.Close()
+
+		client := http.Client{Transport: train.Transport(NewRetry(backoff))}
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// Retry-After exceeds the maximum, the response is returned as is.
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("waits for a valid Retry-After instead of the backoff", func(t *testing.T) {
+		retryAfters := map[string]struct {
+			value string
+			min   time.Duration
+			max   time.Duration
+		}{
+			"seconds": {value: "1", min: time.Second, max: time.Second},
+			"date": {
+				value: time.Now().Add(3 * time.Second).UTC().Format(http.TimeFormat),
+				// The HTTP date has a one second precision.
+				min: time.Second,
+				max: 3 * time.Second,
+			},
+		}
+
+		for name, tt := range retryAfters {
+			t.Run(name, func(t *testing.T) {
+				var calls int32
+				ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					if atomic.AddInt32(&calls, 1) == 1 {
+						w.Header().Set("Retry-After", tt.value)
+						w.WriteHeader(http.StatusServiceUnavailable)
+					}
+				}))
+				defer ts.Close()
+
+				var waits []time.Duration
+				retry := NewRetry(backoff)
+				retry.sleep = func(_ context.Context, d time.Duration) error {
+					waits = append(waits, d)
+					return nil
+				}
+				client := http.Client{Transport: train.Transport(retry)}
+
+				resp, err := client.Get(ts.URL)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				require.Len(t, waits, 1)
+				assert.GreaterOrEqual(t, waits[0], tt.min)
+				assert.LessOrEqual(t, waits[0], tt.max)
+			})
+		}
+	})
+
+	t.Run("retries transient errors only", func(t *testing.T) {
+		errs := map[string]struct {
+			err       error
+			wantCalls int32
+		}{
+			"connection refused": {
+				err:       &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+				wantCalls: 2,
+			},
+			"connection reset": {
+				err:       &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)},
+				wantCalls: 2,
+			},
+			"unexpected EOF": {
+				err:       fmt.Errorf("read response: %w", io.ErrUnexpectedEOF),
+				wantCalls: 2,
+			},
+			"timeout": {
+				err:       &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded},
+				wantCalls: 2,
+			},
+			"interceptor error": {
+				err:       errors.New("sign the request: missing credentials"),
+				wantCalls: 1,
+			},
+			"circuit open": {
+				err:       &CircuitOpenError{Name: "test", Target: "localhost"},
+				wantCalls: 1,
+			},
+			"fault injected": {
+				err:       &FaultInjectedError{Host: "localhost"},
+				wantCalls: 2,
+			},
+		}
+
+		for name, tt := range errs {
+			t.Run(name, func(t *testing.T) {
+				ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+				defer ts.Close()
+
+				var calls int32
+				failOnce := train.InterceptorFunc(func(chain train.Chain) (*http.Response, error) {
+					if atomic.AddInt32(&calls, 1) == 1 {
+						return nil, tt.err
+					}
+					return chain.Proceed(chain.Request())
+				})
+				client := http.Client{Transport: train.Transport(NewRetry(backoff), failOnce)}
+
+				resp, err := client.Get(ts.URL)
+				if tt.wantCalls == 1 {
+					assert.Error(t, err)
+				} else {
+					require.NoError(t, err)
+					_ = resp.Body.Close()
+				}
+				assert.Equal(t, tt.wantCalls, atomic.LoadInt32(&calls))
+			})
+		}
+	})
+
+	t.Run("retries injected connection failures", func(t *testing.T) {
+		var calls int32
+		counter := train.InterceptorFunc(func(chain train.Chain) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return chain.Proceed(chain.Request())
+		})
+		f, _ := newTestFaultInjection(FaultRule{Percentage: 100, Error: true})
+		client := http.Client{Transport: train.Transport(NewRetry(backoff), counter, f)}
+
+		_, err := client.Get("http://example.com")
+		assert.ErrorIs(t, err, ErrFaultInjected)
+		assert.EqualValues(t, 4, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("sets the attempt in the request context", func(t *testing.T) {
+		var attempts []int
+		recorder := train.InterceptorFunc(func(chain train.Chain) (*http.Response, error) {
+			attempt, ok := RetryAttempt(chain.Request().Context())
+			require.True(t, ok)
+			attempts = append(attempts, attempt)
+			return chain.Proceed(chain.Request())
+		})
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}))
+		defer ts.Close()
+
+		client := http.Client{Transport: train.Transport(NewRetry(backoff), recorder)}
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, []int{0, 1, 2, 3}, attempts)
+	})
+
+	t.Run("stops waiting when the context is done", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		client := http.Client{Transport: train.Transport(NewRetry([]time.Duration{time.Hour}))}
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+
+		time.AfterFunc(10*time.Millisecond, cancel)
+		_, err = client.Do(req)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	d, ok := parseRetryAfter("2")
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, d)
+
+	d, ok = parseRetryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), d)
+
+	_, ok = parseRetryAfter("soon")
+	assert.False(t, ok)
+
+	_, ok = parseRetryAfter("")
+	assert.False(t, ok)
+}
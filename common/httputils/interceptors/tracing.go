@@ -2,6 +2,7 @@ package interceptors
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -15,6 +16,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 
+	"github.com/monorepo/common/monitoring/semconv"
 	"github.com/monorepo/common/secret"
 )
 
@@ -65,6 +67,9 @@ func (t *Tracing) Intercept(chain train.Chain) (*http.Response, error) {
 	span.SetTag("http.host", req.Host)
 	span.SetTag("http.path", req.URL.Path)
 	span.SetTag("http.queryparams", t.sqp.HideFromValues(req.URL.Query()).Encode())
+	if attempt, ok := RetryAttempt(req.Context()); ok && attempt > 0 {
+		span.SetTag(string(semconv.HTTPRequestResendCountKey), attempt)
+	}
 
 	if !strings.Contains(req.Host, "svc.cluster.local") && !strings.Contains(req.Host, "svc.disco") {
 		span.SetTag(ext.ServiceName, req.Host)
@@ -75,10 +80,44 @@ func (t *Tracing) Intercept(chain train.Chain) (*http.Response, error) {
 		span.SetTag("http.request_body", requestBodyTagValue)
 	}
 
+	req = req.WithContext(withAttemptTracer(ctx, newDatadogClientTrace))
+
+	err := tracer.Inject(span.Context(), tracer.HTTPHeadersCarrier(req.Header))
+	if err != nil {
+		span.SetTag("tracer_inject_error", err)
+	}
+
+	resp, err := chain.Proceed(req)
+	if fault := FaultInjected(resp, err); fault != "" {
+		span.SetTag(faultInjectedKey, fault)
+	}
+	if err != nil {
+		if errContext := ctx.Err(); errContext != nil {
+			span.SetTag(ext.Error, errContext)
+		} else {
+			span.SetTag(ext.Error, err)
+		}
+	} else {
+		span.SetTag(ext.HTTPCode, resp.StatusCode)
+		if resp.TLS != nil {
+			span.SetTag(string(semconv.TLSProtocolVersionKey), tlsProtocolVersion(resp.TLS))
+			span.SetTag(string(semconv.TLSCipherKey), tls.CipherSuiteName(resp.TLS.CipherSuite))
+		}
+		if resp.StatusCode >= 500 {
+			span.SetTag(ext.Error, errors.New("status code 5XX"))
+		}
+	}
+
+	return resp, err
+}
+
+// newDatadogClientTrace returns a ClientTrace creating the DNS, connect and
+// TLS child spans of the span of the given context.
+func newDatadogClientTrace(ctx context.Context) *httptrace.ClientTrace {
 	var dnsSpan tracer.Span
 	var connectSpan tracer.Span
 	var tlsSpan tracer.Span
-	trace := &httptrace.ClientTrace{
+	return &httptrace.ClientTrace{
 		DNSStart: func(dnsInfo httptrace.DNSStartInfo) {
 			dnsSpan, _ = tracer.StartSpanFromContext(ctx, "dns.resolution", tracer.SpanType("dns"))
 		},
@@ -101,28 +140,12 @@ func (t *Tracing) Intercept(chain train.Chain) (*http.Response, error) {
 			tlsSpan.Finish(tracer.WithError(err))
 		},
 	}
-	req = req.WithContext(httptrace.WithClientTrace(ctx, trace))
-
-	err := tracer.Inject(span.Context(), tracer.HTTPHeadersCarrier(req.Header))
-	if err != nil {
-		span.SetTag("tracer_inject_error", err)
-	}
-
-	resp, err := chain.Proceed(req)
-	if err != nil {
-		if errContext := ctx.Err(); errContext != nil {
-			span.SetTag(ext.Error, errContext)
-		} else {
-			span.SetTag(ext.Error, err)
-		}
-	} else {
-		span.SetTag(ext.HTTPCode, resp.StatusCode)
-		if resp.StatusCode >= 500 {
-			span.SetTag(ext.Error, errors.New("status code 5XX"))
-		}
-	}
+}
 
-	return resp, err
+// tlsProtocolVersion returns the negotiated TLS version without the protocol
+// name, e.g. "1.3".
+func tlsProtocolVersion(state *tls.ConnectionState) string {
+	return strings.TrimPrefix(tls.VersionName(state.Version), "TLS ")
 }
 
 func (t *Tracing) getRequestBodyTagValue(req *http.Request) string {
@@ -0,0 +1,196 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+
+	"github.com/f2prateek/train"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/monorepo/common/monitoring/semconv"
+	"github.com/monorepo/common/monitoring/tracing"
+	"github.com/monorepo/common/secret"
+)
+
+// otelTracerName is the instrumentation name of the OTEL tracer used by the
+// OTELTracing interceptor.
+const otelTracerName = "github.com/monorepo/common/httputils/interceptors"
+
+// OTELTracing is the OpenTelemetry flavour of the Tracing interceptor.
+//
+// It creates a client span for each request, following the OTEL semantic
+// conventions, and injects the trace context in the request headers.
+type OTELTracing struct {
+	sqp        secret.QueryParams
+	tp         tracing.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// NewOTELTracing instantiates a new OTEL tracing interceptor.
+//
+// By default, spans are created from the global tracer provider (see
+// tracing.SetGlobalTracerProvider) and the W3C trace context and baggage are
+// propagated.
+func NewOTELTracing() *OTELTracing {
+	return &OTELTracing{
+		sqp: []string{},
+		propagator: propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		),
+	}
+}
+
+// WithSecretQueryParams sets the names of the query params whose value won't be
+// sent in span attributes. E.g. for api keys sent as query params.
+func (t *OTELTracing) WithSecretQueryParams(names ...string) *OTELTracing {
+	t.sqp = names
+	return t
+}
+
+// WithTracerProvider sets the tracer provider used to create the spans,
+// instead of the global one.
+func (t *OTELTracing) WithTracerProvider(tp tracing.TracerProvider) *OTELTracing {
+	t.tp = tp
+	return t
+}
+
+// WithPropagator sets the propagator used to inject the trace context in the
+// request headers.
+func (t *OTELTracing) WithPropagator(p propagation.TextMapPropagator) *OTELTracing {
+	t.propagator = p
+	return t
+}
+
+// Intercept implements the train.Interceptor interface for the tracing
+func (t *OTELTracing) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+
+	tracer := t.tracer()
+
+	ctx, span := tracer.Start(req.Context(), req.Method,
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(t.requestAttributes(req)...),
+	)
+	defer span.End()
+
+	req = req.WithContext(withAttemptTracer(ctx, func(ctx context.Context) *httptrace.ClientTrace {
+		return newOTELClientTrace(ctx, tracer)
+	}))
+
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := chain.Proceed(req)
+	if fault := FaultInjected(resp, err); fault != "" {
+		span.SetAttributes(attribute.String(faultInjectedKey, fault))
+	}
+	if err != nil {
+		spanErr := err
+		if errContext := ctx.Err(); errContext != nil {
+			spanErr = errContext
+		}
+		span.RecordError(spanErr)
+		span.SetStatus(codes.Error, spanErr.Error())
+		return resp, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.TLS != nil {
+		span.SetAttributes(
+			semconv.TLSProtocolNameTLS,
+			semconv.TLSProtocolVersion(tlsProtocolVersion(resp.TLS)),
+			semconv.TLSCipher(tls.CipherSuiteName(resp.TLS.CipherSuite)),
+		)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		// Client spans are in error for both 4xx and 5xx status codes.
+		span.SetStatus(codes.Error, fmt.Sprintf("status code %d", resp.StatusCode))
+	}
+
+	return resp, nil
+}
+
+func (t *OTELTracing) tracer() oteltrace.Tracer {
+	opts := []oteltrace.TracerOption{
+		oteltrace.WithSchemaURL(semconv.SchemaURL),
+	}
+	if t.tp != nil {
+		return t.tp.Tracer(otelTracerName, opts...)
+	}
+	return tracing.Tracer(otelTracerName, opts...)
+}
+
+func (t *OTELTracing) requestAttributes(req *http.Request) []attribute.KeyValue {
+	u := *req.URL
+	u.RawQuery = t.sqp.HideFromValues(req.URL.Query()).Encode()
+
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.URLFullKey.String(u.Redacted()),
+		semconv.ServerAddressKey.String(req.URL.Hostname()),
+	}
+
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		attrs = append(attrs, semconv.ServerPortKey.Int(port))
+	}
+
+	if attempt, ok := RetryAttempt(req.Context()); ok && attempt > 0 {
+		attrs = append(attrs, semconv.HTTPRequestResendCountKey.Int(attempt))
+	}
+
+	return attrs
+}
+
+// newOTELClientTrace returns a ClientTrace creating the DNS, connect and TLS
+// child spans of the span of the given context.
+func newOTELClientTrace(ctx context.Context, tracer oteltrace.Tracer) *httptrace.ClientTrace {
+	var dnsSpan, connectSpan, tlsSpan oteltrace.Span
+	return &httptrace.ClientTrace{
+		DNSStart: func(dnsInfo httptrace.DNSStartInfo) {
+			_, dnsSpan = tracer.Start(ctx, "dns.resolution")
+		},
+		DNSDone: func(dnsInfo httptrace.DNSDoneInfo) {
+			if dnsSpan == nil {
+				return
+			}
+			addrs := make([]string, len(dnsInfo.Addrs))
+			for i, addr := range dnsInfo.Addrs {
+				addrs[i] = addr.String()
+			}
+			dnsSpan.SetAttributes(attribute.StringSlice("dns.addrs", addrs))
+			endSpanWithError(dnsSpan, dnsInfo.Err)
+		},
+
+		ConnectStart: func(network, addr string) {
+			_, connectSpan = tracer.Start(ctx, "http.connect")
+		},
+		ConnectDone: func(_, _ string, err error) {
+			endSpanWithError(connectSpan, err)
+		},
+
+		TLSHandshakeStart: func() {
+			_, tlsSpan = tracer.Start(ctx, "http.tls")
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			endSpanWithError(tlsSpan, err)
+		},
+	}
+}
+
+func endSpanWithError(span oteltrace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,155 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/monorepo/common/monitoring/semconv"
+)
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestOTELTracing_Intercept(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	var traceparent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		traceparent = req.Header.Get("traceparent")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := http.Client{Transport: train.Transport(
+		NewOTELTracing().
+			WithTracerProvider(tp).
+			WithSecretQueryParams("apikey"),
+	)}
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	u.Path = "/path"
+	u.RawQuery = url.Values{"apikey": []string{"secret"}, "foo": []string{"bar"}}.Encode()
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	parent.End()
+
+	var span sdktrace.ReadOnlySpan
+	for _, s := range sr.Ended() {
+		if s.SpanKind() == oteltrace.SpanKindClient {
+			span = s
+		}
+	}
+	require.NotNil(t, span)
+
+	assert.Equal(t, http.MethodGet, span.Name())
+	assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+	assert.Contains(t, traceparent, span.SpanContext().SpanID().String())
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	attrs := spanAttributes(span)
+	assert.Equal(t, http.MethodGet, attrs[semconv.HTTPRequestMethodKey].AsString())
+	assert.Equal(t, u.Hostname(), attrs[semconv.ServerAddressKey].AsString())
+	assert.EqualValues(t, http.StatusServiceUnavailable, attrs[semconv.HTTPResponseStatusCodeKey].AsInt64())
+	assert.NotContains(t, attrs[semconv.URLFullKey].AsString(), "secret")
+	assert.Contains(t, attrs[semconv.URLFullKey].AsString(), "foo=bar")
+}
+
+func TestOTELTracing_Intercept_with_tls(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+
+	client := http.Client{Transport: train.TransportWith(
+		ts.Client().Transport,
+		NewOTELTracing().WithTracerProvider(tp),
+	)}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	var span sdktrace.ReadOnlySpan
+	for _, s := range sr.Ended() {
+		if s.SpanKind() == oteltrace.SpanKindClient {
+			span = s
+		}
+	}
+	require.NotNil(t, span)
+
+	attrs := spanAttributes(span)
+	assert.Equal(t, "tls", attrs[semconv.TLSProtocolNameKey].AsString())
+	assert.Equal(t, "1.3", attrs[semconv.TLSProtocolVersionKey].AsString())
+	assert.Equal(t, tls.CipherSuiteName(resp.TLS.CipherSuite), attrs[semconv.TLSCipherKey].AsString())
+}
+
+func TestOTELTracing_Intercept_with_retries(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	client := http.Client{Transport: train.Transport(
+		NewRetry([]time.Duration{0}),
+		NewOTELTracing().WithTracerProvider(tp),
+	)}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	var resendCounts []int64
+	for _, s := range sr.Ended() {
+		if s.SpanKind() != oteltrace.SpanKindClient {
+			continue
+		}
+		resendCounts = append(resendCounts, spanAttributes(s)[semconv.HTTPRequestResendCountKey].AsInt64())
+	}
+	assert.Equal(t, []int64{0, 1}, resendCounts)
+}
+
+func Test_newOTELClientTrace_done_without_start(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	trace := newOTELClientTrace(context.Background(), tp.Tracer("test"))
+
+	assert.NotPanics(t, func() {
+		trace.DNSDone(httptrace.DNSDoneInfo{})
+		trace.ConnectDone("tcp", "127.0.0.1:80", nil)
+		trace.TLSHandshakeDone(tls.ConnectionState{}, nil)
+	})
+}
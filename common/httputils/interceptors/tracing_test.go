@@ -2,6 +2,7 @@ package interceptors
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"math/rand"
@@ -50,6 +51,26 @@ func TestTracing_Intercept(t *testing.T) {
 	require.Len(t, spans, 2)
 }
 
+func TestTracing_Intercept_with_tls(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+
+	client := http.Client{Transport: train.TransportWith(ts.Client().Transport, NewTracing())}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	spans := mt.FinishedSpans()
+	require.NotEmpty(t, spans)
+	span := spans[len(spans)-1]
+	assert.Equal(t, "1.3", span.Tag("tls.protocol.version"))
+	assert.Equal(t, tls.CipherSuiteName(resp.TLS.CipherSuite), span.Tag("tls.cipher"))
+}
+
 func TestTracing_Intercept_with_a_custom_resourceNameFunc(t *testing.T) {
 	client := new(http.Client)
 
@@ -0,0 +1,53 @@
+package interceptors
+
+import (
+	"net/http"
+
+	"github.com/f2prateek/train"
+	"github.com/google/uuid"
+
+	"github.com/monorepo/common/contextkeys"
+	"github.com/monorepo/common/httputils/polarisheaders"
+)
+
+// DefaultUniqueIDHeader is the default header carrying the request unique ID.
+const DefaultUniqueIDHeader = polarisheaders.UniqueID
+
+// UniqueID propagates the request unique ID in a header, to correlate the
+// requests between services.
+//
+// The unique ID is taken from the contextkeys.UniqueID value of the request
+// context, or generated if missing. A header already set on the request is
+// kept as is.
+type UniqueID struct {
+	header string
+}
+
+// NewUniqueID instantiates an UniqueID interceptor, setting the
+// DefaultUniqueIDHeader header.
+func NewUniqueID() *UniqueID {
+	return &UniqueID{
+		header: DefaultUniqueIDHeader,
+	}
+}
+
+// WithHeader sets the name of the header carrying the unique ID.
+func (u *UniqueID) WithHeader(name string) *UniqueID {
+	u.header = http.CanonicalHeaderKey(name)
+	return u
+}
+
+// Intercept implements the train.Interceptor interface
+func (u *UniqueID) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+
+	if req.Header.Get(u.header) == "" {
+		id, ok := req.Context().Value(contextkeys.UniqueID).(string)
+		if !ok || id == "" {
+			id = uuid.NewString()
+		}
+		req.Header.Set(u.header, id)
+	}
+
+	return chain.Proceed(req)
+}
@@ -0,0 +1,85 @@
+package interceptors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/f2prateek/train"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/contextkeys"
+)
+
+func TestUniqueID_Intercept(t *testing.T) {
+	tests := map[string]struct {
+		interceptor *UniqueID
+		header      string
+		ctx         context.Context
+		reqHeader   string
+		want        string
+	}{
+		"from context": {
+			interceptor: NewUniqueID(),
+			header:      "X-Unique-Id",
+			ctx:         context.WithValue(context.Background(), contextkeys.UniqueID, "id-from-ctx"),
+			want:        "id-from-ctx",
+		},
+		"already set": {
+			interceptor: NewUniqueID(),
+			header:      "X-Unique-Id",
+			ctx:         context.WithValue(context.Background(), contextkeys.UniqueID, "id-from-ctx"),
+			reqHeader:   "id-from-header",
+			want:        "id-from-header",
+		},
+		"custom header": {
+			interceptor: NewUniqueID().WithHeader("x-correlation-id"),
+			header:      "X-Correlation-Id",
+			ctx:         context.WithValue(context.Background(), contextkeys.UniqueID, "id-from-ctx"),
+			want:        "id-from-ctx",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				got = req.Header.Get(tt.header)
+			}))
+			defer ts.Close()
+
+			client := http.Client{Transport: train.Transport(tt.interceptor)}
+
+			req, err := http.NewRequestWithContext(tt.ctx, http.MethodGet, ts.URL, nil)
+			require.NoError(t, err)
+			if tt.reqHeader != "" {
+				req.Header.Set(tt.header, tt.reqHeader)
+			}
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUniqueID_Intercept_generates_id(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Get(DefaultUniqueIDHeader)
+	}))
+	defer ts.Close()
+
+	client := http.Client{Transport: train.Transport(NewUniqueID())}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	_, err = uuid.Parse(got)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,248 @@
+package httputils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"github.com/monorepo/common/problem"
+)
+
+// httpErrorBodyMaxSize is the maximum size of the response body kept in an
+// HTTPError.
+const httpErrorBodyMaxSize = 4 << 10
+
+// HTTPError is the error returned by the JSON helpers (GetJSON, PostJSON,
+// ...) when the response status code is >= 400.
+type HTTPError struct {
+	StatusCode int
+	// Header is the response header. It is nil when the Doer does not give
+	// access to it (e.g. MockClient).
+	Header http.Header
+	// Body is the beginning of the response body, truncated to 4KiB.
+	Body []byte
+	// Payload is the decoded response body, if an error payload type is
+	// given with WithErrorPayload. It is a pointer to this type.
+	Payload interface{}
+	// Problem is the decoded response body, if it is an
+	// application/problem+json one.
+	Problem *problem.Problem
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	if e.Problem != nil {
+		return fmt.Sprintf("http status %d: %s", e.StatusCode, e.Problem.Error())
+	}
+	return fmt.Sprintf("http status %d: body %q", e.StatusCode, e.Body)
+}
+
+// Unwrap returns the decoded problem, if any, so that it can be retrieved
+// with errors.As.
+func (e *HTTPError) Unwrap() error {
+	if e.Problem == nil {
+		return nil
+	}
+	return e.Problem
+}
+
+// JSONOption customizes the requests sent by the JSON helpers.
+type JSONOption func(*jsonRequest)
+
+type jsonRequest struct {
+	header     http.Header
+	query      url.Values
+	newPayload func() interface{}
+}
+
+// WithHeader adds a header to the request.
+func WithHeader(key, value string) JSONOption {
+	return func(r *jsonRequest) {
+		r.header.Add(key, value)
+	}
+}
+
+// WithQuery adds query params to the request URL.
+func WithQuery(values url.Values) JSONOption {
+	return func(r *jsonRequest) {
+		for k, vs := range values {
+			for _, v := range vs {
+				r.query.Add(k, v)
+			}
+		}
+	}
+}
+
+// WithErrorPayload decodes the JSON body of error responses into a *E,
+// available in HTTPError.Payload.
+func WithErrorPayload[E any]() JSONOption {
+	return func(r *jsonRequest) {
+		r.newPayload = func() interface{} { return new(E) }
+	}
+}
+
+// GetJSON sends a GET request and decodes the JSON response body into a T.
+// A response status code >= 400 is returned as an *HTTPError.
+func GetJSON[T any](ctx context.Context, doer Doer, rawURL string, opts ...JSONOption) (T, error) {
+	return DoJSON[T](ctx, doer, http.MethodGet, rawURL, nil, opts...)
+}
+
+// DeleteJSON sends a DELETE request and decodes the JSON response body into a T.
+// A response status code >= 400 is returned as an *HTTPError.
+func DeleteJSON[T any](ctx context.Context, doer Doer, rawURL string, opts ...JSONOption) (T, error) {
+	return DoJSON[T](ctx, doer, http.MethodDelete, rawURL, nil, opts...)
+}
+
+// PostJSON sends a POST request with body encoded as JSON and decodes the
+// JSON response body into a Resp.
+// A response status code >= 400 is returned as an *HTTPError.
+func PostJSON[Req, Resp any](ctx context.Context, doer Doer, rawURL string, body Req, opts ...JSONOption) (Resp, error) {
+	return DoJSON[Resp](ctx, doer, http.MethodPost, rawURL, body, opts...)
+}
+
+// PutJSON sends a PUT request with body encoded as JSON and decodes the
+// JSON response body into a Resp.
+// A response status code >= 400 is returned as an *HTTPError.
+func PutJSON[Req, Resp any](ctx context.Context, doer Doer, rawURL string, body Req, opts ...JSONOption) (Resp, error) {
+	return DoJSON[Resp](ctx, doer, http.MethodPut, rawURL, body, opts...)
+}
+
+// PatchJSON sends a PATCH request with body encoded as JSON and decodes the
+// JSON response body into a Resp.
+// A response status code >= 400 is returned as an *HTTPError.
+func PatchJSON[Req, Resp any](ctx context.Context, doer Doer, rawURL string, body Req, opts ...JSONOption) (Resp, error) {
+	return DoJSON[Resp](ctx, doer, http.MethodPatch, rawURL, body, opts...)
+}
+
+// DoJSON sends a request with the given method, with body encoded as JSON if
+// not nil, and decodes the JSON response body into a T.
+// An empty response body leaves T to its zero value.
+// A response status code >= 400 is returned as an *HTTPError.
+func DoJSON[T any](ctx context.Context, doer Doer, method, rawURL string, body interface{}, opts ...JSONOption) (T, error) {
+	var res T
+
+	jr := jsonRequest{
+		header: make(http.Header),
+		query:  make(url.Values),
+	}
+	for _, opt := range opts {
+		opt(&jr)
+	}
+
+	req, err := newJSONRequest(method, rawURL, body, jr)
+	if err != nil {
+		return res, err
+	}
+
+	var buf bytes.Buffer
+	statusCode, header, err := doWithHeader(ctx, doer, &buf, req)
+	if err != nil {
+		return res, err
+	}
+
+	if statusCode >= http.StatusBadRequest {
+		return res, newHTTPError(statusCode, header, buf.Bytes(), jr)
+	}
+
+	if buf.Len() == 0 {
+		return res, nil
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		return res, fmt.Errorf("decode status %d: %w", statusCode, err)
+	}
+
+	return res, nil
+}
+
+func newJSONRequest(method, rawURL string, body interface{}, jr jsonRequest) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode request body: %w", err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, rawURL, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(jr.query) > 0 {
+		q := req.URL.Query()
+		for k, vs := range jr.query {
+			q[k] = append(q[k], vs...)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, vs := range jr.header {
+		req.Header[k] = vs
+	}
+
+	return req, nil
+}
+
+func newHTTPError(statusCode int, header http.Header, body []byte, jr jsonRequest) *HTTPError {
+	httpErr := &HTTPError{
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       body,
+	}
+	if len(body) > httpErrorBodyMaxSize {
+		httpErr.Body = body[:httpErrorBodyMaxSize]
+	}
+
+	if p, ok := decodeProblem(header, body); ok {
+		httpErr.Problem = p
+	}
+
+	if jr.newPayload != nil && len(body) > 0 {
+		payload := jr.newPayload()
+		if err := json.Unmarshal(body, payload); err == nil {
+			httpErr.Payload = payload
+		}
+	}
+
+	return httpErr
+}
+
+// headerDoer is implemented by the Doers which give access to the response
+// header.
+type headerDoer interface {
+	doWithHeader(ctx context.Context, dst io.Writer, request *http.Request) (statusCode int, header http.Header, err error)
+}
+
+func doWithHeader(ctx context.Context, doer Doer, dst io.Writer, request *http.Request) (statusCode int, header http.Header, err error) {
+	if hd, ok := doer.(headerDoer); ok {
+		return hd.doWithHeader(ctx, dst, request)
+	}
+	statusCode, err = doer.Do(ctx, dst, request)
+	return statusCode, nil, err
+}
+
+// decodeProblem decodes the body as a problem if the header content type is
+// application/problem+json.
+func decodeProblem(header http.Header, body []byte) (*problem.Problem, bool) {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType != problem.ContentType {
+		return nil, false
+	}
+
+	var p problem.Problem
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, false
+	}
+	return &p, true
+}
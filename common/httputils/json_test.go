@@ -0,0 +1,177 @@
+package httputils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monorepo/common/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type jsonTestItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type jsonTestError struct {
+	Code string `json:"code"`
+}
+
+func Test_GetJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+		assert.Empty(t, r.Header.Get("Content-Type"))
+		assert.Equal(t, "bar", r.Header.Get("X-Foo"))
+		assert.Equal(t, "1", r.URL.Query().Get("a"))
+		assert.Equal(t, "2", r.URL.Query().Get("b"))
+		_, _ = io.WriteString(w, `{"id":1,"name":"foo"}`)
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0)
+
+	item, err := GetJSON[jsonTestItem](context.Background(), client, ts.URL+"?a=1",
+		WithHeader("X-Foo", "bar"),
+		WithQuery(url.Values{"b": {"2"}}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, jsonTestItem{ID: 1, Name: "foo"}, item)
+}
+
+func Test_PostJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var item jsonTestItem
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&item))
+		item.ID = 42
+		_ = json.NewEncoder(w).Encode(item)
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0)
+
+	item, err := PostJSON[jsonTestItem, *jsonTestItem](context.Background(), client, ts.URL, jsonTestItem{Name: "foo"})
+	require.NoError(t, err)
+	assert.Equal(t, &jsonTestItem{ID: 42, Name: "foo"}, item)
+}
+
+func Test_DoJSON_empty_body(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0)
+
+	item, err := DeleteJSON[*jsonTestItem](context.Background(), client, ts.URL)
+	require.NoError(t, err)
+	assert.Nil(t, item)
+}
+
+func Test_DoJSON_HTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = io.WriteString(w, `{"code":"already_exists"}`)
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0)
+
+	_, err := PutJSON[jsonTestItem, jsonTestItem](context.Background(), client, ts.URL, jsonTestItem{ID: 1},
+		WithErrorPayload[jsonTestError](),
+	)
+
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusConflict, httpErr.StatusCode)
+	assert.Equal(t, "abc", httpErr.Header.Get("X-Request-Id"))
+	assert.Equal(t, `{"code":"already_exists"}`, string(httpErr.Body))
+	assert.Equal(t, &jsonTestError{Code: "already_exists"}, httpErr.Payload)
+	assert.EqualError(t, err, `http status 409: body "{\"code\":\"already_exists\"}"`)
+}
+
+func Test_DoJSON_HTTPError_problem(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, problem.New(http.StatusConflict, "item 1 already exists").With("id", 1))
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0)
+
+	_, err := PostJSON[jsonTestItem, jsonTestItem](context.Background(), client, ts.URL, jsonTestItem{ID: 1})
+
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusConflict, httpErr.StatusCode)
+
+	var p *problem.Problem
+	require.True(t, errors.As(err, &p))
+	assert.Equal(t, "item 1 already exists", p.Detail)
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, p.Extensions)
+	assert.EqualError(t, err, "http status 409: problem 409 Conflict: item 1 already exists")
+}
+
+func Test_DoJSON_HTTPError_body_is_truncated(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = io.WriteString(w, strings.Repeat("a", 2*httpErrorBodyMaxSize))
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0)
+
+	_, err := GetJSON[jsonTestItem](context.Background(), client, ts.URL, WithErrorPayload[jsonTestError]())
+
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Len(t, httpErr.Body, httpErrorBodyMaxSize)
+	assert.Nil(t, httpErr.Payload)
+}
+
+func Test_DoJSON_decode_error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `not json`)
+	}))
+	defer ts.Close()
+
+	client := NewClient(time.Second, 0)
+
+	_, err := GetJSON[jsonTestItem](context.Background(), client, ts.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "decode status 200")
+}
+
+func Test_DoJSON_MockClient(t *testing.T) {
+	client := &MockClient{}
+	client.On("Do", mock.Anything, Mock("PATCH /items/1").Test(t).Body(`{"id":1,"name":"bar"}`).Header("Content-Type", "application/json").Match()).
+		Return(http.StatusOK, nil, `{"id":1,"name":"bar"}`)
+	client.On("Do", mock.Anything, Mock("GET /items/2").Test(t).Match()).
+		Return(http.StatusNotFound, nil, `{"code":"not_found"}`)
+
+	item, err := PatchJSON[jsonTestItem, jsonTestItem](context.Background(), client, "/items/1", jsonTestItem{ID: 1, Name: "bar"})
+	require.NoError(t, err)
+	assert.Equal(t, jsonTestItem{ID: 1, Name: "bar"}, item)
+
+	_, err = GetJSON[jsonTestItem](context.Background(), client, "/items/2", WithErrorPayload[jsonTestError]())
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	assert.Nil(t, httpErr.Header)
+	assert.Equal(t, &jsonTestError{Code: "not_found"}, httpErr.Payload)
+
+	client.AssertExpectations(t)
+}
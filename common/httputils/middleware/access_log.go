@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/logging"
+)
+
+// AccessLog is a server middleware logging a line for each handled request,
+// with its method, path, route, status code, duration and response size.
+//
+// The line is logged with the request scoped logger if any (see
+// logging.FromContext and UniqueID), or with the configured logger. The
+// requests answered with a 5xx status code are logged as errors, the other
+// ones as infos.
+type AccessLog struct {
+	RouteMatchers []interceptors.RouteMatcher
+
+	logger    logging.Logger
+	skipPaths map[string]bool
+}
+
+// NewAccessLog instantiates an AccessLog middleware.
+func NewAccessLog(logger logging.Logger, lrm ...interceptors.RouteMatcher) *AccessLog {
+	if logger == nil {
+		logger = logging.NewNoop()
+	}
+	return &AccessLog{
+		RouteMatchers: lrm,
+		logger:        logger,
+		skipPaths:     map[string]bool{},
+	}
+}
+
+// WithSkipPaths disables the logging of the requests to the given paths, e.g.
+// the health check ones.
+func (a *AccessLog) WithSkipPaths(paths ...string) *AccessLog {
+	for _, path := range paths {
+		a.skipPaths[path] = true
+	}
+	return a
+}
+
+// Middleware is the net/http and gorilla/mux middleware.
+func (a *AccessLog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.skipPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		rec := newResponseRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		route, _ := requestRoute(r, a.RouteMatchers)
+		a.log(r, route, rec.Status(), rec.size, time.Since(start), nil)
+	})
+}
+
+// Gin is the gin middleware.
+func (a *AccessLog) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if a.skipPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		c.Next()
+
+		route, _ := ginRoute(c, a.RouteMatchers)
+		var err error
+		if last := c.Errors.Last(); last != nil {
+			err = last.Err
+		}
+		a.log(c.Request, route, c.Writer.Status(), int64(max(c.Writer.Size(), 0)), time.Since(start), err)
+	}
+}
+
+// log logs the access line of a request, with the error attached to the gin
+// context if any (see problem.Abort).
+func (a *AccessLog) log(r *http.Request, route string, status int, size int64, duration time.Duration, err error) {
+	logger, ok := logging.FromContext(r.Context())
+	if !ok {
+		logger = a.logger
+	}
+
+	fields := logging.Fields{
+		"http_method":   r.Method,
+		"http_path":     r.URL.Path,
+		"http_status":   status,
+		"duration":      duration,
+		"response_size": size,
+		"remote_addr":   r.RemoteAddr,
+		"user_agent":    r.UserAgent(),
+	}
+	if route != "" {
+		fields["http_route"] = route
+	}
+	logger = logger.WithFields(fields)
+	if err != nil {
+		logger = logger.WithError(err)
+	}
+
+	if status >= http.StatusInternalServerError {
+		logger.Error("HTTP request handled")
+	} else {
+		logger.Info("HTTP request handled")
+	}
+}
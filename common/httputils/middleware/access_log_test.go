@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/monorepo/common/logging"
+	"github.com/monorepo/common/logging/loggingtest"
+	"github.com/monorepo/common/problem"
+)
+
+func TestAccessLog_Middleware(t *testing.T) {
+	logger := loggingtest.NewMock(t)
+	logger.On("WithFields", mock.MatchedBy(func(fields logging.Fields) bool {
+		return fields["http_method"] == http.MethodGet &&
+			fields["http_path"] == "/users/42" &&
+			fields["http_route"] == "get:/users/{id}" &&
+			fields["http_status"] == http.StatusOK &&
+			fields["response_size"] == int64(len("Hello World"))
+	})).Return(logger).Once()
+	logger.On("Info", []interface{}{"HTTP request handled"}).Once()
+
+	router := mux.NewRouter()
+	router.Use(NewAccessLog(logger).WithSkipPaths("/healthz").Middleware)
+	router.HandleFunc("/users/{id}", helloHandler)
+	router.HandleFunc("/healthz", helloHandler)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	logger.AssertExpectations(t)
+}
+
+func TestAccessLog_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := loggingtest.NewMock(t)
+	scoped := loggingtest.NewMock(t)
+	scoped.On("WithFields", mock.MatchedBy(func(fields logging.Fields) bool {
+		return fields["http_route"] == "post:/users" && fields["http_status"] == http.StatusBadGateway
+	})).Return(scoped).Once()
+	scoped.On("WithError", mock.Anything).Return(scoped).Once()
+	scoped.On("Error", []interface{}{"HTTP request handled"}).Once()
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		// The request scoped logger is preferred.
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), scoped))
+	}, NewAccessLog(logger).Gin())
+	router.POST("/users", func(c *gin.Context) {
+		problem.Abort(c, problem.New(http.StatusBadGateway, "upstream failure"))
+	})
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/users", nil))
+
+	assert.Equal(t, http.StatusBadGateway, resp.Code)
+	scoped.AssertExpectations(t)
+	logger.AssertExpectations(t)
+}
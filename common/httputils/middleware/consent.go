@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/monorepo/common/consent"
+	"github.com/monorepo/common/logging"
+)
+
+// Consent is a server middleware parsing the user consents of the Didomi
+// token of the incoming requests, from the consent.Header header or the
+// consent.CookieName cookie. The consent is stored in the request context (see
+// consent.FromContext), where the interceptors.ConsentChecker client
+// interceptor picks it.
+//
+// Requests with an invalid token are served without consent.
+type Consent struct {
+	logger logging.Logger
+}
+
+// NewConsent instantiates a Consent middleware, logging the invalid tokens
+// with the given logger.
+func NewConsent(logger logging.Logger) *Consent {
+	if logger == nil {
+		logger = logging.NewNoop()
+	}
+	return &Consent{
+		logger: logger,
+	}
+}
+
+// Middleware is the net/http and gorilla/mux middleware.
+func (m *Consent) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(m.newContext(r)))
+	})
+}
+
+// Gin is the gin middleware.
+func (m *Consent) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(m.newContext(c.Request))
+		c.Next()
+	}
+}
+
+func (m *Consent) newContext(r *http.Request) context.Context {
+	ctx := r.Context()
+
+	c, err := consent.FromRequest(r)
+	if err != nil {
+		if !errors.Is(err, consent.ErrNoToken) {
+			logger, ok := logging.FromContext(ctx)
+			if !ok {
+				logger = m.logger
+			}
+			logger.WithError(err).Warning("invalid didomi token")
+		}
+		return ctx
+	}
+
+	return consent.NewContext(ctx, c)
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/monorepo/common/consent"
+	"github.com/monorepo/common/httputils/httptester"
+	"github.com/monorepo/common/logging/loggingtest"
+)
+
+// consentHandler writes the user ID of the consent of the request context.
+func consentHandler(w http.ResponseWriter, r *http.Request) {
+	if c, ok := consent.FromContext(r.Context()); ok {
+		_, _ = w.Write([]byte(c.UserID))
+	}
+}
+
+func TestConsent_Middleware(t *testing.T) {
+	token := base64.StdEncoding.EncodeToString([]byte(`{"user_id": "user"}`))
+
+	tests := map[string]struct {
+		header   map[string]string
+		wantBody string
+		wantLog  bool
+	}{
+		"header": {
+			header:   map[string]string{consent.Header: token},
+			wantBody: "user",
+		},
+		"cookie": {
+			header:   map[string]string{"Cookie": consent.CookieName + "=" + token},
+			wantBody: "user",
+		},
+		"missing": {},
+		"invalid": {
+			header:  map[string]string{consent.Header: "invalid"},
+			wantLog: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			logger := loggingtest.NewMock(t)
+			if tt.wantLog {
+				logger.On("WithError", mock.Anything).Return(logger)
+				logger.On("Warning", []interface{}{"invalid didomi token"}).Return()
+			}
+
+			h := NewConsent(logger).Middleware(http.HandlerFunc(consentHandler))
+
+			resp := httptester.Get(h, "/", tt.header)
+			assert.Equal(t, tt.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestConsent_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(NewConsent(nil).Gin())
+	r.GET("/", func(c *gin.Context) {
+		consentHandler(c.Writer, c.Request)
+	})
+
+	token := base64.StdEncoding.EncodeToString([]byte(`{"user_id": "user"}`))
+	resp := httptester.Get(r, "/", map[string]string{consent.Header: token})
+	assert.Equal(t, "user", resp.Body.String())
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/monorepo/common/graceful"
+	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/httputils/polarisheaders"
+)
+
+// Deadline is a server middleware deriving the request context with the
+// deadline propagated by the interceptors.Deadline client interceptor, so that
+// the request is not handled longer than the caller waits for it.
+//
+// The deadline is propagated to the requests sent by the handlers with the
+// request context through a client with the interceptors.Deadline interceptor.
+// Missing or invalid headers are ignored.
+type Deadline struct {
+	header      string
+	maxTimeout  time.Duration
+	gracePeriod time.Duration
+}
+
+// NewDeadline instantiates a Deadline middleware, reading the
+// interceptors.DefaultDeadlineHeader header.
+func NewDeadline() *Deadline {
+	return &Deadline{
+		header: interceptors.DefaultDeadlineHeader,
+	}
+}
+
+// WithHeader sets the name of the header carrying the remaining time.
+func (d *Deadline) WithHeader(name string) *Deadline {
+	d.header = http.CanonicalHeaderKey(name)
+	return d
+}
+
+// WithMaxTimeout caps the propagated timeout, and applies it to the requests
+// without one.
+func (d *Deadline) WithMaxTimeout(timeout time.Duration) *Deadline {
+	d.maxTimeout = timeout
+	return d
+}
+
+// WithGracePeriod lets the handlers finish during delay once the request
+// context is canceled, e.g. when the server shuts down or the client goes
+// away. See graceful.Graceful. The deadline is still enforced.
+func (d *Deadline) WithGracePeriod(delay time.Duration) *Deadline {
+	d.gracePeriod = delay
+	return d
+}
+
+// Middleware is the net/http and gorilla/mux middleware.
+func (d *Deadline) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := d.newContext(r.Context(), r.Header)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Gin is the gin middleware.
+func (d *Deadline) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := d.newContext(c.Request.Context(), c.Request.Header)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// newContext returns the request context with the propagated deadline, and
+// the grace period.
+func (d *Deadline) newContext(parent context.Context, h http.Header) (context.Context, context.CancelFunc) {
+	ctx, forceCancel := graceful.Graceful(parent, d.gracePeriod)
+	cancels := []context.CancelFunc{forceCancel}
+
+	if deadline, ok := parent.Deadline(); ok && d.gracePeriod > 0 {
+		// The graceful context does not carry the parent deadline.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		cancels = append(cancels, cancel)
+	}
+
+	timeout, ok := polarisheaders.ParseTimeout(h.Get(d.header))
+	if d.maxTimeout > 0 && (!ok || timeout > d.maxTimeout) {
+		timeout, ok = d.maxTimeout, true
+	}
+	if ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		cancels = append(cancels, cancel)
+	}
+
+	return ctx, func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
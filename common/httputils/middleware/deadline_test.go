@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/httputils/httptester"
+)
+
+// deadlineHandler writes the remaining time before the deadline of the
+// request context, or "none".
+func deadlineHandler(w http.ResponseWriter, r *http.Request) {
+	deadline, ok := r.Context().Deadline()
+	if !ok {
+		_, _ = w.Write([]byte("none"))
+		return
+	}
+	_, _ = w.Write([]byte(time.Until(deadline).Round(time.Second).String()))
+}
+
+func TestDeadline_Middleware(t *testing.T) {
+	tests := map[string]struct {
+		middleware *Deadline
+		header     string
+		value      string
+		want       string
+	}{
+		"from header": {
+			middleware: NewDeadline(),
+			header:     "X-Request-Deadline",
+			value:      "2S",
+			want:       "2s",
+		},
+		"missing": {
+			middleware: NewDeadline(),
+			want:       "none",
+		},
+		"invalid": {
+			middleware: NewDeadline(),
+			header:     "X-Request-Deadline",
+			value:      "2s",
+			want:       "none",
+		},
+		"capped": {
+			middleware: NewDeadline().WithMaxTimeout(5 * time.Second),
+			header:     "X-Request-Deadline",
+			value:      "1M",
+			want:       "5s",
+		},
+		"max timeout": {
+			middleware: NewDeadline().WithMaxTimeout(5 * time.Second),
+			want:       "5s",
+		},
+		"custom header": {
+			middleware: NewDeadline().WithHeader("grpc-timeout"),
+			header:     "Grpc-Timeout",
+			value:      "3000m",
+			want:       "3s",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := tt.middleware.Middleware(http.HandlerFunc(deadlineHandler))
+
+			resp := httptester.Get(h, "/", map[string]string{tt.header: tt.value})
+			assert.Equal(t, tt.want, resp.Body.String())
+		})
+	}
+}
+
+func TestDeadline_Middleware_grace_period(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+
+	h := NewDeadline().
+		WithGracePeriod(time.Minute).
+		Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cancelParent()
+
+			select {
+			case <-r.Context().Done():
+				t.Error("request context canceled during the grace period")
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			// The propagated deadline is still enforced.
+			_, ok := r.Context().Deadline()
+			assert.True(t, ok)
+			<-r.Context().Done()
+			assert.ErrorIs(t, r.Context().Err(), context.DeadlineExceeded)
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(parent)
+	req.Header.Set("X-Request-Deadline", "50m")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestDeadline_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(NewDeadline().Gin())
+	r.GET("/", func(c *gin.Context) {
+		deadlineHandler(c.Writer, c.Request)
+	})
+
+	resp := httptester.Get(r, "/", map[string]string{"X-Request-Deadline": "2S"})
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "2s", resp.Body.String())
+}
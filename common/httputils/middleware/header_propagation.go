@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/monorepo/common/httputils/polarisheaders"
+)
+
+// HeaderPropagation is a server middleware populating the request context
+// from the request headers, according to polarisheaders mappings, so that the
+// interceptors.HeaderPropagation client interceptor forwards them.
+//
+// Use the UniqueID middleware rather than the polarisheaders.UniqueIDMapping
+// to validate the incoming unique IDs.
+type HeaderPropagation struct {
+	mappings []polarisheaders.Mapping
+}
+
+// NewHeaderPropagation instantiates a HeaderPropagation middleware with the
+// given mappings, e.g. polarisheaders.BrandMappings.
+func NewHeaderPropagation(mappings ...polarisheaders.Mapping) *HeaderPropagation {
+	return &HeaderPropagation{
+		mappings: mappings,
+	}
+}
+
+// Middleware is the net/http and gorilla/mux middleware.
+func (p *HeaderPropagation) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := polarisheaders.Extract(r.Context(), r.Header, p.mappings...)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Gin is the gin middleware.
+func (p *HeaderPropagation) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := polarisheaders.Extract(c.Request.Context(), c.Request.Header, p.mappings...)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/monorepo/common/contextkeys"
+	"github.com/monorepo/common/httputils/httptester"
+	"github.com/monorepo/common/httputils/polarisheaders"
+)
+
+// brandHandler writes the brand values of the request context.
+func brandHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	for _, key := range []any{contextkeys.Brand, contextkeys.BrandOriginHost, contextkeys.Language, contextkeys.AuthToken} {
+		v, _ := ctx.Value(key).(string)
+		_, _ = w.Write([]byte(v + ";"))
+	}
+}
+
+var brandHeaders = map[string]string{
+	polarisheaders.Brand:         "brand",
+	polarisheaders.ForwardedHost: "brand.example.com",
+	polarisheaders.Language:      "fr-FR",
+	polarisheaders.Authorization: "Bearer token",
+}
+
+func TestHeaderPropagation_Middleware(t *testing.T) {
+	h := NewHeaderPropagation(polarisheaders.BrandMappings...).Middleware(http.HandlerFunc(brandHandler))
+
+	resp := httptester.Get(h, "/", brandHeaders)
+	assert.Equal(t, "brand;brand.example.com;fr-FR;;", resp.Body.String())
+}
+
+func TestHeaderPropagation_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(NewHeaderPropagation(polarisheaders.Default...).Gin())
+	r.GET("/", func(c *gin.Context) {
+		brandHandler(c.Writer, c.Request)
+	})
+
+	resp := httptester.Get(r, "/", brandHeaders)
+	assert.Equal(t, "brand;brand.example.com;fr-FR;;", resp.Body.String())
+}
+
+func TestHeaderPropagation_authorization(t *testing.T) {
+	h := NewHeaderPropagation(polarisheaders.AuthorizationMapping).Middleware(http.HandlerFunc(brandHandler))
+
+	resp := httptester.Get(h, "/", brandHeaders)
+	assert.Equal(t, ";;;token;", resp.Body.String())
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/monitoring"
+	"github.com/monorepo/common/monitoring/metrics"
+)
+
+// Monitoring is the server flavour of the interceptors.Monitoring client
+// interceptor. It records:
+//   - http.server.request.latency, the time spent handling the requests,
+//   - http.server.request.count, the number of handled requests,
+//   - http.server.response.size, the size of the response bodies,
+//
+// tagged with the response status code and class, the route of the request
+// (see interceptors.RouteMatcher) and the monitoring tags of the request
+// context.
+//
+// The route is the template of the gin or gorilla/mux route which matched the
+// request, e.g. "get:/users/{id}", or the route of the first matching route
+// matcher. Requests without a route are not tagged, to keep the metrics
+// cardinality low.
+type Monitoring struct {
+	Monitor       metrics.StatsdHandler
+	RouteMatchers []interceptors.RouteMatcher
+}
+
+// NewMonitoring instantiates a new Monitoring middleware.
+func NewMonitoring(sh metrics.StatsdHandler, lrm ...interceptors.RouteMatcher) *Monitoring {
+	return &Monitoring{
+		Monitor:       sh,
+		RouteMatchers: lrm,
+	}
+}
+
+// Middleware is the net/http and gorilla/mux middleware.
+func (m *Monitoring) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newResponseRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		route, _ := requestRoute(r, m.RouteMatchers)
+		m.record(r, route, rec.Status(), rec.size, time.Since(start))
+	})
+}
+
+// Gin is the gin middleware.
+func (m *Monitoring) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route, _ := ginRoute(c, m.RouteMatchers)
+		m.record(c.Request, route, c.Writer.Status(), int64(max(c.Writer.Size(), 0)), time.Since(start))
+	}
+}
+
+func (m *Monitoring) record(r *http.Request, route string, status int, size int64, latency time.Duration) {
+	tags := []string{
+		fmt.Sprintf("status_code:%d", status),
+		fmt.Sprintf("status_class:%dxx", status/100),
+	}
+	if route != "" {
+		tags = append(tags, "route:"+route)
+	}
+	for k, v := range monitoring.GetTagsFromContext(r.Context()) {
+		tags = append(tags, fmt.Sprintf("%s:%s", k, v))
+	}
+
+	m.Monitor.Timing("http.server.request.latency", latency, tags, 1)
+	m.Monitor.Count("http.server.request.count", 1, tags, 1)
+	m.Monitor.Histogram("http.server.response.size", float64(size), tags, 1)
+}
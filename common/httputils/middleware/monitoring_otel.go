@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	otelnoop "go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/monitoring"
+	"github.com/monorepo/common/monitoring/metrics"
+	"github.com/monorepo/common/monitoring/semconv"
+)
+
+// otelInstrumentationName is the instrumentation name of the OTEL meter and
+// tracer used by the OTEL middlewares.
+const otelInstrumentationName = "github.com/monorepo/common/httputils/middleware"
+
+// OTELMonitoring is the OpenTelemetry flavour of the Monitoring middleware.
+//
+// It records the http.server.* metrics of the OTEL semantic conventions.
+type OTELMonitoring struct {
+	RouteMatchers []interceptors.RouteMatcher
+
+	duration       otelmetric.Float64Histogram
+	requestSize    otelmetric.Int64Histogram
+	responseSize   otelmetric.Int64Histogram
+	activeRequests otelmetric.Int64UpDownCounter
+}
+
+// NewOTELMonitoring instantiates a new OTELMonitoring middleware.
+//
+// If mp is nil, the global meter provider is used (see
+// metrics.SetGlobalMeterProvider).
+func NewOTELMonitoring(mp metrics.MeterProvider, lrm ...interceptors.RouteMatcher) *OTELMonitoring {
+	if mp == nil {
+		mp = metrics.GetGlobalMeterProvider()
+	}
+
+	meter := mp.Meter(otelInstrumentationName, otelmetric.WithSchemaURL(semconv.SchemaURL))
+	noopMeter := otelnoop.Meter{}
+
+	m := &OTELMonitoring{
+		RouteMatchers: lrm,
+	}
+
+	var err error
+
+	m.duration, err = meter.Float64Histogram(
+		semconv.HTTPServerRequestDurationName,
+		otelmetric.WithUnit(semconv.HTTPServerRequestDurationUnit),
+		otelmetric.WithDescription(semconv.HTTPServerRequestDurationDescription),
+	)
+	if err != nil {
+		otel.Handle(err)
+		m.duration, _ = noopMeter.Float64Histogram(semconv.HTTPServerRequestDurationName)
+	}
+
+	m.requestSize, err = meter.Int64Histogram(
+		semconv.HTTPServerRequestBodySizeName,
+		otelmetric.WithUnit(semconv.HTTPServerRequestBodySizeUnit),
+		otelmetric.WithDescription(semconv.HTTPServerRequestBodySizeDescription),
+	)
+	if err != nil {
+		otel.Handle(err)
+		m.requestSize, _ = noopMeter.Int64Histogram(semconv.HTTPServerRequestBodySizeName)
+	}
+
+	m.responseSize, err = meter.Int64Histogram(
+		semconv.HTTPServerResponseBodySizeName,
+		otelmetric.WithUnit(semconv.HTTPServerResponseBodySizeUnit),
+		otelmetric.WithDescription(semconv.HTTPServerResponseBodySizeDescription),
+	)
+	if err != nil {
+		otel.Handle(err)
+		m.responseSize, _ = noopMeter.Int64Histogram(semconv.HTTPServerResponseBodySizeName)
+	}
+
+	m.activeRequests, err = meter.Int64UpDownCounter(
+		semconv.HTTPServerActiveRequestsName,
+		otelmetric.WithUnit(semconv.HTTPServerActiveRequestsUnit),
+		otelmetric.WithDescription(semconv.HTTPServerActiveRequestsDescription),
+	)
+	if err != nil {
+		otel.Handle(err)
+		m.activeRequests, _ = noopMeter.Int64UpDownCounter(semconv.HTTPServerActiveRequestsName)
+	}
+
+	return m
+}
+
+// Middleware is the net/http and gorilla/mux middleware.
+func (m *OTELMonitoring) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		start := time.Now()
+
+		activeAttrs := otelmetric.WithAttributes(requestAttributes(r)...)
+		m.activeRequests.Add(ctx, 1, activeAttrs)
+		defer m.activeRequests.Add(ctx, -1, activeAttrs)
+
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		route, _ := requestRoute(r, m.RouteMatchers)
+		m.record(ctx, r, route, rec.Status(), rec.size, time.Since(start))
+	})
+}
+
+// Gin is the gin middleware.
+func (m *OTELMonitoring) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		start := time.Now()
+
+		activeAttrs := otelmetric.WithAttributes(requestAttributes(c.Request)...)
+		m.activeRequests.Add(ctx, 1, activeAttrs)
+		defer m.activeRequests.Add(ctx, -1, activeAttrs)
+
+		c.Next()
+
+		route, _ := ginRoute(c, m.RouteMatchers)
+		m.record(ctx, c.Request, route, c.Writer.Status(), int64(max(c.Writer.Size(), 0)), time.Since(start))
+	}
+}
+
+func (m *OTELMonitoring) record(ctx context.Context, r *http.Request, route string, status int, size int64, duration time.Duration) {
+	attrs := append(requestAttributes(r), semconv.HTTPResponseStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		// Server requests are only in error for 5xx status codes.
+		attrs = append(attrs, semconv.ErrorTypeKey.String(strconv.Itoa(status)))
+	}
+	if route != "" {
+		attrs = append(attrs, semconv.HTTPRoute(routeTemplate(r.Method, route)))
+	}
+	for k, v := range monitoring.GetTagsFromContext(r.Context()) {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	set := otelmetric.WithAttributeSet(attribute.NewSet(attrs...))
+
+	m.duration.Record(ctx, duration.Seconds(), set)
+	if r.ContentLength > 0 {
+		m.requestSize.Record(ctx, r.ContentLength, set)
+	}
+	m.responseSize.Record(ctx, size, set)
+}
+
+// requestAttributes returns the attributes identifying the kind of request.
+func requestAttributes(r *http.Request) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLSchemeKey.String(requestScheme(r)),
+	}
+}
+
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
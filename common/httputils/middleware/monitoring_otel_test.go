@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/monorepo/common/monitoring/semconv"
+)
+
+func TestOTELMonitoring_Middleware(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	router := mux.NewRouter()
+	router.Use(NewOTELMonitoring(mp).Middleware)
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("Hello World"))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users/42", strings.NewReader("body")))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	got := make(map[string]metricdata.Aggregation)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		got[m.Name] = m.Data
+	}
+
+	duration, ok := got[semconv.HTTPServerRequestDurationName].(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, duration.DataPoints, 1)
+	attrs := duration.DataPoints[0].Attributes
+	assertAttribute(t, attrs, semconv.HTTPRouteKey, attribute.StringValue("/users/{id}"))
+	assertAttribute(t, attrs, semconv.HTTPResponseStatusCodeKey, attribute.IntValue(http.StatusInternalServerError))
+	assertAttribute(t, attrs, semconv.HTTPRequestMethodKey, attribute.StringValue(http.MethodPost))
+	assertAttribute(t, attrs, semconv.URLSchemeKey, attribute.StringValue("http"))
+	assertAttribute(t, attrs, semconv.ErrorTypeKey, attribute.StringValue("500"))
+
+	requestSize, ok := got[semconv.HTTPServerRequestBodySizeName].(metricdata.Histogram[int64])
+	require.True(t, ok)
+	require.Len(t, requestSize.DataPoints, 1)
+	assert.Equal(t, int64(4), requestSize.DataPoints[0].Sum)
+
+	responseSize, ok := got[semconv.HTTPServerResponseBodySizeName].(metricdata.Histogram[int64])
+	require.True(t, ok)
+	require.Len(t, responseSize.DataPoints, 1)
+	assert.Equal(t, int64(len("Hello World")), responseSize.DataPoints[0].Sum)
+
+	active, ok := got[semconv.HTTPServerActiveRequestsName].(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, active.DataPoints, 1)
+	assert.Equal(t, int64(0), active.DataPoints[0].Value)
+}
+
+func assertAttribute(t *testing.T, attrs attribute.Set, key attribute.Key, want attribute.Value) {
+	t.Helper()
+	got, ok := attrs.Value(key)
+	if assert.True(t, ok, "missing attribute %s", key) {
+		assert.Equal(t, want, got)
+	}
+}
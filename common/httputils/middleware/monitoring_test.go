@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/monitoring"
+	"github.com/monorepo/common/monitoring/metrics"
+)
+
+// tagsStatsdHandler records the tags of the counts sent by name.
+type tagsStatsdHandler struct {
+	metrics.StatsdHandler
+
+	mu   sync.Mutex
+	tags map[string][][]string
+}
+
+func newTagsStatsdHandler() *tagsStatsdHandler {
+	return &tagsStatsdHandler{
+		StatsdHandler: metrics.NoopStatsdHandler,
+		tags:          make(map[string][][]string),
+	}
+}
+
+func (h *tagsStatsdHandler) Count(name string, value int64, tags []string, rate float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tags[name] = append(h.tags[name], tags)
+}
+
+func (h *tagsStatsdHandler) get(name string) [][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.tags[name]
+}
+
+func helloHandler(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.WriteString(w, "Hello World")
+}
+
+func TestMonitoring_Middleware(t *testing.T) {
+	sh := newTagsStatsdHandler()
+	m := NewMonitoring(sh, interceptors.StaticRouteMatcher(http.MethodGet, "/static"))
+
+	router := mux.NewRouter()
+	router.Use(m.Middleware)
+	router.HandleFunc("/users/{id}", helloHandler)
+	router.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	ctx := monitoring.AddTagsInContext(context.Background(), map[string]string{"caller": "test"})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil).WithContext(ctx))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/fail", nil))
+
+	// The route matchers are used when the mux route is unknown.
+	m.Middleware(http.HandlerFunc(helloHandler)).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/static", nil))
+	m.Middleware(http.HandlerFunc(helloHandler)).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	assert.Equal(t, [][]string{
+		{"status_code:200", "status_class:2xx", "route:get:/users/{id}", "caller:test"},
+		{"status_code:503", "status_class:5xx", "route:post:/fail"},
+		{"status_code:200", "status_class:2xx", "route:get:/static"},
+		{"status_code:200", "status_class:2xx"},
+	}, sh.get("http.server.request.count"))
+}
+
+func TestMonitoring_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sh := newTagsStatsdHandler()
+	router := gin.New()
+	router.Use(NewMonitoring(sh).Gin())
+	router.GET("/users/:id", func(c *gin.Context) {
+		c.String(http.StatusCreated, strings.Repeat("a", 42))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	assert.Equal(t, [][]string{
+		{"status_code:201", "status_class:2xx", "route:get:/users/:id"},
+		{"status_code:404", "status_class:4xx"},
+	}, sh.get("http.server.request.count"))
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/monorepo/common/logging"
+	"github.com/monorepo/common/problem"
+)
+
+// Recovery is a server middleware recovering the panics of the handlers into
+// 500 problem responses, and logging them as errors with their stack trace.
+//
+// It should be the last registered middleware, for the other ones (e.g.
+// Monitoring, Tracing and AccessLog) to observe the 500 responses. The
+// http.ErrAbortHandler panics are not recovered, to abort the response as
+// expected.
+type Recovery struct {
+	logger logging.Logger
+}
+
+// NewRecovery instantiates a Recovery middleware, logging the panics with the
+// request scoped logger if any (see logging.FromContext), or with the given
+// one.
+func NewRecovery(logger logging.Logger) *Recovery {
+	if logger == nil {
+		logger = logging.NewNoop()
+	}
+	return &Recovery{
+		logger: logger,
+	}
+}
+
+// Middleware is the net/http and gorilla/mux middleware.
+func (rc *Recovery) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := newResponseRecorder(w)
+		defer func() {
+			if v := recover(); v != nil {
+				err := rc.recovered(r, v)
+				// The response can't be changed once its header is written.
+				if !rec.Written() {
+					problem.WriteError(w, err)
+				}
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// Gin is the gin middleware.
+func (rc *Recovery) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if v := recover(); v != nil {
+				err := rc.recovered(c.Request, v)
+				if !c.Writer.Written() {
+					problem.Abort(c, err)
+				} else {
+					_ = c.Error(err)
+					c.Abort()
+				}
+			}
+		}()
+
+		c.Next()
+	}
+}
+
+// recovered logs a recovered panic, and returns the problem to respond with.
+// The http.ErrAbortHandler panics are panicked again.
+func (rc *Recovery) recovered(r *http.Request, v interface{}) error {
+	if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+		panic(v)
+	}
+
+	logger, ok := logging.FromContext(r.Context())
+	if !ok {
+		logger = rc.logger
+	}
+	logger.WithFields(logging.Fields{
+		"panic": fmt.Sprint(v),
+		"stack": string(debug.Stack()),
+	}).Error("HTTP handler panicked")
+
+	return problem.New(http.StatusInternalServerError, "")
+}
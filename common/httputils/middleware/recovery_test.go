@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/monorepo/common/logging"
+	"github.com/monorepo/common/logging/loggingtest"
+	"github.com/monorepo/common/problem"
+)
+
+func panicHandler(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+}
+
+func newPanicLoggerMock(t *testing.T) *loggingtest.Mock {
+	logger := loggingtest.NewMock(t)
+	logger.On("WithFields", mock.MatchedBy(func(fields logging.Fields) bool {
+		stack, _ := fields["stack"].(string)
+		return fields["panic"] == "boom" && strings.Contains(stack, "panicHandler")
+	})).Return(logger).Once()
+	logger.On("Error", []interface{}{"HTTP handler panicked"}).Once()
+	return logger
+}
+
+func TestRecovery_Middleware(t *testing.T) {
+	logger := newPanicLoggerMock(t)
+
+	resp := httptest.NewRecorder()
+	NewRecovery(logger).Middleware(http.HandlerFunc(panicHandler)).
+		ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, problem.ContentType, resp.Header().Get("Content-Type"))
+	logger.AssertExpectations(t)
+}
+
+func TestRecovery_Middleware_observed(t *testing.T) {
+	logger := newPanicLoggerMock(t)
+	sh := newTagsStatsdHandler()
+
+	h := NewMonitoring(sh).Middleware(NewRecovery(logger).Middleware(http.HandlerFunc(panicHandler)))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, [][]string{
+		{"status_code:500", "status_class:5xx"},
+	}, sh.get("http.server.request.count"))
+}
+
+func TestRecovery_Middleware_abort_handler(t *testing.T) {
+	h := NewRecovery(loggingtest.NewMock(t)).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestRecovery_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := newPanicLoggerMock(t)
+
+	router := gin.New()
+	router.Use(NewRecovery(logger).Gin())
+	router.GET("/", func(c *gin.Context) {
+		panicHandler(c.Writer, c.Request)
+	})
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, problem.ContentType, resp.Header().Get("Content-Type"))
+	logger.AssertExpectations(t)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/mux"
+
+	"github.com/monorepo/common/httputils/interceptors"
+)
+
+// requestRoute returns the route of a net/http or gorilla/mux request: the
+// template of the mux.Route which matched the request, or the route of the
+// first matching route matcher.
+//
+// Routes are identified as by the interceptors.RouteMatcher implementations,
+// e.g. "get:/users/{id}". The mux route is only known by the middlewares
+// given to mux.Router.Use, not by the ones wrapping the router.
+func requestRoute(r *http.Request, lrm []interceptors.RouteMatcher) (string, bool) {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return routeID(r.Method, tpl), true
+		}
+	}
+	return matchRoute(r, lrm)
+}
+
+// ginRoute returns the route of a gin request: the path of the gin route
+// which matched the request, or the route of the first matching route matcher.
+func ginRoute(c *gin.Context, lrm []interceptors.RouteMatcher) (string, bool) {
+	if path := c.FullPath(); path != "" {
+		return routeID(c.Request.Method, path), true
+	}
+	return matchRoute(c.Request, lrm)
+}
+
+func matchRoute(r *http.Request, lrm []interceptors.RouteMatcher) (string, bool) {
+	for _, rm := range lrm {
+		if route, ok := rm.MatchRequest(r); ok {
+			return route, true
+		}
+	}
+	return "", false
+}
+
+func routeID(method, template string) string {
+	return fmt.Sprintf("%s:%s", strings.ToLower(method), template)
+}
+
+// routeTemplate returns the path template of a route identified as by
+// routeID, e.g. "/users/{id}" for "get:/users/{id}", as expected by the
+// http.route attribute of the OTEL semantic conventions.
+func routeTemplate(method, route string) string {
+	return strings.TrimPrefix(route, strings.ToLower(method)+":")
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/monorepo/common/httputils/signing"
+	"github.com/monorepo/common/logging"
+	"github.com/monorepo/common/problem"
+)
+
+// DefaultMaxSignedBodySize is the default maximum size of the request bodies
+// read by the SignatureVerifier middleware.
+const DefaultMaxSignedBodySize = 10 << 20
+
+// SignatureVerifier is a server middleware rejecting the requests without a
+// valid signature, e.g. signed by the signing.Interceptor client interceptor
+// with a signing.HMAC signer.
+//
+// The request body is read in memory to be verified. The requests with a
+// missing or invalid signature are rejected with a 401 problem, and the ones
+// with a too large body with a 413 problem.
+type SignatureVerifier struct {
+	verifier    signing.Verifier
+	logger      logging.Logger
+	maxBodySize int64
+}
+
+// NewSignatureVerifier instantiates a SignatureVerifier middleware, logging
+// the rejected requests with the given logger.
+func NewSignatureVerifier(verifier signing.Verifier, logger logging.Logger) *SignatureVerifier {
+	if logger == nil {
+		logger = logging.NewNoop()
+	}
+	return &SignatureVerifier{
+		verifier:    verifier,
+		logger:      logger,
+		maxBodySize: DefaultMaxSignedBodySize,
+	}
+}
+
+// WithMaxBodySize sets the maximum size of the request bodies,
+// DefaultMaxSignedBodySize by default.
+func (v *SignatureVerifier) WithMaxBodySize(size int64) *SignatureVerifier {
+	v.maxBodySize = size
+	return v
+}
+
+// Middleware is the net/http and gorilla/mux middleware.
+func (v *SignatureVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.verify(r); err != nil {
+			problem.WriteError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Gin is the gin middleware.
+func (v *SignatureVerifier) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := v.verify(c.Request); err != nil {
+			problem.Abort(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// verify verifies the signature of the request, and returns the problem to
+// respond with if it is invalid. The request body is replaced with a copy.
+func (v *SignatureVerifier) verify(r *http.Request) error {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, v.maxBodySize+1))
+	_ = r.Body.Close()
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "can't read the request body")
+	}
+	if int64(len(payload)) > v.maxBodySize {
+		return problem.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body larger than %d bytes", v.maxBodySize))
+	}
+	r.Body = io.NopCloser(bytes.NewReader(payload))
+
+	if err := v.verifier.Verify(r, payload); err != nil {
+		logger, ok := logging.FromContext(r.Context())
+		if !ok {
+			logger = v.logger
+		}
+		logger.WithError(err).Warning("request rejected")
+		return problem.New(http.StatusUnauthorized, "invalid request signature")
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/httputils/signing"
+	"github.com/monorepo/common/logging/loggingtest"
+)
+
+// echoHandler writes the request body.
+func echoHandler(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.Copy(w, r.Body)
+}
+
+func newSignedRequest(t *testing.T, h *signing.HMAC, body string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	require.NoError(t, h.Sign(req, []byte(body)))
+	return req
+}
+
+func TestSignatureVerifier_Middleware(t *testing.T) {
+	signer := signing.NewHMAC("key-1", "secret")
+
+	tests := map[string]struct {
+		req        func(t *testing.T) *http.Request
+		wantStatus int
+		wantBody   string
+	}{
+		"valid": {
+			req: func(t *testing.T) *http.Request {
+				return newSignedRequest(t, signer, "body")
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "body",
+		},
+		"unsigned": {
+			req: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("body"))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		"wrong key": {
+			req: func(t *testing.T) *http.Request {
+				return newSignedRequest(t, signing.NewHMAC("key-1", "wrong secret"), "body")
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		"too large": {
+			req: func(t *testing.T) *http.Request {
+				return newSignedRequest(t, signer, strings.Repeat("a", 11))
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			logger := loggingtest.NewMock(t)
+			logger.On("WithError", mock.Anything).Return(logger).Maybe()
+			logger.On("Warning", []interface{}{"request rejected"}).Maybe()
+
+			h := NewSignatureVerifier(signing.NewHMAC("key-1", "secret"), logger).
+				WithMaxBodySize(10).
+				Middleware(http.HandlerFunc(echoHandler))
+
+			resp := httptest.NewRecorder()
+			h.ServeHTTP(resp, tt.req(t))
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, resp.Body.String())
+			}
+		})
+	}
+}
+
+func TestSignatureVerifier_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	signer := signing.NewHMAC("key-1", "secret")
+
+	r := gin.New()
+	r.Use(NewSignatureVerifier(signing.NewHMAC("key-1", "secret"), nil).Gin())
+	r.POST("/users", func(c *gin.Context) {
+		echoHandler(c.Writer, c.Request)
+	})
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, newSignedRequest(t, signer, "body"))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "body", resp.Body.String())
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("body")))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/monorepo/common/httputils/interceptors"
+)
+
+// Tracing is the server flavour of the interceptors.Tracing client
+// interceptor.
+//
+// It starts a server span for each request, child of the trace context
+// extracted from the request headers if any (e.g. injected by the
+// interceptors.Tracing interceptor). The span is stored in the request
+// context, so that the requests sent by the handlers with a traced client
+// belong to the same trace.
+//
+// The span resource is the route of the request (see Monitoring), or the
+// request method if the request has no route.
+type Tracing struct {
+	RouteMatchers []interceptors.RouteMatcher
+}
+
+// NewTracing instantiates a new Tracing middleware.
+func NewTracing(lrm ...interceptors.RouteMatcher) *Tracing {
+	return &Tracing{
+		RouteMatchers: lrm,
+	}
+}
+
+// Middleware is the net/http and gorilla/mux middleware.
+func (t *Tracing) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, _ := requestRoute(r, t.RouteMatchers)
+		span, ctx := t.startSpan(r, route)
+		defer span.Finish()
+
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		finishSpan(span, rec.Status())
+	})
+}
+
+// Gin is the gin middleware.
+func (t *Tracing) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, _ := ginRoute(c, t.RouteMatchers)
+		span, ctx := t.startSpan(c.Request, route)
+		defer span.Finish()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		finishSpan(span, c.Writer.Status())
+	}
+}
+
+func (t *Tracing) startSpan(r *http.Request, route string) (ddtrace.Span, context.Context) {
+	resource := r.Method
+	if route != "" {
+		resource = route
+	}
+
+	opts := []ddtrace.StartSpanOption{
+		tracer.SpanType(ext.SpanTypeWeb),
+		tracer.ResourceName(resource),
+		tracer.Tag(ext.SpanKind, ext.SpanKindServer),
+		tracer.Tag("http.method", r.Method),
+		tracer.Tag("http.scheme", requestScheme(r)),
+		tracer.Tag("http.host", r.Host),
+		tracer.Tag("http.path", r.URL.Path),
+		tracer.Tag(ext.HTTPUserAgent, r.UserAgent()),
+	}
+	if route != "" {
+		opts = append(opts, tracer.Tag(ext.HTTPRoute, route))
+	}
+	if spanCtx, err := tracer.Extract(tracer.HTTPHeadersCarrier(r.Header)); err == nil {
+		opts = append(opts, tracer.ChildOf(spanCtx))
+	}
+
+	return tracer.StartSpanFromContext(r.Context(), "http.request", opts...)
+}
+
+func finishSpan(span ddtrace.Span, status int) {
+	span.SetTag(ext.HTTPCode, status)
+	if status >= http.StatusInternalServerError {
+		span.SetTag(ext.Error, errors.New("status code 5XX"))
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/monitoring/semconv"
+	"github.com/monorepo/common/monitoring/tracing"
+)
+
+// OTELTracing is the OpenTelemetry flavour of the Tracing middleware.
+//
+// It starts a server span for each request, following the OTEL semantic
+// conventions, child of the trace context extracted from the request headers
+// if any (e.g. injected by the interceptors.OTELTracing interceptor).
+type OTELTracing struct {
+	RouteMatchers []interceptors.RouteMatcher
+
+	tp         tracing.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// NewOTELTracing instantiates a new OTELTracing middleware.
+//
+// By default, spans are created from the global tracer provider (see
+// tracing.SetGlobalTracerProvider) and the W3C trace context and baggage are
+// extracted.
+func NewOTELTracing(lrm ...interceptors.RouteMatcher) *OTELTracing {
+	return &OTELTracing{
+		RouteMatchers: lrm,
+		propagator: propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		),
+	}
+}
+
+// WithTracerProvider sets the tracer provider used to create the spans,
+// instead of the global one.
+func (t *OTELTracing) WithTracerProvider(tp tracing.TracerProvider) *OTELTracing {
+	t.tp = tp
+	return t
+}
+
+// WithPropagator sets the propagator used to extract the trace context from
+// the request headers.
+func (t *OTELTracing) WithPropagator(p propagation.TextMapPropagator) *OTELTracing {
+	t.propagator = p
+	return t
+}
+
+// Middleware is the net/http and gorilla/mux middleware.
+func (t *OTELTracing) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, _ := requestRoute(r, t.RouteMatchers)
+		ctx, span := t.startSpan(r, route)
+		defer span.End()
+
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		endOTELSpan(span, rec.Status())
+	})
+}
+
+// Gin is the gin middleware.
+func (t *OTELTracing) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, _ := ginRoute(c, t.RouteMatchers)
+		ctx, span := t.startSpan(c.Request, route)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		endOTELSpan(span, c.Writer.Status())
+	}
+}
+
+func (t *OTELTracing) startSpan(r *http.Request, route string) (context.Context, oteltrace.Span) {
+	ctx := t.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+	name := r.Method
+	attrs := append(requestAttributes(r),
+		semconv.URLPath(r.URL.Path),
+		semconv.ServerAddressKey.String((&url.URL{Host: r.Host}).Hostname()),
+		semconv.UserAgentOriginal(r.UserAgent()),
+	)
+	if route != "" {
+		// The span name is "{method} {route}", with the route template.
+		tpl := routeTemplate(r.Method, route)
+		name = r.Method + " " + tpl
+		attrs = append(attrs, semconv.HTTPRoute(tpl))
+	}
+
+	return t.tracer().Start(ctx, name,
+		oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+		oteltrace.WithAttributes(attrs...),
+	)
+}
+
+func (t *OTELTracing) tracer() oteltrace.Tracer {
+	opts := []oteltrace.TracerOption{
+		oteltrace.WithSchemaURL(semconv.SchemaURL),
+	}
+	if t.tp != nil {
+		return t.tp.Tracer(otelInstrumentationName, opts...)
+	}
+	return tracing.Tracer(otelInstrumentationName, opts...)
+}
+
+func endOTELSpan(span oteltrace.Span, status int) {
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		// Server spans are only in error for 5xx status codes.
+		span.SetStatus(codes.Error, fmt.Sprintf("status code %d", status))
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/monorepo/common/monitoring/semconv"
+)
+
+func TestOTELTracing_Middleware(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	var handlerSpan oteltrace.SpanContext
+	router := mux.NewRouter()
+	router.Use(NewOTELTracing().WithTracerProvider(tp).Middleware)
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = oteltrace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /users/{id}", span.Name())
+	assert.Equal(t, oteltrace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, span.SpanContext(), handlerSpan)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.SpanContext().TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", span.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	attrs := attribute.NewSet(span.Attributes()...)
+	assertAttribute(t, attrs, semconv.HTTPRouteKey, attribute.StringValue("/users/{id}"))
+	assertAttribute(t, attrs, semconv.URLPathKey, attribute.StringValue("/users/42"))
+	assertAttribute(t, attrs, semconv.ServerAddressKey, attribute.StringValue("example.com"))
+	assertAttribute(t, attrs, semconv.HTTPResponseStatusCodeKey, attribute.IntValue(http.StatusServiceUnavailable))
+}
+
+func TestOTELTracing_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	router := gin.New()
+	router.Use(NewOTELTracing().WithTracerProvider(tp).Gin())
+	router.GET("/users/:id", func(c *gin.Context) {
+		assert.True(t, oteltrace.SpanContextFromContext(c.Request.Context()).IsValid())
+		c.Status(http.StatusNotFound)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /users/:id", span.Name())
+	assert.False(t, span.Parent().IsValid())
+	// Server spans are not in error for 4xx status codes.
+	assert.Equal(t, codes.Unset, span.Status().Code)
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func TestTracing_Middleware(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	var handlerSpanID uint64
+	router := mux.NewRouter()
+	router.Use(NewTracing().Middleware)
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		span, ok := tracer.SpanFromContext(r.Context())
+		require.True(t, ok)
+		handlerSpanID = span.Context().SpanID()
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(tracer.DefaultTraceIDHeader, "1234")
+	req.Header.Set(tracer.DefaultParentIDHeader, "5678")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "http.request", span.OperationName())
+	assert.Equal(t, handlerSpanID, span.SpanID())
+	assert.Equal(t, uint64(1234), span.TraceID())
+	assert.Equal(t, uint64(5678), span.ParentID())
+	assert.Equal(t, ext.SpanTypeWeb, span.Tag(ext.SpanType))
+	assert.Equal(t, "get:/users/{id}", span.Tag(ext.ResourceName))
+	assert.Equal(t, "get:/users/{id}", span.Tag(ext.HTTPRoute))
+	assert.Equal(t, "/users/42", span.Tag("http.path"))
+	assert.Equal(t, http.StatusBadGateway, span.Tag(ext.HTTPCode))
+	assert.NotNil(t, span.Tag(ext.Error))
+}
+
+func TestTracing_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	router := gin.New()
+	router.Use(NewTracing().Gin())
+	router.GET("/users/:id", func(c *gin.Context) {
+		_, ok := tracer.SpanFromContext(c.Request.Context())
+		assert.True(t, ok)
+		c.Status(http.StatusNoContent)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, uint64(0), span.ParentID())
+	assert.Equal(t, "get:/users/:id", span.Tag(ext.ResourceName))
+	assert.Equal(t, http.StatusNoContent, span.Tag(ext.HTTPCode))
+	assert.Nil(t, span.Tag(ext.Error))
+}
@@ -0,0 +1,111 @@
+// Package middleware provides HTTP server middlewares, for net/http,
+// gorilla/mux and gin servers.
+//
+// The net/http middlewares have the func(http.Handler) http.Handler signature
+// of mux.MiddlewareFunc, so they can be given to mux.Router.Use.
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/monorepo/common/contextkeys"
+	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/logging"
+)
+
+// uniqueIDMaxLength is the maximum length of an incoming unique ID.
+const uniqueIDMaxLength = 200
+
+// UniqueIDLogField is the name of the log field carrying the request unique
+// ID.
+const UniqueIDLogField = "request_id"
+
+// UniqueID is a server middleware giving a unique ID to each request, to
+// correlate the requests between services.
+//
+// The unique ID is taken from the incoming request header, or generated if
+// missing or invalid. It is stored in the request context under
+// contextkeys.UniqueID (where the interceptors.UniqueID client interceptor
+// picks it), echoed in the response header, and added as a field to the
+// request scoped logger (see logging.FromContext).
+type UniqueID struct {
+	header string
+	logger logging.Logger
+}
+
+// NewUniqueID instantiates an UniqueID middleware, reading and writing the
+// interceptors.DefaultUniqueIDHeader header.
+//
+// The request scoped logger is derived from the logger already in the request
+// context if any, or from the given one.
+func NewUniqueID(logger logging.Logger) *UniqueID {
+	return &UniqueID{
+		header: interceptors.DefaultUniqueIDHeader,
+		logger: logger,
+	}
+}
+
+// WithHeader sets the name of the header carrying the unique ID.
+func (u *UniqueID) WithHeader(name string) *UniqueID {
+	u.header = http.CanonicalHeaderKey(name)
+	return u
+}
+
+// Middleware is the net/http and gorilla/mux middleware.
+func (u *UniqueID) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := u.uniqueID(r)
+		w.Header().Set(u.header, id)
+		next.ServeHTTP(w, r.WithContext(u.newContext(r.Context(), id)))
+	})
+}
+
+// Gin is the gin middleware.
+func (u *UniqueID) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := u.uniqueID(c.Request)
+		c.Header(u.header, id)
+		c.Request = c.Request.WithContext(u.newContext(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// uniqueID returns the valid unique ID of the request header, or a new one.
+func (u *UniqueID) uniqueID(r *http.Request) string {
+	id := r.Header.Get(u.header)
+	if !isValidUniqueID(id) {
+		return uuid.NewString()
+	}
+	return id
+}
+
+func (u *UniqueID) newContext(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, contextkeys.UniqueID, id)
+
+	logger, ok := logging.FromContext(ctx)
+	if !ok {
+		logger = u.logger
+	}
+	if logger == nil {
+		return ctx
+	}
+	return logging.NewContext(ctx, logger.WithField(UniqueIDLogField, id))
+}
+
+// isValidUniqueID reports whether the id is not empty, not too long and made
+// of visible ASCII characters only, to avoid header and log injections.
+func isValidUniqueID(id string) bool {
+	if id == "" || len(id) > uniqueIDMaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/contextkeys"
+	"github.com/monorepo/common/httputils/httptester"
+	"github.com/monorepo/common/logging"
+	"github.com/monorepo/common/logging/loggingtest"
+)
+
+// uniqueIDHandler writes the unique ID of the request context, and checks the
+// request scoped logger.
+func uniqueIDHandler(t *testing.T, wantLogger logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger, ok := logging.FromContext(r.Context())
+		assert.True(t, ok)
+		assert.Same(t, wantLogger, logger)
+
+		id, _ := r.Context().Value(contextkeys.UniqueID).(string)
+		_, _ = w.Write([]byte(id))
+	}
+}
+
+func TestUniqueID_Middleware(t *testing.T) {
+	tests := map[string]struct {
+		header   string
+		value    string
+		wantSame bool
+	}{
+		"from header": {
+			header:   "X-Unique-Id",
+			value:    "abc-123",
+			wantSame: true,
+		},
+		"missing": {
+			header: "X-Unique-Id",
+		},
+		"invalid": {
+			header: "X-Unique-Id",
+			value:  "abc\n123",
+		},
+		"too long": {
+			header: "X-Unique-Id",
+			value:  strings.Repeat("a", uniqueIDMaxLength+1),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			requestLogger := loggingtest.NewMock(t)
+			logger := loggingtest.NewMock(t)
+			logger.On("WithField", UniqueIDLogField, mock.AnythingOfType("string")).Return(requestLogger)
+
+			h := NewUniqueID(logger).Middleware(uniqueIDHandler(t, requestLogger))
+
+			resp := httptester.Get(h, "/", map[string]string{tt.header: tt.value})
+
+			id := resp.Body.String()
+			assert.Equal(t, id, resp.Header().Get(tt.header))
+			if tt.wantSame {
+				assert.Equal(t, tt.value, id)
+			} else {
+				_, err := uuid.Parse(id)
+				assert.NoError(t, err)
+			}
+			logger.AssertCalled(t, "WithField", UniqueIDLogField, id)
+		})
+	}
+}
+
+func TestUniqueID_Middleware_mux_with_context_logger(t *testing.T) {
+	requestLogger := loggingtest.NewMock(t)
+	ctxLogger := loggingtest.NewMock(t)
+	ctxLogger.On("WithField", UniqueIDLogField, "abc").Return(requestLogger)
+
+	r := mux.NewRouter()
+	r.Use(
+		func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(logging.NewContext(r.Context(), ctxLogger)))
+			})
+		},
+		NewUniqueID(logging.NewNoop()).WithHeader("x-correlation-id").Middleware,
+	)
+	r.Handle("/", uniqueIDHandler(t, requestLogger))
+
+	resp := httptester.Get(r, "/", map[string]string{"X-Correlation-Id": "abc"})
+	assert.Equal(t, "abc", resp.Body.String())
+	assert.Equal(t, "abc", resp.Header().Get("X-Correlation-Id"))
+}
+
+func TestUniqueID_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	requestLogger := loggingtest.NewMock(t)
+	logger := loggingtest.NewMock(t)
+	logger.On("WithField", UniqueIDLogField, "abc").Return(requestLogger)
+
+	r := gin.New()
+	r.Use(NewUniqueID(logger).Gin())
+	r.GET("/", func(c *gin.Context) {
+		uniqueIDHandler(t, requestLogger)(c.Writer, c.Request)
+	})
+
+	resp := httptester.Get(r, "/", map[string]string{"X-Unique-Id": "abc"})
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "abc", resp.Body.String())
+	assert.Equal(t, "abc", resp.Header().Get("X-Unique-Id"))
+}
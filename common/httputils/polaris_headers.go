@@ -2,23 +2,32 @@ package httputils
 
 import (
 	"net/http"
+
+	"github.com/monorepo/common/httputils/polarisheaders"
 )
 
 // PolarisHeaders contains all required headers
 type PolarisHeaders http.Header
 
+// polarisHeadersMappings are the mappings of the headers copied by
+// GetPolarisHeaders, whose callers explicitly forward the authentication to
+// the Polaris services.
+var polarisHeadersMappings = append([]polarisheaders.Mapping{polarisheaders.AuthorizationMapping}, polarisheaders.Default...)
+
 // GetPolarisHeaders return header struct with all internal polaris headers
-// This will copy all authentication headers + Request Unique ID
-//func GetPolarisHeaders(req *http.Request) PolarisHeaders {
-//	headers := PolarisHeaders{}
-//	for _, h := range []string{polarisheaders.UniqueID, polarisheaders.Authorization} {
-//		v := req.Header[h]
-//		if len(v) > 0 {
-//			headers[h] = v
-//		}
-//	}
-//	return headers
-//}
+// This will copy all authentication headers + the headers of the
+// polarisheaders.Default mappings (Request Unique ID, brand and language).
+// Headers missing from the request are taken from its context values.
+func GetPolarisHeaders(req *http.Request) PolarisHeaders {
+	headers := http.Header{}
+	for _, m := range polarisHeadersMappings {
+		if v := req.Header.Values(m.Header); len(v) > 0 {
+			headers[m.Header] = v
+		}
+	}
+	polarisheaders.Inject(req.Context(), headers, polarisHeadersMappings...)
+	return PolarisHeaders(headers)
+}
 
 // SetPolarisHeaders add PolarisHeaders to request headers
 func SetPolarisHeaders(req *http.Request, headers PolarisHeaders) {
@@ -1,33 +1,41 @@
 package httputils
 
 import (
+	"context"
+	"net/http"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/monorepo/common/contextkeys"
+	"github.com/monorepo/common/httputils/polarisheaders"
 )
 
 func Test_get_all_headers(t *testing.T) {
-	//req, _ := http.NewRequest(http.MethodGet, "http://test", nil)
-	//req.Header.Set("X-Unique-Id", "unique id")
-	//req.Header.Set("Authorization", "auth")
-	//req.Header.Set("not-copied", "dummy")
-	//
-	//headers := GetPolarisHeaders(req)
-	//
-	//assert.Equal(t, []string{"unique id"}, headers[polarisheaders.UniqueID])
-	//assert.Equal(t, []string{"auth"}, headers["Authorization"])
-	//assert.Nil(t, headers["not-copied"])
+	req, _ := http.NewRequest(http.MethodGet, "http://test", nil)
+	req.Header.Set("X-Unique-Id", "unique id")
+	req.Header.Set("Authorization", "auth")
+	req.Header.Set("not-copied", "dummy")
+	req = req.WithContext(context.WithValue(req.Context(), contextkeys.Brand, "brand"))
+
+	headers := GetPolarisHeaders(req)
+
+	assert.Equal(t, []string{"unique id"}, headers[polarisheaders.UniqueID])
+	assert.Equal(t, []string{"auth"}, headers["Authorization"])
+	assert.Equal(t, []string{"brand"}, headers[polarisheaders.Brand])
+	assert.Nil(t, headers["Not-Copied"])
 }
 
 func Test_set_all_headers(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://test", nil)
+
+	headers := PolarisHeaders{}
+
+	headers[polarisheaders.UniqueID] = []string{"unique id"}
+	headers[polarisheaders.Authorization] = []string{"auth"}
+
+	SetPolarisHeaders(req, headers)
 
-	//req, _ := http.NewRequest(http.MethodGet, "http://test", nil)
-	//
-	//headers := PolarisHeaders{}
-	//
-	//headers[polarisheaders.UniqueID] = []string{"unique id"}
-	//headers[polarisheaders.Authorization] = []string{"auth"}
-	//
-	//SetPolarisHeaders(req, headers)
-	//
-	//assert.Equal(t, "unique id", req.Header.Get(polarisheaders.UniqueID))
-	//assert.Equal(t, "auth", req.Header.Get(polarisheaders.Authorization))
+	assert.Equal(t, "unique id", req.Header.Get(polarisheaders.UniqueID))
+	assert.Equal(t, "auth", req.Header.Get(polarisheaders.Authorization))
 }
@@ -0,0 +1,61 @@
+package polarisheaders
+
+import (
+	"strconv"
+	"time"
+)
+
+// timeoutMaxValue is the maximum value of a timeout header, of 8 digits.
+const timeoutMaxValue = 99999999
+
+// timeoutUnits are the units of a timeout header, from the most precise.
+var timeoutUnits = []struct {
+	unit     byte
+	duration time.Duration
+}{
+	{'n', time.Nanosecond},
+	{'u', time.Microsecond},
+	{'m', time.Millisecond},
+	{'S', time.Second},
+	{'M', time.Minute},
+	{'H', time.Hour},
+}
+
+// FormatTimeout returns the header value of a timeout, in the grpc-timeout
+// format: an integer of at most 8 digits followed by its unit, "H" for hours,
+// "M" for minutes, "S" for seconds, "m" for milliseconds, "u" for microseconds
+// or "n" for nanoseconds, e.g. "1500m" for 1.5s.
+//
+// The most precise unit is used. Negative timeouts are formatted as "0n".
+func FormatTimeout(timeout time.Duration) string {
+	if timeout < 0 {
+		timeout = 0
+	}
+	for _, u := range timeoutUnits {
+		// Round up, not to shorten the timeout to 0.
+		value := (timeout + u.duration - 1) / u.duration
+		if value <= timeoutMaxValue {
+			return strconv.FormatInt(int64(value), 10) + string(u.unit)
+		}
+	}
+	return strconv.Itoa(timeoutMaxValue) + "H"
+}
+
+// ParseTimeout returns the timeout of a header value formatted by
+// FormatTimeout, and whether it is valid.
+func ParseTimeout(header string) (time.Duration, bool) {
+	if len(header) < 2 || len(header) > 9 {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(header[:len(header)-1], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	unit := header[len(header)-1]
+	for _, u := range timeoutUnits {
+		if u.unit == unit {
+			return time.Duration(value) * u.duration, true
+		}
+	}
+	return 0, false
+}
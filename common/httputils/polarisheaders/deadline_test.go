@@ -0,0 +1,60 @@
+package polarisheaders
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatTimeout(t *testing.T) {
+	tests := map[time.Duration]string{
+		-time.Second:            "0n",
+		0:                       "0n",
+		50 * time.Nanosecond:    "50n",
+		50 * time.Millisecond:   "50000000n",
+		1500 * time.Millisecond: "1500000u",
+		2 * time.Minute:         "120000m",
+		48 * time.Hour:          "172800S",
+	}
+
+	for timeout, want := range tests {
+		t.Run(want, func(t *testing.T) {
+			assert.Equal(t, want, FormatTimeout(timeout))
+
+			got, ok := ParseTimeout(want)
+			assert.True(t, ok)
+			if timeout > 0 {
+				assert.Equal(t, timeout, got)
+			}
+		})
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := map[string]struct {
+		header string
+		want   time.Duration
+		valid  bool
+	}{
+		"hours":      {header: "1H", want: time.Hour, valid: true},
+		"minutes":    {header: "2M", want: 2 * time.Minute, valid: true},
+		"seconds":    {header: "3S", want: 3 * time.Second, valid: true},
+		"millis":     {header: "1500m", want: 1500 * time.Millisecond, valid: true},
+		"empty":      {header: ""},
+		"no value":   {header: "S"},
+		"no unit":    {header: "100"},
+		"bad unit":   {header: "100s"},
+		"negative":   {header: "-1S"},
+		"too long":   {header: "123456789S"},
+		"not number": {header: "1.5S"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := ParseTimeout(tt.header)
+			assert.Equal(t, tt.valid, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Package polarisheaders declares the headers propagated between the Polaris
+// services, and their mapping to the request context values of the
+// contextkeys package.
+//
+// Client side, Inject sets the headers of an outgoing request from its context.
+// Server side, Extract populates the context of an incoming request from its
+// headers.
+package polarisheaders
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/monorepo/common/contextkeys"
+)
+
+// All propagated headers.
+const (
+	// UniqueID is the header carrying the request unique ID.
+	UniqueID = "X-Unique-Id"
+	// Authorization is the header carrying the authorization token.
+	Authorization = "Authorization"
+	// Brand is the header carrying the brand identifier.
+	Brand = "X-Brand"
+	// ForwardedHost is the header carrying the brand origin hostname.
+	ForwardedHost = "X-Forwarded-Host"
+	// Language is the header carrying the language identifier.
+	Language = "Accept-Language"
+	// Deadline is the header carrying the remaining time before the deadline
+	// of the request, see FormatTimeout.
+	Deadline = "X-Request-Deadline"
+)
+
+// Mapping maps a context value of the contextkeys package to a header. The
+// context value is a string.
+type Mapping struct {
+	// Key is the context key of the value.
+	Key any
+	// Header is the canonical name of the header.
+	Header string
+	// Format returns the header value of a context value. The context value is
+	// used as is when nil.
+	Format func(value string) string
+	// Parse returns the context value of a header value, and whether it is
+	// valid. The header value is used as is when nil.
+	Parse func(header string) (string, bool)
+}
+
+// All available mappings.
+var (
+	UniqueIDMapping = Mapping{
+		Key:    contextkeys.UniqueID,
+		Header: UniqueID,
+	}
+	AuthorizationMapping = Mapping{
+		Key:    contextkeys.AuthToken,
+		Header: Authorization,
+		Format: func(token string) string {
+			return "Bearer " + token
+		},
+		Parse: func(header string) (string, bool) {
+			scheme, token, ok := strings.Cut(header, " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") {
+				return "", false
+			}
+			token = strings.TrimSpace(token)
+			return token, token != ""
+		},
+	}
+	BrandMapping = Mapping{
+		Key:    contextkeys.Brand,
+		Header: Brand,
+	}
+	ForwardedHostMapping = Mapping{
+		Key:    contextkeys.BrandOriginHost,
+		Header: ForwardedHost,
+	}
+	LanguageMapping = Mapping{
+		Key:    contextkeys.Language,
+		Header: Language,
+	}
+)
+
+// BrandMappings are the mappings of the brand related values.
+var BrandMappings = []Mapping{
+	BrandMapping,
+	ForwardedHostMapping,
+	LanguageMapping,
+}
+
+// Default are the mappings of the values propagated between the Polaris
+// services.
+//
+// The authorization token is not part of them: it would be taken from the
+// incoming requests without being validated, and sent to every host, third
+// parties included. Forwarding it is an explicit opt-in with
+// AuthorizationMapping, for the trusted hosts only.
+var Default = []Mapping{
+	UniqueIDMapping,
+	BrandMapping,
+	ForwardedHostMapping,
+	LanguageMapping,
+}
+
+// Value returns the header value of the mapped context value, if any.
+func (m Mapping) Value(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(m.Key).(string)
+	if !ok || v == "" {
+		return "", false
+	}
+	if m.Format != nil {
+		v = m.Format(v)
+	}
+	return v, true
+}
+
+// ContextValue returns the context value of the mapped header, if any and
+// valid.
+func (m Mapping) ContextValue(h http.Header) (string, bool) {
+	v := h.Get(m.Header)
+	if v == "" {
+		return "", false
+	}
+	if m.Parse != nil {
+		return m.Parse(v)
+	}
+	return v, true
+}
+
+// Inject sets the headers of the mapped context values. Headers already set
+// are kept as is.
+func Inject(ctx context.Context, h http.Header, mappings ...Mapping) {
+	for _, m := range mappings {
+		if h.Get(m.Header) != "" {
+			continue
+		}
+		if v, ok := m.Value(ctx); ok {
+			h.Set(m.Header, v)
+		}
+	}
+}
+
+// Extract returns a copy of the context with the values of the mapped
+// headers. Values of missing or invalid headers are not changed.
+func Extract(ctx context.Context, h http.Header, mappings ...Mapping) context.Context {
+	for _, m := range mappings {
+		if v, ok := m.ContextValue(h); ok {
+			ctx = context.WithValue(ctx, m.Key, v)
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,80 @@
+package polarisheaders
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/monorepo/common/contextkeys"
+)
+
+func TestInject(t *testing.T) {
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, contextkeys.UniqueID, "unique id")
+	ctx = context.WithValue(ctx, contextkeys.AuthToken, "token")
+	ctx = context.WithValue(ctx, contextkeys.Brand, "brand")
+	ctx = context.WithValue(ctx, contextkeys.BrandOriginHost, "brand.example.com")
+	ctx = context.WithValue(ctx, contextkeys.Language, "")
+
+	h := http.Header{}
+	h.Set(Brand, "other brand")
+
+	Inject(ctx, h, Default...)
+
+	// The authorization token is only forwarded on demand.
+	assert.Equal(t, http.Header{
+		UniqueID:      {"unique id"},
+		Brand:         {"other brand"},
+		ForwardedHost: {"brand.example.com"},
+	}, h)
+
+	Inject(ctx, h, AuthorizationMapping)
+	assert.Equal(t, "Bearer token", h.Get(Authorization))
+}
+
+func TestExtract(t *testing.T) {
+	h := http.Header{}
+	h.Set(UniqueID, "unique id")
+	h.Set(Authorization, "bearer token")
+	h.Set(ForwardedHost, "brand.example.com")
+	h.Set(Language, "fr-FR")
+
+	ctx := context.WithValue(context.Background(), contextkeys.Brand, "brand")
+	ctx = Extract(ctx, h, Default...)
+
+	assert.Equal(t, "unique id", ctx.Value(contextkeys.UniqueID))
+	assert.Nil(t, ctx.Value(contextkeys.AuthToken))
+	assert.Equal(t, "brand", ctx.Value(contextkeys.Brand))
+	assert.Equal(t, "brand.example.com", ctx.Value(contextkeys.BrandOriginHost))
+	assert.Equal(t, "fr-FR", ctx.Value(contextkeys.Language))
+
+	ctx = Extract(ctx, h, AuthorizationMapping)
+	assert.Equal(t, "token", ctx.Value(contextkeys.AuthToken))
+}
+
+func TestAuthorizationMapping_ContextValue(t *testing.T) {
+	tests := map[string]struct {
+		header    string
+		wantToken string
+		wantOk    bool
+	}{
+		"bearer":       {header: "Bearer token", wantToken: "token", wantOk: true},
+		"other scheme": {header: "Basic dXNlcjpwYXNz"},
+		"no token":     {header: "Bearer "},
+		"no scheme":    {header: "token"},
+		"missing":      {},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(Authorization, tt.header)
+
+			token, ok := AuthorizationMapping.ContextValue(h)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantToken, token)
+		})
+	}
+}
@@ -1,15 +1,26 @@
 package httputils
 
 import (
+	"context"
+	"errors"
+	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
+	"time"
 
 	"github.com/f2prateek/train"
+
 	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/httputils/svcauth"
+	"github.com/monorepo/common/logging"
 	"github.com/monorepo/common/monitoring/metrics"
+	"github.com/monorepo/common/problem"
 )
 
 // ReverseProxy is an httputil.ReverseProxy wrapper.
+//
+// The upstream failures are responded with problems, see ProxyErrorHandler.
 type ReverseProxy struct {
 	httputil.ReverseProxy
 	isTraced    bool
@@ -27,8 +38,9 @@ type transportWithInterceptors interface {
 func NewReverseProxy(director func(*http.Request), transport transportWithInterceptors) *ReverseProxy {
 	return &ReverseProxy{
 		ReverseProxy: httputil.ReverseProxy{
-			Director:  director,
-			Transport: transport,
+			Director:     director,
+			Transport:    transport,
+			ErrorHandler: ProxyErrorHandler(nil),
 		},
 	}
 }
@@ -71,3 +83,144 @@ func (rp *ReverseProxy) WithTracer() *ReverseProxy {
 	rp.prependInterceptors(interceptors.NewTracing())
 	return rp
 }
+
+// WithLimiter limits the number of concurrent proxied requests. The requests
+// canceled while waiting for their turn are responded by the error handler.
+func (rp *ReverseProxy) WithLimiter(count int) *ReverseProxy {
+	rp.appendInterceptors(interceptors.NewLimiter(count))
+	return rp
+}
+
+// WithCircuitBreaker set a circuit Breaker with monitoring, for each target
+// host. See Client.WithCircuitBreaker.
+func (rp *ReverseProxy) WithCircuitBreaker(name string, backPressureThreshold uint64, duration time.Duration) *ReverseProxy {
+	rp.appendInterceptors(interceptors.
+		NewBreaker(name, backPressureThreshold, duration).
+		WithMonitor(metrics.GetGlobalStatsdHandler()))
+	return rp
+}
+
+// WithServiceAuth defines the authorization interceptor.
+func (rp *ReverseProxy) WithServiceAuth(conf svcauth.Conf) *ReverseProxy {
+	rp.appendInterceptors(svcauth.NewServiceAuth(conf))
+	return rp
+}
+
+// WithUserAgent define the user agent of the proxied requests.
+func (rp *ReverseProxy) WithUserAgent(name, version string) *ReverseProxy {
+	rp.appendInterceptors(interceptors.NewUserAgent(name, version))
+	return rp
+}
+
+// WithSecretQueryParams set query params names whose values will be hidden in
+// error messages and tracing metadata, e.g. api keys sent as query params.
+// See Client.WithSecretQueryParams.
+func (rp *ReverseProxy) WithSecretQueryParams(names ...string) *ReverseProxy {
+	if rp.isTraced {
+		panic("httputils ReverseProxy.WithSecretQueryParams should be set before tracer to avoid leak through tracing")
+	}
+	rp.prependInterceptors(&interceptors.QueryObfuscator{QueryParams: names})
+
+	return rp
+}
+
+// WithErrorHandler replaces the handler of the upstream failures, by default
+// ProxyErrorHandler(nil).
+func (rp *ReverseProxy) WithErrorHandler(handler func(http.ResponseWriter, *http.Request, error)) *ReverseProxy {
+	rp.ErrorHandler = handler
+	return rp
+}
+
+// WithModifyResponse registers hooks modifying the upstream responses, e.g.
+// to rewrite their headers (see SetResponseHeader and
+// RemoveResponseHeaders). The hooks run in their registration order, after
+// the previously registered ones. A hook error stops the chain, and is
+// handled by the error handler.
+func (rp *ReverseProxy) WithModifyResponse(hooks ...func(*http.Response) error) *ReverseProxy {
+	previous := rp.ModifyResponse
+	rp.ModifyResponse = func(resp *http.Response) error {
+		if previous != nil {
+			if err := previous(resp); err != nil {
+				return err
+			}
+		}
+		for _, hook := range hooks {
+			if err := hook(resp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return rp
+}
+
+// SetResponseHeader returns a ModifyResponse hook setting a header of the
+// upstream responses.
+func SetResponseHeader(key, value string) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		resp.Header.Set(key, value)
+		return nil
+	}
+}
+
+// RemoveResponseHeaders returns a ModifyResponse hook removing headers of the
+// upstream responses, e.g. the ones disclosing the upstream implementation.
+func RemoveResponseHeaders(keys ...string) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		for _, key := range keys {
+			resp.Header.Del(key)
+		}
+		return nil
+	}
+}
+
+// ProxyErrorHandler returns a ReverseProxy error handler responding to the
+// upstream failures with problems:
+//   - 503 Service Unavailable for the requests throttled by the proxy itself,
+//     either by its rate limiter (interceptors.ErrRateLimited) or while
+//     waiting in its limiter queue (interceptors.ErrLimited),
+//   - 504 Gateway Timeout for the timeouts, either from the network or from
+//     the request context deadline,
+//   - 502 Bad Gateway for the other ones, e.g. an open circuit breaker
+//     (interceptors.ErrCircuitOpen).
+//
+// The failures are logged with the request scoped logger if any (see
+// logging.FromContext), or with the given one, the standard logger if nil as
+// the default httputil.ReverseProxy error handler. The requests canceled by
+// the client are logged as warnings, the other failures as errors.
+func ProxyErrorHandler(logger logging.Logger) func(http.ResponseWriter, *http.Request, error) {
+	if logger == nil {
+		logger = logging.FromFunc(log.Print)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		status := proxyErrorStatus(err)
+
+		l, ok := logging.FromContext(r.Context())
+		if !ok {
+			l = logger
+		}
+		l = l.WithError(err).WithField("http_status", status)
+		if errors.Is(r.Context().Err(), context.Canceled) {
+			l.Warning("HTTP proxy request canceled by the client")
+		} else {
+			l.Error("HTTP proxy request failed")
+		}
+
+		problem.Write(w, problem.New(status, ""))
+	}
+}
+
+// proxyErrorStatus returns the status code of the response to an upstream
+// failure.
+func proxyErrorStatus(err error) int {
+	if errors.Is(err, interceptors.ErrRateLimited) || errors.Is(err, interceptors.ErrLimited) {
+		return http.StatusServiceUnavailable
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
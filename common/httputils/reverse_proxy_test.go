@@ -1,12 +1,24 @@
 package httputils
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/f2prateek/train"
 	"github.com/monorepo/common/httputils/interceptors"
+	"github.com/monorepo/common/httputils/svcauth"
 	"github.com/monorepo/common/monitoring/metrics"
+	"github.com/monorepo/common/problem"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -72,6 +84,176 @@ func Test_WithTracer(t *testing.T) {
 	trMock.AssertExpectations(t)
 }
 
+func newTestReverseProxy(t *testing.T, upstream string) (*ReverseProxy, *HTTPTransportWithInterceptors) {
+	target, err := url.Parse(upstream)
+	require.NoError(t, err)
+
+	tr := NewHTTPTransportWithInterceptors(time.Second, 0)
+	rp := NewReverseProxy(func(req *http.Request) {
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+	}, tr)
+	return rp, tr
+}
+
+func Test_ReverseProxy_options(t *testing.T) {
+	var gotUserAgent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserAgent = r.UserAgent()
+	}))
+	defer upstream.Close()
+
+	rp, tr := newTestReverseProxy(t, upstream.URL)
+	rp.WithSecretQueryParams("api_key").
+		WithLimiter(2).
+		WithServiceAuth(svcauth.Conf{}).
+		WithUserAgent("app", "1.0")
+
+	is := tr.Interceptors()
+	require.Len(t, is, 4)
+	assert.IsType(t, &interceptors.QueryObfuscator{}, is[0])
+	assert.IsType(t, interceptors.Limiter(nil), is[1])
+	assert.IsType(t, &svcauth.ServiceAuth{}, is[2])
+	assert.IsType(t, interceptors.NewUserAgent("", ""), is[3])
+
+	w := httptest.NewRecorder()
+	rp.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?api_key=s3cr3t", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "app/1.0", gotUserAgent)
+}
+
+func Test_ReverseProxy_WithSecretQueryParams_after_tracer(t *testing.T) {
+	rp, _ := newTestReverseProxy(t, "http://localhost")
+	rp.WithTracer()
+
+	assert.Panics(t, func() { rp.WithSecretQueryParams("api_key") })
+}
+
+func Test_ReverseProxy_WithModifyResponse(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "upstream/1.2.3")
+		w.Header().Set("X-Powered-By", "php")
+	}))
+	defer upstream.Close()
+
+	rp, _ := newTestReverseProxy(t, upstream.URL)
+	rp.ModifyResponse = SetResponseHeader("X-Original", "kept")
+	rp.WithModifyResponse(
+		RemoveResponseHeaders("Server", "X-Powered-By"),
+		SetResponseHeader("X-Proxy", "monorepo"),
+	)
+
+	w := httptest.NewRecorder()
+	rp.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Server"))
+	assert.Empty(t, w.Header().Get("X-Powered-By"))
+	assert.Equal(t, "monorepo", w.Header().Get("X-Proxy"))
+	assert.Equal(t, "kept", w.Header().Get("X-Original"))
+}
+
+func Test_ReverseProxy_WithModifyResponse_error(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+
+	errHook := errors.New("hook failure")
+	var gotErr error
+	rp, _ := newTestReverseProxy(t, upstream.URL)
+	rp.WithModifyResponse(func(*http.Response) error { return errHook }).
+		WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			gotErr = err
+			w.WriteHeader(http.StatusTeapot)
+		})
+
+	w := httptest.NewRecorder()
+	rp.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.ErrorIs(t, gotErr, errHook)
+}
+
+func Test_ReverseProxy_error_handler(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	tests := []struct {
+		name     string
+		upstream string
+		setup    func(*HTTPTransportWithInterceptors)
+		expected int
+	}{
+		{
+			name:     "connection refused",
+			upstream: closed.URL,
+			expected: http.StatusBadGateway,
+		},
+		{
+			name:     "response header timeout",
+			upstream: slow.URL,
+			setup: func(tr *HTTPTransportWithInterceptors) {
+				tr.ResponseHeaderTimeout = 10 * time.Millisecond
+			},
+			expected: http.StatusGatewayTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp, tr := newTestReverseProxy(t, tt.upstream)
+			if tt.setup != nil {
+				tt.setup(tr)
+			}
+
+			w := httptest.NewRecorder()
+			rp.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+			var p problem.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+			assert.Equal(t, tt.expected, p.Status)
+		})
+	}
+}
+
+func Test_ReverseProxy_error_handler_standard_logger(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	rp, _ := newTestReverseProxy(t, closed.URL)
+	rp.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Contains(t, buf.String(), "HTTP proxy request failed")
+}
+
+func Test_proxyErrorStatus(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{err: context.DeadlineExceeded, expected: http.StatusGatewayTimeout},
+		{err: fmt.Errorf("wrapped: %w", os.ErrDeadlineExceeded), expected: http.StatusGatewayTimeout},
+		{err: context.Canceled, expected: http.StatusBadGateway},
+		{err: &interceptors.CircuitOpenError{Name: "upstream", Target: "localhost"}, expected: http.StatusBadGateway},
+		{err: errors.New("connection reset"), expected: http.StatusBadGateway},
+		{err: fmt.Errorf("%w: localhost", interceptors.ErrRateLimited), expected: http.StatusServiceUnavailable},
+		{err: fmt.Errorf("%w: %w", interceptors.ErrLimited, context.DeadlineExceeded), expected: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, proxyErrorStatus(tt.err), tt.err.Error())
+	}
+}
+
 type transportMock struct {
 	mock.Mock
 }
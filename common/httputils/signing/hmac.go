@@ -0,0 +1,298 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monorepo/common/secret"
+)
+
+// The headers of the HMAC signature.
+const (
+	// SignatureHeader is the header carrying the signature, in the
+	// "HMAC-SHA256 KeyId=<id>, SignedHeaders=<h1;h2>, Signature=<hex>" format.
+	SignatureHeader = "X-Signature"
+	// TimestampHeader is the header carrying the signing time, in Unix
+	// seconds.
+	TimestampHeader = "X-Signature-Timestamp"
+	// NonceHeader is the header carrying the random nonce of the request.
+	NonceHeader = "X-Signature-Nonce"
+)
+
+// hmacAlgorithm is the algorithm of the HMAC signature.
+const hmacAlgorithm = "HMAC-SHA256"
+
+// DefaultMaxClockSkew is the default maximum difference between the signing
+// time of a request and its verification time.
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// HMAC signs and verifies the requests with a HMAC-SHA256 of their canonical
+// form:
+//
+//	METHOD
+//	/escaped/path
+//	canonical query string, sorted by key and value
+//	lowercased-signed-header:trimmed values, one per line, sorted by name
+//	(empty line)
+//	signed headers names, separated by ";"
+//	hex SHA-256 of the body
+//
+// The host, TimestampHeader and NonceHeader headers are always signed. The
+// verification rejects the requests signed more than the maximum clock skew
+// away, and the replayed nonces.
+type HMAC struct {
+	keyID   string
+	keys    map[string]secret.String
+	headers []string
+	maxSkew time.Duration
+	nonces  *nonceCache
+	now     func() time.Time
+}
+
+// NewHMAC instantiates a HMAC signer and verifier, signing with the given
+// key, identified by keyID.
+func NewHMAC(keyID string, key secret.String) *HMAC {
+	return &HMAC{
+		keyID:   keyID,
+		keys:    map[string]secret.String{keyID: key},
+		headers: []string{"host", strings.ToLower(NonceHeader), strings.ToLower(TimestampHeader)},
+		maxSkew: DefaultMaxClockSkew,
+		nonces:  newNonceCache(),
+		now:     time.Now,
+	}
+}
+
+// WithKey adds a key accepted by the verification, e.g. during a key
+// rotation.
+func (h *HMAC) WithKey(keyID string, key secret.String) *HMAC {
+	h.keys[keyID] = key
+	return h
+}
+
+// WithSignedHeaders adds headers to sign, e.g. Content-Type.
+func (h *HMAC) WithSignedHeaders(names ...string) *HMAC {
+	for _, name := range names {
+		if name = strings.ToLower(name); !containsString(h.headers, name) {
+			h.headers = append(h.headers, name)
+		}
+	}
+	sort.Strings(h.headers)
+	return h
+}
+
+// WithMaxClockSkew sets the maximum difference between the signing time of a
+// request and its verification time, DefaultMaxClockSkew by default.
+func (h *HMAC) WithMaxClockSkew(maxSkew time.Duration) *HMAC {
+	h.maxSkew = maxSkew
+	return h
+}
+
+// Sign implements the Signer interface.
+func (h *HMAC) Sign(req *http.Request, payload []byte) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+	req.Header.Set(TimestampHeader, strconv.FormatInt(h.now().Unix(), 10))
+	req.Header.Set(NonceHeader, hex.EncodeToString(nonce))
+
+	signature := h.signature(h.keys[h.keyID], req, h.headers, payload)
+	req.Header.Set(SignatureHeader, fmt.Sprintf("%s KeyId=%s, SignedHeaders=%s, Signature=%s",
+		hmacAlgorithm, h.keyID, strings.Join(h.headers, ";"), hex.EncodeToString(signature)))
+	return nil
+}
+
+// Verify implements the Verifier interface.
+func (h *HMAC) Verify(req *http.Request, payload []byte) error {
+	keyID, headers, signature, err := parseHMACSignature(req.Header.Get(SignatureHeader))
+	if err != nil {
+		return err
+	}
+
+	key, ok := h.keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w: unknown key id %q", ErrInvalidSignature, keyID)
+	}
+	for _, required := range h.headers {
+		if !containsString(headers, required) {
+			return fmt.Errorf("%w: header %q not signed", ErrInvalidSignature, required)
+		}
+	}
+
+	timestamp, err := strconv.ParseInt(req.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	now := h.now()
+	if skew := now.Sub(time.Unix(timestamp, 0)); skew > h.maxSkew || skew < -h.maxSkew {
+		return fmt.Errorf("%w: timestamp out of the %s clock skew", ErrInvalidSignature, h.maxSkew)
+	}
+
+	if !hmac.Equal(signature, h.signature(key, req, headers, payload)) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+
+	// The nonces are remembered as long as their timestamp is valid.
+	if !h.nonces.add(keyID+" "+req.Header.Get(NonceHeader), now, now.Add(2*h.maxSkew)) {
+		return fmt.Errorf("%w: replayed nonce", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// signature returns the HMAC of the canonical request.
+func (h *HMAC) signature(key secret.String, req *http.Request, headers []string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(canonicalRequest(req, headers, payload)))
+	return mac.Sum(nil)
+}
+
+// canonicalRequest returns the canonical form of the request signed by HMAC.
+func canonicalRequest(req *http.Request, headers []string, payload []byte) string {
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteByte('\n')
+	path := req.URL.EscapedPath()
+	if path == "" {
+		// The empty path is sent as "/".
+		path = "/"
+	}
+	b.WriteString(path)
+	b.WriteByte('\n')
+	b.WriteString(canonicalQuery(req.URL.Query()))
+	b.WriteByte('\n')
+	for _, name := range headers {
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(headerValue(req, name))
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	b.WriteString(strings.Join(headers, ";"))
+	b.WriteByte('\n')
+	sum := sha256.Sum256(payload)
+	b.WriteString(hex.EncodeToString(sum[:]))
+	return b.String()
+}
+
+// canonicalQuery returns the query string sorted by key and value.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// headerValue returns the trimmed values of the request header, joined by
+// commas.
+func headerValue(req *http.Request, name string) string {
+	if name == "host" {
+		if req.Host != "" {
+			return req.Host
+		}
+		return req.URL.Host
+	}
+
+	values := req.Header.Values(name)
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(trimmed, ",")
+}
+
+// parseHMACSignature returns the key ID, the signed headers and the signature
+// of a SignatureHeader value.
+func parseHMACSignature(header string) (keyID string, headers []string, signature []byte, err error) {
+	algorithm, params, ok := strings.Cut(header, " ")
+	if !ok || algorithm != hmacAlgorithm {
+		return "", nil, nil, fmt.Errorf("%w: missing or unsupported signature", ErrInvalidSignature)
+	}
+
+	for _, param := range strings.Split(params, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		switch name {
+		case "KeyId":
+			keyID = value
+		case "SignedHeaders":
+			headers = strings.Split(value, ";")
+		case "Signature":
+			signature, err = hex.DecodeString(value)
+			if err != nil {
+				return "", nil, nil, fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+			}
+		}
+	}
+	if keyID == "" || len(headers) == 0 || len(signature) == 0 {
+		return "", nil, nil, fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	return keyID, headers, signature, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// nonceCachePurgeInterval is the minimum interval between two purges of the
+// expired nonces.
+const nonceCachePurgeInterval = time.Minute
+
+// nonceCache remembers the nonces until their expiration.
+type nonceCache struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastPurge time.Time
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{
+		nonces: make(map[string]time.Time),
+	}
+}
+
+// add remembers the nonce until expiresAt, and reports whether it was not
+// already known.
+func (c *nonceCache) add(nonce string, now, expiresAt time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastPurge) >= nonceCachePurgeInterval {
+		for n, exp := range c.nonces {
+			if !exp.After(now) {
+				delete(c.nonces, n)
+			}
+		}
+		c.lastPurge = now
+	}
+
+	if exp, ok := c.nonces[nonce]; ok && exp.After(now) {
+		return false
+	}
+	c.nonces[nonce] = expiresAt
+	return true
+}
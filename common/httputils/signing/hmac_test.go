@@ -0,0 +1,128 @@
+package signing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSignedRequest(t *testing.T, h *HMAC, body string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "http://api.example.com/v1/users?b=2&a=1&a=0", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	require.NoError(t, h.Sign(req, []byte(body)))
+	return req
+}
+
+func TestHMAC_Sign(t *testing.T) {
+	h := NewHMAC("key-1", "secret").WithSignedHeaders("Content-Type")
+	h.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	req := newSignedRequest(t, h, `{"name":"test"}`)
+
+	assert.Equal(t, "1700000000", req.Header.Get(TimestampHeader))
+	assert.Len(t, req.Header.Get(NonceHeader), 32)
+	assert.Regexp(t,
+		`^HMAC-SHA256 KeyId=key-1, SignedHeaders=content-type;host;x-signature-nonce;x-signature-timestamp, Signature=[0-9a-f]{64}$`,
+		req.Header.Get(SignatureHeader))
+}
+
+func TestHMAC_Verify(t *testing.T) {
+	signer := NewHMAC("key-1", "secret").WithSignedHeaders("Content-Type")
+	verifier := NewHMAC("key-2", "other secret").
+		WithKey("key-1", "secret").
+		WithMaxClockSkew(time.Minute)
+
+	t.Run("valid", func(t *testing.T) {
+		req := newSignedRequest(t, signer, "body")
+		assert.NoError(t, verifier.Verify(req, []byte("body")))
+	})
+
+	tests := map[string]struct {
+		signer *HMAC
+		tamper func(req *http.Request)
+		body   string
+	}{
+		"missing signature": {
+			signer: signer,
+			tamper: func(req *http.Request) { req.Header.Del(SignatureHeader) },
+		},
+		"unknown key": {
+			signer: NewHMAC("key-3", "secret"),
+		},
+		"wrong key": {
+			signer: NewHMAC("key-1", "wrong secret"),
+		},
+		"tampered body": {
+			signer: signer,
+			body:   "other body",
+		},
+		"tampered query": {
+			signer: signer,
+			tamper: func(req *http.Request) { req.URL.RawQuery = "a=1" },
+		},
+		"tampered header": {
+			signer: signer,
+			tamper: func(req *http.Request) { req.Header.Set("Content-Type", "text/plain") },
+		},
+		"expired": {
+			signer: signer,
+			tamper: func(req *http.Request) {
+				req.Header.Set(TimestampHeader, "1700000000")
+			},
+		},
+		"malformed": {
+			signer: signer,
+			tamper: func(req *http.Request) {
+				req.Header.Set(SignatureHeader, "HMAC-SHA256 KeyId=key-1, SignedHeaders=host, Signature=zz")
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := newSignedRequest(t, tt.signer, "body")
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+			body := "body"
+			if tt.body != "" {
+				body = tt.body
+			}
+			assert.ErrorIs(t, verifier.Verify(req, []byte(body)), ErrInvalidSignature)
+		})
+	}
+}
+
+func TestHMAC_Verify_replay(t *testing.T) {
+	h := NewHMAC("key-1", "secret")
+
+	req := newSignedRequest(t, h, "body")
+	require.NoError(t, h.Verify(req, []byte("body")))
+	assert.ErrorIs(t, h.Verify(req, []byte("body")), ErrInvalidSignature)
+}
+
+func TestHMAC_Verify_missing_signed_header(t *testing.T) {
+	signer := NewHMAC("key-1", "secret")
+	verifier := NewHMAC("key-1", "secret").WithSignedHeaders("Content-Type")
+
+	req := newSignedRequest(t, signer, "body")
+	assert.ErrorIs(t, verifier.Verify(req, []byte("body")), ErrInvalidSignature)
+}
+
+func Test_nonceCache_add(t *testing.T) {
+	c := newNonceCache()
+	now := time.Now()
+
+	assert.True(t, c.add("a", now, now.Add(time.Minute)))
+	assert.False(t, c.add("a", now.Add(30*time.Second), now.Add(time.Minute)))
+	assert.True(t, c.add("a", now.Add(time.Minute), now.Add(2*time.Minute)))
+
+	c.add("b", now.Add(3*time.Minute), now.Add(4*time.Minute))
+	assert.Len(t, c.nonces, 1)
+}
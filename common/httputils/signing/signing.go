@@ -0,0 +1,100 @@
+// Package signing signs the outgoing HTTP requests, for the services
+// authenticating their callers with a request signature, and verifies the
+// signature of the incoming requests.
+//
+// The Interceptor client interceptor signs the requests with a Signer: HMAC
+// for the partners sharing a secret key, or SigV4 for the AWS endpoints. The
+// middleware.SignatureVerifier server middleware verifies them with a Verifier.
+package signing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/f2prateek/train"
+)
+
+// ErrInvalidSignature is the error of the requests with a missing or invalid
+// signature.
+var ErrInvalidSignature = errors.New("invalid request signature")
+
+// Signer signs the requests.
+type Signer interface {
+	// Sign sets the signature headers of the request, whose body is payload.
+	Sign(req *http.Request, payload []byte) error
+}
+
+// Verifier verifies the signature of the requests.
+type Verifier interface {
+	// Verify returns an error matching ErrInvalidSignature if the signature
+	// of the request, whose body is payload, is missing or invalid.
+	Verify(req *http.Request, payload []byte) error
+}
+
+// Interceptor signs the outgoing requests with a Signer.
+//
+// It must be registered after the interceptors changing the signed parts of
+// the requests, e.g. the compression of the body. The request body is read in
+// memory to be signed.
+type Interceptor struct {
+	signer Signer
+}
+
+// NewInterceptor instantiates an Interceptor signing the requests with the
+// given signer.
+func NewInterceptor(signer Signer) *Interceptor {
+	return &Interceptor{
+		signer: signer,
+	}
+}
+
+// Intercept implements the train.Interceptor interface
+func (i *Interceptor) Intercept(chain train.Chain) (*http.Response, error) {
+	req := chain.Request()
+
+	payload, err := readBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("read the request body to sign: %w", err)
+	}
+	if err := i.signer.Sign(req, payload); err != nil {
+		return nil, fmt.Errorf("sign the request: %w", err)
+	}
+
+	return chain.Proceed(req)
+}
+
+// readBody returns the body of the request, leaving it unread.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+
+	payload, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	setBody(req, payload)
+	return payload, nil
+}
+
+// setBody replaces the body of the request with payload.
+func setBody(req *http.Request, payload []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(payload)), nil
+	}
+	req.ContentLength = int64(len(payload))
+}
@@ -0,0 +1,67 @@
+package signing
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/f2prateek/train"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signerFunc is a Signer function.
+type signerFunc func(req *http.Request, payload []byte) error
+
+func (f signerFunc) Sign(req *http.Request, payload []byte) error {
+	return f(req, payload)
+}
+
+func TestInterceptor_Intercept(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "signature of body", req.Header.Get("X-Test-Signature"))
+		b, _ := io.ReadAll(req.Body)
+		body = string(b)
+	}))
+	defer ts.Close()
+
+	client := http.Client{Transport: train.Transport(NewInterceptor(signerFunc(func(req *http.Request, payload []byte) error {
+		req.Header.Set("X-Test-Signature", "signature of "+string(payload))
+		return nil
+	})))}
+
+	tests := map[string]func() (*http.Request, error){
+		"with GetBody": func() (*http.Request, error) {
+			return http.NewRequest(http.MethodPost, ts.URL, strings.NewReader("body"))
+		},
+		"without GetBody": func() (*http.Request, error) {
+			return http.NewRequest(http.MethodPost, ts.URL, io.NopCloser(strings.NewReader("body")))
+		},
+	}
+
+	for name, newRequest := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := newRequest()
+			require.NoError(t, err)
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			assert.Equal(t, "body", body)
+		})
+	}
+}
+
+func TestInterceptor_Intercept_error(t *testing.T) {
+	errSign := errors.New("sign error")
+	client := http.Client{Transport: train.Transport(NewInterceptor(signerFunc(func(*http.Request, []byte) error {
+		return errSign
+	})))}
+
+	_, err := client.Get("http://localhost")
+	assert.ErrorIs(t, err, errSign)
+}
@@ -0,0 +1,61 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigV4_Sign(t *testing.T) {
+	cfg := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", "TOKEN"),
+	}
+	s := NewSigV4(cfg, "es")
+	s.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	req := httptest.NewRequest(http.MethodPost, "https://search.example.com/index/_search", strings.NewReader("{}"))
+	require.NoError(t, s.Sign(req, []byte("{}")))
+
+	assert.Equal(t, "20240102T030405Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "TOKEN", req.Header.Get("X-Amz-Security-Token"))
+	assert.Equal(t, "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", req.Header.Get("X-Amz-Content-Sha256"))
+	assert.True(t, strings.HasPrefix(req.Header.Get("Authorization"),
+		"AWS4-HMAC-SHA256 Credential=AKID/20240102/eu-west-1/es/aws4_request, SignedHeaders="))
+}
+
+func TestSigV4_Sign_region(t *testing.T) {
+	cfg := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	}
+	s := NewSigV4(cfg, "execute-api").WithRegion("us-east-1")
+
+	req := httptest.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+	require.NoError(t, s.Sign(req, nil))
+	assert.Contains(t, req.Header.Get("Authorization"), "/us-east-1/execute-api/aws4_request")
+}
+
+func TestSigV4_Sign_credentials_error(t *testing.T) {
+	errCreds := errors.New("no credentials")
+	s := NewSigV4(aws.Config{
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{}, errCreds
+		}),
+	}, "es")
+
+	req := httptest.NewRequest(http.MethodGet, "https://search.example.com/", nil)
+	assert.ErrorIs(t, s.Sign(req, nil), errCreds)
+
+	req = httptest.NewRequest(http.MethodGet, "https://search.example.com/", nil)
+	assert.Error(t, NewSigV4(aws.Config{}, "es").Sign(req, nil))
+}
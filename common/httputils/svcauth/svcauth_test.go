@@ -1,6 +1,7 @@
 package svcauth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -367,3 +368,20 @@ func TestServiceAuth_Intercept_is_thread_safe_even_forced_renewal(t *testing.T)
 	wg.Wait()
 	assert.Equal(t, int64(11), count, "should be called once at start and once per goroutine due to 401")
 }
+
+func TestTokenGetter_Check(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	authorizer := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(int(status.Load()))
+		_, _ = resp.Write([]byte(`{"access_token":"test-token","expires_in":0}`))
+	}))
+	defer authorizer.Close()
+
+	tg := NewTokenGetter(Conf{AuthorizerURL: authorizer.URL})
+	assert.NoError(t, tg.Check(context.Background()))
+
+	// The token expires right away, and can't be renewed.
+	status.Store(http.StatusInternalServerError)
+	assert.Error(t, tg.Check(context.Background()))
+}
@@ -126,6 +126,13 @@ func (l *TokenGetter) GetToken(ctx context.Context) (string, error) {
 	return token.AccessToken, nil
 }
 
+// Check implements the health.Check interface, checking a token can be
+// retrieved from the authorizer.
+func (l *TokenGetter) Check(ctx context.Context) error {
+	_, err := l.GetToken(ctx)
+	return err
+}
+
 func (l *TokenGetter) access() (string, bool) {
 	return l.token.AccessToken, !l.expiresAt.Before(time.Now())
 }
@@ -0,0 +1,20 @@
+package logging
+
+import (
+	"context"
+)
+
+// Using a specific type as context key to avoid possible collisions.
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying the given logger, e.g. a request
+// scoped logger with request fields.
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, if any.
+func FromContext(ctx context.Context) (Logger, bool) {
+	logger, ok := ctx.Value(ctxKey{}).(Logger)
+	return logger, ok
+}
@@ -0,0 +1,18 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	logger := NewNoop()
+	got, ok := FromContext(NewContext(context.Background(), logger))
+	assert.True(t, ok)
+	assert.Same(t, logger, got)
+}
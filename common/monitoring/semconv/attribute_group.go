@@ -5,6 +5,28 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 )
 
+// The shared attributes used to report an error.
+const (
+	// ErrorTypeKey is the attribute Key conforming to the "error.type"
+	// semantic conventions.
+	//
+	// It represents the class of error the operation ended with.
+	//
+	// Type: Enum
+	// RequirementLevel: Optional
+	// Stability: stable
+	// Examples: 'timeout', 'java.net.UnknownHostException',
+	// 'server_certificate_invalid', '500'
+	ErrorTypeKey = semconv.ErrorTypeKey
+)
+
+// All available values for the `ErrorTypeKey`.
+var (
+	// ErrorTypeOther is a fallback error value to be used when the
+	// instrumentation doesn't define a custom value.
+	ErrorTypeOther = semconv.ErrorTypeOther
+)
+
 // Semantic convention attributes in the HTTP namespace.
 const (
 	// HTTPRequestBodySizeKey is the attribute Key conforming to the
@@ -297,6 +319,66 @@ var ServerAddress = semconv.ServerAddress
 // It represents the server port number.
 var ServerPort = semconv.ServerPort
 
+// Semantic convention attributes in the TLS namespace.
+const (
+	// TLSCipherKey is the attribute Key conforming to the "tls.cipher"
+	// semantic conventions.
+	//
+	// It represents the string indicating the
+	// [cipher](https://datatracker.ietf.org/doc/html/rfc5246#appendix-A.5)
+	// used during the current connection.
+	//
+	// Type: string
+	// RequirementLevel: Optional
+	// Stability: experimental
+	// Examples: 'TLS_RSA_WITH_3DES_EDE_CBC_SHA',
+	// 'TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256'
+	TLSCipherKey = semconv.TLSCipherKey
+
+	// TLSProtocolNameKey is the attribute Key conforming to the
+	// "tls.protocol.name" semantic conventions.
+	//
+	// It represents the normalized lowercase protocol name parsed from
+	// original string of the negotiated SSL/TLS protocol version.
+	//
+	// Type: Enum
+	// RequirementLevel: Optional
+	// Stability: experimental
+	TLSProtocolNameKey = semconv.TLSProtocolNameKey
+
+	// TLSProtocolVersionKey is the attribute Key conforming to the
+	// "tls.protocol.version" semantic conventions.
+	//
+	// It represents the numeric part of the version parsed from the original
+	// string of the negotiated SSL/TLS protocol version.
+	//
+	// Type: string
+	// RequirementLevel: Optional
+	// Stability: experimental
+	// Examples: '1.2', '3'
+	TLSProtocolVersionKey = semconv.TLSProtocolVersionKey
+)
+
+// All available values for `TLSProtocolNameKey`.
+var (
+	// tls
+	TLSProtocolNameTLS = semconv.TLSProtocolNameTLS
+)
+
+// TLSCipher returns an attribute KeyValue conforming to the "tls.cipher"
+// semantic conventions.
+//
+// It represents the string indicating the cipher used during the current
+// connection.
+var TLSCipher = semconv.TLSCipher
+
+// TLSProtocolVersion returns an attribute KeyValue conforming to the
+// "tls.protocol.version" semantic conventions.
+//
+// It represents the numeric part of the version parsed from the original
+// string of the negotiated SSL/TLS protocol version.
+var TLSProtocolVersion = semconv.TLSProtocolVersion
+
 // Attributes describing URL.
 const (
 	// URLFullKey is the attribute Key conforming to the "url.full" semantic
@@ -312,6 +394,18 @@ const (
 	// '//localhost'
 	URLFullKey = attribute.Key("url.full")
 
+	// URLPathKey is the attribute Key conforming to the "url.path" semantic
+	// conventions.
+	//
+	// It represents the [URI path](https://www.rfc-editor.org/rfc/rfc3986#section-3.3)
+	// component.
+	//
+	// Type: string
+	// RequirementLevel: Optional
+	// Stability: stable
+	// Examples: '/search'
+	URLPathKey = semconv.URLPathKey
+
 	// URLSchemeKey is the attribute Key conforming to the "url.scheme" semantic conventions.
 	//
 	// It represents the [URI scheme](https://www.rfc-editor.org/rfc/rfc3986#section-3.1) component
@@ -331,6 +425,13 @@ const (
 // [RFC3986](https://www.rfc-editor.org/rfc/rfc3986)
 var URLFull = semconv.URLFull
 
+// URLPath returns an attribute KeyValue conforming to the "url.path"
+// semantic conventions.
+//
+// It represents the [URI path](https://www.rfc-editor.org/rfc/rfc3986#section-3.3)
+// component.
+var URLPath = semconv.URLPath
+
 // URLScheme returns an attribute KeyValue conforming to the "url.scheme" semantic conventions.
 //
 // It represents the [URI scheme](https://www.rfc-editor.org/rfc/rfc3986#section-3.1) component
@@ -59,3 +59,59 @@ const (
 	HTTPServerResponseBodySizeUnit        = semconv.HTTPServerResponseBodySizeUnit
 	HTTPServerResponseBodySizeDescription = semconv.HTTPServerResponseBodySizeDescription
 )
+
+// HTTPClientRequestDuration is the metric conforming to the
+// "http.client.request.duration" semantic conventions.
+//
+// It represents the duration of HTTP client requests.
+//
+// Instrument: histogram
+// Unit: s
+// Stability: Stable
+const (
+	HTTPClientRequestDurationName        = semconv.HTTPClientRequestDurationName
+	HTTPClientRequestDurationUnit        = semconv.HTTPClientRequestDurationUnit
+	HTTPClientRequestDurationDescription = semconv.HTTPClientRequestDurationDescription
+)
+
+// HTTPClientActiveRequests is the metric conforming to the
+// "http.client.active_requests" semantic conventions.
+//
+// It represents the number of active HTTP client requests.
+//
+// Instrument: updowncounter
+// Unit: {request}
+// Stability: Experimental
+const (
+	HTTPClientActiveRequestsName        = semconv.HTTPClientActiveRequestsName
+	HTTPClientActiveRequestsUnit        = semconv.HTTPClientActiveRequestsUnit
+	HTTPClientActiveRequestsDescription = semconv.HTTPClientActiveRequestsDescription
+)
+
+// HTTPClientRequestBodySize is the metric conforming to the
+// "http.client.request.body.size" semantic conventions.
+//
+// It represents the size of HTTP client request bodies.
+//
+// Instrument: histogram
+// Unit: By
+// Stability: Experimental
+const (
+	HTTPClientRequestBodySizeName        = semconv.HTTPClientRequestBodySizeName
+	HTTPClientRequestBodySizeUnit        = semconv.HTTPClientRequestBodySizeUnit
+	HTTPClientRequestBodySizeDescription = semconv.HTTPClientRequestBodySizeDescription
+)
+
+// HTTPClientResponseBodySize is the metric conforming to the
+// "http.client.response.body.size" semantic conventions.
+//
+// It represents the size of HTTP client response bodies.
+//
+// Instrument: histogram
+// Unit: By
+// Stability: Experimental
+const (
+	HTTPClientResponseBodySizeName        = semconv.HTTPClientResponseBodySizeName
+	HTTPClientResponseBodySizeUnit        = semconv.HTTPClientResponseBodySizeUnit
+	HTTPClientResponseBodySizeDescription = semconv.HTTPClientResponseBodySizeDescription
+)
@@ -0,0 +1,67 @@
+package problem
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/monorepo/common/pagination"
+)
+
+// StatusCode returns the HTTP status code matching the given error.
+//
+// Problems keep their own status code, except the ones responded by a
+// dependency which are bad gateway errors. The pagination error types are
+// mapped to 4xx and 5xx status codes, and any other error is an internal
+// server error.
+func StatusCode(err error) int {
+	var p *Problem
+	if errors.As(err, &p) {
+		if p.downstream {
+			return http.StatusBadGateway
+		}
+		if p.Status != 0 {
+			return p.Status
+		}
+	}
+
+	var (
+		notFoundErr pagination.NotFoundError
+		badKeyErr   pagination.BadRequestKeyError
+		badValueErr pagination.BadRequestValueError
+		missingErr  pagination.MissingQueryParameterError
+	)
+	switch {
+	case errors.As(err, &notFoundErr):
+		return http.StatusNotFound
+	case errors.As(err, &badKeyErr),
+		errors.As(err, &badValueErr),
+		errors.As(err, &missingErr):
+		return http.StatusBadRequest
+	default:
+		// Including pagination.RepositoryError, DeletePeriodError and
+		// RowsAffectedError.
+		return http.StatusInternalServerError
+	}
+}
+
+// FromError returns the problem matching the given error.
+//
+// If err is (or wraps) a Problem created locally, it is returned. Otherwise a
+// problem of the default type is built with the status code given by
+// StatusCode. The error message is used as detail for client errors only:
+// server errors messages may leak implementation details, so they are not
+// sent. The problems responded by a dependency (e.g. decoded by the httputils
+// clients) are bad gateway errors, without their detail: their status code is
+// about the request to the dependency, not the one being handled.
+func FromError(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) && !p.downstream {
+		return p
+	}
+
+	status := StatusCode(err)
+	if status >= http.StatusInternalServerError {
+		return New(status, "")
+	}
+	return New(status, err.Error())
+}
@@ -0,0 +1,95 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/pagination"
+)
+
+func TestStatusCode(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want int
+	}{
+		"problem": {
+			err:  New(http.StatusConflict, ""),
+			want: http.StatusConflict,
+		},
+		"wrapped problem": {
+			err:  fmt.Errorf("create: %w", New(http.StatusUnprocessableEntity, "")),
+			want: http.StatusUnprocessableEntity,
+		},
+		"downstream problem": {
+			err:  fmt.Errorf("get user: %w", downstreamProblem(t, `{"status": 401, "detail": "invalid token"}`)),
+			want: http.StatusBadGateway,
+		},
+		"not found": {
+			err:  pagination.NotFoundError{Entity: pagination.Label{}},
+			want: http.StatusNotFound,
+		},
+		"bad request key": {
+			err:  pagination.BadRequestKeyError{Key: "id"},
+			want: http.StatusBadRequest,
+		},
+		"bad request value": {
+			err:  fmt.Errorf("parse: %w", pagination.BadRequestValueError{Key: "limit", Value: -1}),
+			want: http.StatusBadRequest,
+		},
+		"missing query parameter": {
+			err:  pagination.MissingQueryParameterError{Key: "id"},
+			want: http.StatusBadRequest,
+		},
+		"repository": {
+			err:  pagination.RepositoryError{Usecase: "get", Err: errors.New("connection refused")},
+			want: http.StatusInternalServerError,
+		},
+		"other": {
+			err:  errors.New("boom"),
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, StatusCode(tt.err))
+		})
+	}
+}
+
+func TestFromError(t *testing.T) {
+	p := New(http.StatusConflict, "already exists")
+	assert.Same(t, p, FromError(fmt.Errorf("create: %w", p)))
+
+	assert.Equal(t,
+		New(http.StatusBadRequest, `missing key "id" in query string`),
+		FromError(pagination.MissingQueryParameterError{Key: "id"}),
+	)
+
+	// The problems of the dependencies are not sent as is.
+	assert.Equal(t,
+		New(http.StatusBadGateway, ""),
+		FromError(fmt.Errorf("get user: %w", downstreamProblem(t, `{"status": 403, "detail": "user 42 is banned"}`))),
+	)
+
+	// Server errors details are not sent.
+	assert.Equal(t,
+		New(http.StatusInternalServerError, ""),
+		FromError(pagination.RepositoryError{Usecase: "get", Err: errors.New("connection refused")}),
+	)
+}
+
+// downstreamProblem returns a problem decoded from JSON, as responded by a
+// dependency.
+func downstreamProblem(t *testing.T, body string) *Problem {
+	t.Helper()
+	var p Problem
+	require.NoError(t, json.Unmarshal([]byte(body), &p))
+	return &p
+}
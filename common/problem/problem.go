@@ -0,0 +1,149 @@
+// Package problem implements the RFC 7807 "Problem Details for HTTP APIs"
+// error model, shared by HTTP servers (to write error responses) and HTTP
+// clients (to decode them as Go errors).
+package problem
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// DefaultType is the problem type used when no specific type is given. The
+// problem has then no additional semantics beyond its status code.
+const DefaultType = "about:blank"
+
+// Problem is an RFC 7807 problem details object.
+//
+// It implements the error interface, so it can be returned as is by handlers
+// and HTTP clients, and retrieved with errors.As.
+type Problem struct {
+	// Type is a URI reference identifying the problem type.
+	Type string
+	// Title is a short human-readable summary of the problem type.
+	Title string
+	// Status is the HTTP status code of the response.
+	Status int
+	// Detail is a human-readable explanation specific to this occurrence of the
+	// problem.
+	Detail string
+	// Instance is a URI reference identifying this occurrence of the problem.
+	Instance string
+	// Extensions are the additional members of the problem.
+	Extensions map[string]interface{}
+
+	// downstream is set on the problems decoded from JSON, i.e. responded by
+	// a dependency, which must not be sent as is to the clients, see
+	// FromError.
+	downstream bool
+}
+
+// New returns a problem of the default type for the given status code, titled
+// after its status text.
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   DefaultType,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// With sets an extension member of the problem.
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Error implements the error interface.
+func (p *Problem) Error() string {
+	title := p.Title
+	if title == "" {
+		title = http.StatusText(p.Status)
+	}
+	if p.Detail == "" {
+		return fmt.Sprintf("problem %d %s", p.Status, title)
+	}
+	return fmt.Sprintf("problem %d %s: %s", p.Status, title, p.Detail)
+}
+
+// problemMembers are the standard members of a problem.
+var problemMembers = []string{"type", "title", "status", "detail", "instance"}
+
+// MarshalJSON implements json.Marshaler. Extensions are inlined next to the
+// standard members, which they cannot override.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+len(problemMembers))
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	for _, k := range problemMembers {
+		delete(m, k)
+	}
+
+	if p.Type != "" {
+		m["type"] = p.Type
+	}
+	if p.Title != "" {
+		m["title"] = p.Title
+	}
+	if p.Status != 0 {
+		m["status"] = p.Status
+	}
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Unknown members are decoded in
+// Extensions. The decoded problem is considered as responded by a dependency,
+// see FromError.
+func (p *Problem) UnmarshalJSON(b []byte) error {
+	var std struct {
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Status   int    `json:"status"`
+		Detail   string `json:"detail"`
+		Instance string `json:"instance"`
+	}
+	if err := json.Unmarshal(b, &std); err != nil {
+		return err
+	}
+
+	var ext map[string]interface{}
+	if err := json.Unmarshal(b, &ext); err != nil {
+		return err
+	}
+	for _, k := range problemMembers {
+		delete(ext, k)
+	}
+	if len(ext) == 0 {
+		ext = nil
+	}
+
+	*p = Problem{
+		Type:       std.Type,
+		Title:      std.Title,
+		Status:     std.Status,
+		Detail:     std.Detail,
+		Instance:   std.Instance,
+		Extensions: ext,
+		downstream: true,
+	}
+	if p.Type == "" {
+		p.Type = DefaultType
+	}
+
+	return nil
+}
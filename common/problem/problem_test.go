@@ -0,0 +1,59 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblem_MarshalJSON(t *testing.T) {
+	p := New(http.StatusBadRequest, "missing key \"id\"").
+		With("key", "id").
+		With("status", "ignored")
+	p.Instance = "/items"
+
+	b, err := json.Marshal(p)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "missing key \"id\"",
+		"instance": "/items",
+		"key": "id"
+	}`, string(b))
+}
+
+func TestProblem_UnmarshalJSON(t *testing.T) {
+	var p Problem
+	err := json.Unmarshal([]byte(`{
+		"type": "https://example.test/probs/out-of-credit",
+		"title": "You do not have enough credit.",
+		"status": 403,
+		"balance": 30
+	}`), &p)
+	require.NoError(t, err)
+
+	assert.Equal(t, Problem{
+		Type:       "https://example.test/probs/out-of-credit",
+		Title:      "You do not have enough credit.",
+		Status:     http.StatusForbidden,
+		Extensions: map[string]interface{}{"balance": float64(30)},
+		downstream: true,
+	}, p)
+}
+
+func TestProblem_UnmarshalJSON_default_type(t *testing.T) {
+	var p Problem
+	require.NoError(t, json.Unmarshal([]byte(`{"status": 404}`), &p))
+	assert.Equal(t, Problem{Type: DefaultType, Status: http.StatusNotFound, downstream: true}, p)
+}
+
+func TestProblem_Error(t *testing.T) {
+	assert.EqualError(t, New(http.StatusNotFound, ""), "problem 404 Not Found")
+	assert.EqualError(t, New(http.StatusNotFound, "no item 42"), "problem 404 Not Found: no item 42")
+	assert.EqualError(t, &Problem{Status: http.StatusConflict, Title: "Already exists"}, "problem 409 Already exists")
+}
@@ -0,0 +1,38 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Write writes the problem as an application/problem+json response. The
+// response status code is the problem status, or 500 if not set.
+func Write(w http.ResponseWriter, p *Problem) {
+	status := p.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// WriteError writes the problem matching the given error (see FromError) as
+// an application/problem+json response.
+func WriteError(w http.ResponseWriter, err error) {
+	Write(w, FromError(err))
+}
+
+// Abort writes the problem matching the given error (see FromError) as an
+// application/problem+json response, and aborts the gin handlers chain.
+//
+// The error is also attached to the gin context, for logging middlewares.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+	WriteError(c.Writer, err)
+}
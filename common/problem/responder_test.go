@@ -0,0 +1,68 @@
+package problem
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/monorepo/common/pagination"
+)
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	Write(w, New(http.StatusNotFound, "no item 42"))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"no item 42"}`, w.Body.String())
+}
+
+func TestWrite_without_status(t *testing.T) {
+	w := httptest.NewRecorder()
+	Write(w, &Problem{Type: "https://example.test/probs/unknown"})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"type":"https://example.test/probs/unknown"}`, w.Body.String())
+}
+
+func TestAbort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var nextCalled bool
+	r := gin.New()
+	r.GET("/items", func(c *gin.Context) {
+		Abort(c, pagination.MissingQueryParameterError{Key: "id"})
+	}, func(c *gin.Context) {
+		nextCalled = true
+	})
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "/items", nil)
+	require.NoError(t, err)
+	r.ServeHTTP(w, req)
+
+	assert.False(t, nextCalled)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"missing key \"id\" in query string"}`, w.Body.String())
+}
+
+func TestAbort_attaches_error(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	err := errors.New("boom")
+	Abort(c, err)
+
+	assert.True(t, c.IsAborted())
+	require.Len(t, c.Errors, 1)
+	assert.Equal(t, err, c.Errors[0].Err)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
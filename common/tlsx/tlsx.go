@@ -0,0 +1,299 @@
+// Package tlsx builds TLS client configurations from files or secrets, and
+// reloads the files when they are rotated, without restarting the client.
+package tlsx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/monorepo/common/logging"
+	"github.com/monorepo/common/secret"
+)
+
+// Conf contains the TLS configuration of a client.
+//
+// The PEM encoded certificate authorities and client certificate are given
+// either as files or as secrets.
+type Conf struct {
+	// CAFile is a PEM file of certificate authorities trusted in addition to
+	// the system ones.
+	CAFile string `mapstructure:"ca_file"`
+	// CertFile and KeyFile are the PEM files of the client certificate, for
+	// mutual TLS.
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+
+	// CA, Cert and Key are the PEM values of CAFile, CertFile and KeyFile, e.g.
+	// from a secret store.
+	CA   secret.Bytes `mapstructure:"ca"`
+	Cert secret.Bytes `mapstructure:"cert"`
+	Key  secret.Bytes `mapstructure:"key"`
+
+	ServerName         string `mapstructure:"server_name"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+	// MinVersion is the minimum TLS version: "1.0", "1.1", "1.2" or "1.3".
+	MinVersion string `mapstructure:"min_version"`
+
+	// ReloadInterval is the minimum interval between two checks of the files
+	// modification. The files are not reloaded when 0.
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+}
+
+// IsZero reports whether the configuration is empty.
+func (c Conf) IsZero() bool {
+	return c.CAFile == "" && c.CertFile == "" && c.KeyFile == "" &&
+		len(c.CA) == 0 && len(c.Cert) == 0 && len(c.Key) == 0 &&
+		c.ServerName == "" && !c.InsecureSkipVerify && c.MinVersion == ""
+}
+
+// Reloader holds the certificate authorities and client certificate of a
+// Conf, and reloads them when their files are modified.
+//
+// The files modification is checked during the TLS handshakes, at most once
+// per ReloadInterval: the new connections use the new files, while the
+// established ones are kept. Files which can't be loaded, e.g. in the middle of
+// a rotation, are ignored until the next check.
+type Reloader struct {
+	conf   Conf
+	logger logging.Logger
+	now    func() time.Time
+
+	mu        sync.RWMutex
+	roots     *x509.CertPool
+	cert      *tls.Certificate
+	modTimes  map[string]time.Time
+	nextCheck time.Time
+	onReload  []func()
+}
+
+// NewReloader instantiates a Reloader, and loads the configuration files or
+// secrets.
+func NewReloader(conf Conf) (*Reloader, error) {
+	if (conf.CertFile == "") != (conf.KeyFile == "") || (len(conf.Cert) == 0) != (len(conf.Key) == 0) {
+		return nil, errors.New("TLS client certificate and key must be both set")
+	}
+	if _, err := minVersion(conf.MinVersion); err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{
+		conf:   conf,
+		logger: logging.NewNoop(),
+		now:    time.Now,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// WithLogger logs the reloads with the given logger.
+func (r *Reloader) WithLogger(logger logging.Logger) *Reloader {
+	r.logger = logger
+	return r
+}
+
+// OnReload registers a function called after each successful reload, e.g. to
+// close the idle connections using the previous files.
+func (r *Reloader) OnReload(f func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onReload = append(r.onReload, f)
+}
+
+// TLSConfig returns a TLS client configuration always using the latest loaded
+// certificate authorities and client certificate.
+func (r *Reloader) TLSConfig() *tls.Config {
+	version, _ := minVersion(r.conf.MinVersion)
+	config := &tls.Config{
+		ServerName:         r.conf.ServerName,
+		MinVersion:         version,
+		InsecureSkipVerify: r.conf.InsecureSkipVerify, //nolint:gosec // Explicitly configured.
+	}
+
+	if r.hasCert() {
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			r.check()
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		}
+	}
+
+	if r.hasCA() && !r.conf.InsecureSkipVerify {
+		if r.conf.ReloadInterval > 0 && r.conf.CAFile != "" {
+			// RootCAs can't change, the server certificate is then verified
+			// against the latest certificate authorities on its own.
+			config.InsecureSkipVerify = true //nolint:gosec // Verified by VerifyConnection.
+			config.VerifyConnection = r.verifyConnection
+		} else {
+			config.RootCAs = r.roots
+		}
+	}
+
+	return config
+}
+
+// Reload loads the configuration files or secrets.
+func (r *Reloader) Reload() error {
+	roots, cert, modTimes, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.roots, r.cert, r.modTimes = roots, cert, modTimes
+	r.nextCheck = r.now().Add(r.conf.ReloadInterval)
+	onReload := r.onReload
+	r.mu.Unlock()
+
+	for _, f := range onReload {
+		f()
+	}
+	return nil
+}
+
+// check reloads the files if the reload interval is elapsed and they were
+// modified.
+func (r *Reloader) check() {
+	if r.conf.ReloadInterval <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	now := r.now()
+	if now.Before(r.nextCheck) {
+		r.mu.Unlock()
+		return
+	}
+	r.nextCheck = now.Add(r.conf.ReloadInterval)
+	modified := r.modifiedLocked()
+	r.mu.Unlock()
+
+	if !modified {
+		return
+	}
+	if err := r.Reload(); err != nil {
+		r.logger.WithError(err).Warning("TLS files reload failed, previous files kept")
+		return
+	}
+	r.logger.Info("TLS files reloaded")
+}
+
+// modifiedLocked reports whether one of the files was modified since loaded.
+// It must be called with the lock held.
+func (r *Reloader) modifiedLocked() bool {
+	for path, modTime := range r.modTimes {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads the certificate authorities and the client certificate, with the
+// modification time of their files.
+func (r *Reloader) load() (*x509.CertPool, *tls.Certificate, map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	read := func(path string, value secret.Bytes) ([]byte, error) {
+		if path == "" {
+			return value, nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[path] = info.ModTime()
+		return os.ReadFile(path)
+	}
+
+	var roots *x509.CertPool
+	if r.hasCA() {
+		pem, err := read(r.conf.CAFile, r.conf.CA)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("read TLS CA: %w", err)
+		}
+		roots, err = x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, nil, nil, errors.New("no certificate found in TLS CA")
+		}
+	}
+
+	var cert *tls.Certificate
+	if r.hasCert() {
+		certPEM, err := read(r.conf.CertFile, r.conf.Cert)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("read TLS client certificate: %w", err)
+		}
+		keyPEM, err := read(r.conf.KeyFile, r.conf.Key)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("read TLS client key: %w", err)
+		}
+		c, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("load TLS client certificate: %w", err)
+		}
+		cert = &c
+	}
+
+	return roots, cert, modTimes, nil
+}
+
+// verifyConnection verifies the server certificate against the latest
+// certificate authorities.
+func (r *Reloader) verifyConnection(cs tls.ConnectionState) error {
+	r.check()
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: no server certificate")
+	}
+
+	r.mu.RLock()
+	roots := r.roots
+	r.mu.RUnlock()
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+func (r *Reloader) hasCA() bool {
+	return r.conf.CAFile != "" || len(r.conf.CA) > 0
+}
+
+func (r *Reloader) hasCert() bool {
+	return r.conf.CertFile != "" || len(r.conf.Cert) > 0
+}
+
+// minVersion returns the TLS version of a "1.x" version.
+func minVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown TLS min version %q", version)
+	}
+}
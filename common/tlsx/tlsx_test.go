@@ -0,0 +1,258 @@
+package tlsx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCert is a certificate with its PEM encodings.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert generates a certificate signed by the parent, or self signed
+// when nil.
+func newTestCert(t *testing.T, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeFile writes the file with a modification time in the future, to be
+// seen as modified.
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// newMTLSServer starts a server whose certificate is signed by serverCA, and
+// requiring a client certificate signed by clientCA.
+func newMTLSServer(t *testing.T, serverCA, clientCA *testCert) *httptest.Server {
+	t.Helper()
+
+	serverCert := newTestCert(t, serverCA, false)
+	cert, err := tls.X509KeyPair(serverCert.certPEM, serverCert.keyPEM)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA.cert)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func get(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func TestReloader_secrets(t *testing.T) {
+	serverCA := newTestCert(t, nil, true)
+	clientCA := newTestCert(t, nil, true)
+	clientCert := newTestCert(t, clientCA, false)
+	ts := newMTLSServer(t, serverCA, clientCA)
+
+	r, err := NewReloader(Conf{
+		CA:         serverCA.certPEM,
+		Cert:       clientCert.certPEM,
+		Key:        clientCert.keyPEM,
+		MinVersion: "1.2",
+	})
+	require.NoError(t, err)
+
+	config := r.TLSConfig()
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	assert.NotNil(t, config.RootCAs)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	assert.NoError(t, get(client, ts.URL))
+}
+
+func TestReloader_reloads_files(t *testing.T) {
+	serverCA := newTestCert(t, nil, true)
+	clientCA := newTestCert(t, nil, true)
+	otherCA := newTestCert(t, nil, true)
+	ts := newMTLSServer(t, serverCA, clientCA)
+
+	dir := t.TempDir()
+	conf := Conf{
+		CAFile:         filepath.Join(dir, "ca.pem"),
+		CertFile:       filepath.Join(dir, "cert.pem"),
+		KeyFile:        filepath.Join(dir, "key.pem"),
+		ReloadInterval: time.Minute,
+	}
+
+	// Wrong server CA and client certificate.
+	modTime := time.Now()
+	wrongCert := newTestCert(t, otherCA, false)
+	writeFile(t, conf.CAFile, otherCA.certPEM, modTime)
+	writeFile(t, conf.CertFile, wrongCert.certPEM, modTime)
+	writeFile(t, conf.KeyFile, wrongCert.keyPEM, modTime)
+
+	r, err := NewReloader(conf)
+	require.NoError(t, err)
+	clock := &fakeClock{now: time.Now()}
+	r.now = clock.Now
+	var reloads int
+	r.OnReload(func() { reloads++ })
+
+	tr := &http.Transport{TLSClientConfig: r.TLSConfig()}
+	client := &http.Client{Transport: tr}
+	assert.Error(t, get(client, ts.URL))
+
+	// Rotated server CA.
+	modTime = modTime.Add(time.Second)
+	writeFile(t, conf.CAFile, serverCA.certPEM, modTime)
+	clock.Add(time.Minute)
+	err = get(client, ts.URL)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "certificate signed by unknown authority")
+	assert.Equal(t, 1, reloads)
+
+	// Rotated client certificate, not reloaded before the interval.
+	clientCert := newTestCert(t, clientCA, false)
+	modTime = modTime.Add(time.Second)
+	writeFile(t, conf.CertFile, clientCert.certPEM, modTime)
+	writeFile(t, conf.KeyFile, clientCert.keyPEM, modTime)
+	assert.Error(t, get(client, ts.URL))
+
+	clock.Add(time.Minute)
+	assert.NoError(t, get(client, ts.URL))
+	assert.Equal(t, 2, reloads)
+}
+
+func TestReloader_keeps_files_on_reload_error(t *testing.T) {
+	clientCA := newTestCert(t, nil, true)
+	clientCert := newTestCert(t, clientCA, false)
+
+	dir := t.TempDir()
+	conf := Conf{
+		CertFile:       filepath.Join(dir, "cert.pem"),
+		KeyFile:        filepath.Join(dir, "key.pem"),
+		ReloadInterval: time.Minute,
+	}
+	modTime := time.Now()
+	writeFile(t, conf.CertFile, clientCert.certPEM, modTime)
+	writeFile(t, conf.KeyFile, clientCert.keyPEM, modTime)
+
+	r, err := NewReloader(conf)
+	require.NoError(t, err)
+	clock := &fakeClock{now: time.Now()}
+	r.now = clock.Now
+
+	// Certificate rotated, but not the key yet.
+	writeFile(t, conf.CertFile, newTestCert(t, clientCA, false).certPEM, modTime.Add(time.Second))
+	clock.Add(time.Minute)
+
+	cert, err := r.TLSConfig().GetClientCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, clientCert.cert.Raw, cert.Certificate[0])
+}
+
+func TestNewReloader_errors(t *testing.T) {
+	tests := map[string]Conf{
+		"cert without key":  {CertFile: "cert.pem"},
+		"key without cert":  {Key: []byte("key")},
+		"min version":       {MinVersion: "2.0"},
+		"missing CA file":   {CAFile: "missing.pem"},
+		"invalid CA":        {CA: []byte("invalid")},
+		"invalid key pair":  {Cert: []byte("cert"), Key: []byte("key")},
+		"missing cert file": {CertFile: "missing.pem", KeyFile: "missing.key"},
+	}
+
+	for name, conf := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewReloader(conf)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConf_IsZero(t *testing.T) {
+	assert.True(t, Conf{}.IsZero())
+	assert.True(t, Conf{ReloadInterval: time.Minute}.IsZero())
+	assert.False(t, Conf{CA: []byte("ca")}.IsZero())
+	assert.False(t, Conf{MinVersion: "1.2"}.IsZero())
+}
@@ -5,13 +5,21 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gorilla/mux"
+
+	"github.com/monorepo/common/application"
+	"github.com/monorepo/common/application/service"
+	"github.com/monorepo/common/health"
+	"github.com/monorepo/common/httputils/middleware"
+	"github.com/monorepo/common/logging"
+	"github.com/monorepo/common/monitoring/metrics"
 )
 
 func YourHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("Received request")
-	log.Print(r)
+	logger, _ := logging.FromContext(r.Context())
+	logger.Info("Received request")
 }
 
 func getPort() string {
@@ -24,13 +32,42 @@ func getPort() string {
 }
 
 func main() {
+	var cfg application.Config
+	app, err := application.New("sample_app", &cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := app.Logger()
+
+	// The readiness fails as soon as the application starts shutting down.
+	h := health.New().WithShutdownContext(app.Context())
+
 	r := mux.NewRouter()
 	// Routes consist of a path and a handler function.
 	r.HandleFunc("/", YourHandler)
+	r.Handle(health.LivenessPath, h.LivenessHandler())
+	r.Handle(health.ReadinessPath, h.ReadinessHandler())
+	// The middlewares run once the route is matched, so the metrics, spans and
+	// access logs are tagged with its template. Recovery is the last one, for
+	// the other ones to observe the 500 responses of the panicking handlers.
+	r.Use(
+		middleware.NewUniqueID(logger).Middleware,
+		middleware.NewTracing().Middleware,
+		middleware.NewMonitoring(metrics.GetGlobalStatsdHandler()).Middleware,
+		middleware.NewAccessLog(logger).WithSkipPaths(health.LivenessPath, health.ReadinessPath).Middleware,
+		middleware.NewRecovery(logger).Middleware,
+	)
 	// Bind to a port and pass our router in
-
 	port := getPort()
-	log.Println("Going to listen on port: " + port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	app.Register(service.NewHTTPServer(&http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}))
 
+	logger.Info("Going to listen on port: " + port)
+	if err := app.Run(); err != nil {
+		logger.WithError(err).Error("application failed")
+		os.Exit(1)
+	}
 }